@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// --- Credential agent (ssh-agent style, over a unix socket) ---
+
+// The agent unlocks the profiles once and then serves their environment
+// variables to exec, env and token, refreshing tokens as needed. Clients
+// find it through CLAUDE_SWITCH_AGENT_SOCK or the default socket in the
+// config directory, and fall back to reading profiles themselves when no
+// agent answers.
+
+const agentSockEnv = "CLAUDE_SWITCH_AGENT_SOCK"
+
+type agentRequest struct {
+	Op      string `json:"op"`
+	Profile string `json:"profile,omitempty"`
+}
+
+type agentVar struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type agentError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+type agentResponse struct {
+	Env   []agentVar  `json:"env,omitempty"`
+	Error *agentError `json:"error,omitempty"`
+}
+
+func agentSocketPath() string {
+	if path := os.Getenv(agentSockEnv); path != "" {
+		return path
+	}
+	return filepath.Join(configDir(), "agent.sock")
+}
+
+func agentLogPath() string {
+	return filepath.Join(configDir(), "agent.log")
+}
+
+func cmdAgent(args []string) error {
+	sock := agentSocketPath()
+	var lifetime time.Duration
+	daemon, stop, passphraseStdin := false, false, false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--daemon" || a == "-d":
+			daemon = true
+		case a == "--stop":
+			stop = true
+		case a == passphraseStdinFlag:
+			passphraseStdin = true
+		case a == "--socket" || strings.HasPrefix(a, "--socket="):
+			value, ok := strings.CutPrefix(a, "--socket=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a path", a)
+				}
+				i++
+				value = args[i]
+			}
+			sock = expandHome(value)
+		case a == "--lifetime" || strings.HasPrefix(a, "--lifetime="):
+			value, ok := strings.CutPrefix(a, "--lifetime=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a duration (e.g. 8h)", a)
+				}
+				i++
+				value = args[i]
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return usageError("invalid lifetime '%s' (expected a duration, e.g. 8h)", value)
+			}
+			lifetime = d
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+
+	if stop {
+		if _, err := callAgent(sock, agentRequest{Op: "stop"}); err != nil {
+			return &cliError{Code: errGeneric, Message: fmt.Sprintf("no agent is listening on %s", sock), Err: err}
+		}
+		if !jsonOutput() {
+			infof("Agent stopped.\n")
+		}
+		return nil
+	}
+	if _, err := callAgent(sock, agentRequest{Op: "ping"}); err == nil {
+		return &cliError{Code: errGeneric, Message: fmt.Sprintf("an agent is already listening on %s", sock), Hint: "stop it with 'claude-switch agent --stop'"}
+	}
+	var passphrase string
+	if encryptionEnabled() {
+		p, err := unlockDaemon(passphraseStdin)
+		if err != nil {
+			return err
+		}
+		passphrase = p
+	}
+
+	if daemon {
+		args := []string{"agent", "--socket", sock}
+		if lifetime > 0 {
+			args = append(args, "--lifetime", lifetime.String())
+		}
+		pid, err := spawnDaemon(args, agentLogPath(), passphrase)
+		if err != nil {
+			return err
+		}
+		// Wait for the socket so the next command can use the agent.
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if _, err := callAgent(sock, agentRequest{Op: "ping"}); err == nil {
+				break
+			}
+		}
+		if jsonOutput() {
+			return printJSON(map[string]any{"pid": pid, "socket": sock, "log": agentLogPath()})
+		}
+		infof("Agent running in the background (pid %d) on %s; log: %s\n", pid, sock, agentLogPath())
+		if sock != filepath.Join(configDir(), "agent.sock") {
+			line, _ := exportLine(detectShell(), agentSockEnv, sock)
+			fmt.Println(line)
+		}
+		return nil
+	}
+	return runAgent(sock, lifetime)
+}
+
+// listenPrivate listens on a unix socket at sock that only the user can
+// connect to. The socket is created in a private directory and moved into
+// place once its mode is 0600, since under the umask it would be open to
+// others between Listen and a chmod.
+func listenPrivate(sock string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(sock), ".agent-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, filepath.Base(sock))
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// The socket is renamed, so the listener mustn't unlink tmp on close.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	os.Remove(sock)
+	if err := os.Rename(tmp, sock); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func runAgent(sock string, lifetime time.Duration) error {
+	if err := os.MkdirAll(filepath.Dir(sock), 0o700); err != nil {
+		return err
+	}
+	ln, err := listenPrivate(sock)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", sock, err)
+	}
+	defer os.Remove(sock)
+
+	done := make(chan struct{})
+	var once sync.Once
+	shutdown := func() { once.Do(func() { close(done); ln.Close() }) }
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+		}
+		shutdown()
+	}()
+	if lifetime > 0 {
+		timer := time.AfterFunc(lifetime, func() {
+			daemonLog("lifetime of %s reached", lifetime)
+			shutdown()
+		})
+		defer timer.Stop()
+	}
+
+	daemonLog("agent listening on %s", sock)
+	// Requests are served one at a time: two concurrent refreshes of the
+	// same profile would race to rotate its refresh token.
+	var mu sync.Mutex
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-done:
+				daemonLog("agent stopped")
+				return nil
+			default:
+				return err
+			}
+		}
+		go serveAgentConn(conn, &mu, shutdown)
+	}
+}
+
+func serveAgentConn(conn net.Conn, mu *sync.Mutex, shutdown func()) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req agentRequest
+		var resp agentResponse
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.setError(usageError("malformed request: %v", err))
+			enc.Encode(resp)
+			return
+		}
+		switch req.Op {
+		case "ping":
+		case "stop":
+			enc.Encode(resp)
+			shutdown()
+			return
+		case "env":
+			mu.Lock()
+			vars, err := profileEnvVars(req.Profile, false)
+			mu.Unlock()
+			if err != nil {
+				daemonLog("'%s': %v", req.Profile, err)
+				resp.setError(err)
+			}
+			for _, v := range vars {
+				resp.Env = append(resp.Env, agentVar{v.Key, v.Value})
+			}
+		default:
+			resp.setError(usageError("unknown operation '%s'", req.Op))
+		}
+		if enc.Encode(resp) != nil {
+			return
+		}
+	}
+}
+
+func (r *agentResponse) setError(err error) {
+	ce := classifyError(err)
+	r.Error = &agentError{ce.Code, err.Error(), ce.Hint}
+}
+
+// callAgent sends one request. A non-nil error means no agent answered;
+// errors from the agent itself come back in the response.
+func callAgent(sock string, req agentRequest) (*agentResponse, error) {
+	conn, err := net.DialTimeout("unix", sock, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// credentialEnvVars is profileEnvVars, served by the agent when one is
+// running. When the agent can't refresh a dead token and reauth is allowed,
+// the profile is read directly so the user can log in again.
+func credentialEnvVars(name string, reauth bool) ([]envVar, error) {
+	resp, err := callAgent(agentSocketPath(), agentRequest{Op: "env", Profile: name})
+	if err != nil {
+		return profileEnvVars(name, reauth)
+	}
+	if e := resp.Error; e != nil {
+		if reauth && e.Code == errReauthRequired {
+			return profileEnvVars(name, reauth)
+		}
+		return nil, &cliError{Code: e.Code, Message: e.Message, Profile: name, Hint: e.Hint}
+	}
+	vars := make([]envVar, 0, len(resp.Env))
+	for _, v := range resp.Env {
+		vars = append(vars, envVar{v.Key, v.Value})
+	}
+	return vars, nil
+}
+
+// --- token <name> ---
+
+// cmdToken prints a profile's current access token (refreshed if needed) or
+// API key, for tools that want the bare credential.
+func cmdToken(args []string) error {
+	if len(args) != 1 {
+		return usageError("token requires a profile name")
+	}
+	name, err := resolveProfileName(args[0], true)
+	if err != nil {
+		return err
+	}
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		return err
+	}
+	if len(vars) == 0 {
+		return errors.New("no credential found")
+	}
+	if jsonOutput() {
+		return printJSON(map[string]string{"profile": name, "variable": vars[0].Key, "token": vars[0].Value})
+	}
+	fmt.Println(vars[0].Value)
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// --- api-key-helper: Claude's apiKeyHelper for API key profiles ---
+
+// Claude reads an API key from ANTHROPIC_API_KEY or from the output of the
+// apiKeyHelper command in its settings.json, never from its credential
+// files. With api_key_helper set under [defaults], use points apiKeyHelper
+// at `claude-switch api-key-helper` when an API key profile becomes active,
+// and takes it out again when an OAuth profile does, so use works for
+// every kind of profile.
+
+// apiKeyHelperCommand is the subcommand Claude runs.
+const apiKeyHelperCommand = "api-key-helper"
+
+func claudeSettingsPath() string {
+	return filepath.Join(claudeConfigDir(), "settings.json")
+}
+
+// cmdAPIKeyHelper prints the active profile's API key, as Claude expects
+// of an apiKeyHelper.
+func cmdAPIKeyHelper(args []string) error {
+	for _, a := range args {
+		return usageError("unexpected argument: %s", a)
+	}
+	state := loadState()
+	if state.ActiveProfile == nil {
+		return &cliError{Code: errNoCredentials, Message: "no active profile", Hint: "run 'claude-switch use <name>'"}
+	}
+	name := *state.ActiveProfile
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if v.Key == "ANTHROPIC_API_KEY" {
+			fmt.Println(v.Value)
+			return nil
+		}
+	}
+	return &cliError{
+		Code:    errNoCredentials,
+		Message: fmt.Sprintf("active profile '%s' has no API key", name),
+		Profile: name,
+		Hint:    "switch to an API key profile with 'claude-switch use <name>'",
+	}
+}
+
+// selfCommand is a shell command line that runs this binary, with the
+// storage backend and directory overrides passed on, for settings that other
+// programs run.
+func selfCommand() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	quote := quotePosix
+	if runtime.GOOS == "windows" {
+		// Paths can't contain quotes there, and cmd doesn't unescape
+		// backslashes.
+		quote = func(s string) string { return `"` + s + `"` }
+	}
+	command := quote(exe)
+	if backendOverride != "" {
+		command += " --backend " + quote(backendOverride)
+	}
+	// Claude runs the command from wherever it was started, so directory
+	// overrides are passed on as absolute flags.
+	for _, o := range []struct{ flag, key string }{
+		{"--config-dir", "CLAUDE_SWITCH_CONFIG_DIR"},
+		{"--claude-dir", "CLAUDE_CONFIG_DIR"},
+	} {
+		if dir := os.Getenv(o.key); dir != "" {
+			abs, err := filepath.Abs(expandHome(dir))
+			if err != nil {
+				return "", err
+			}
+			command += " " + o.flag + " " + quote(abs)
+		}
+	}
+	return command, nil
+}
+
+// isOwnAPIKeyHelper reports whether an apiKeyHelper setting runs
+// claude-switch, as opposed to a helper the user set up.
+func isOwnAPIKeyHelper(value json.RawMessage) bool {
+	var command string
+	return json.Unmarshal(value, &command) == nil && strings.HasSuffix(command, " "+apiKeyHelperCommand)
+}
+
+// setAPIKeyHelper points Claude's apiKeyHelper at claude-switch for an API
+// key profile when api_key_helper is on, and otherwise removes it if
+// claude-switch put it there. It reports whether the helper is in place.
+func setAPIKeyHelper(profile *Profile) (bool, error) {
+	current := readJSONDoc(claudeSettingsPath())["apiKeyHelper"]
+	want := profile != nil && profile.Type == "api_key" && defaults.APIKeyHelper
+	if !want {
+		if current == nil || !isOwnAPIKeyHelper(current) {
+			return false, nil
+		}
+		debugf("settings: removing apiKeyHelper from %s", claudeSettingsPath())
+		return false, withLock(claudeSettingsLock, func() error {
+			return claudeSwitcher().SetKeys(claudeSettingsPath(), map[string]json.RawMessage{"apiKeyHelper": nil})
+		})
+	}
+	if current != nil && !isOwnAPIKeyHelper(current) {
+		return false, &cliError{
+			Code:    errConfig,
+			Message: fmt.Sprintf("%s already has an apiKeyHelper of its own", claudeSettingsPath()),
+			Hint:    "remove it, or set api_key_helper to false and use 'claude-switch exec' for API key profiles",
+		}
+	}
+	command, err := selfCommand()
+	if err != nil {
+		return false, err
+	}
+	value, err := json.Marshal(command + " " + apiKeyHelperCommand)
+	if err != nil {
+		return false, err
+	}
+	if string(current) == string(value) {
+		return true, nil
+	}
+	debugf("settings: setting apiKeyHelper in %s", claudeSettingsPath())
+	return true, withLock(claudeSettingsLock, func() error {
+		if err := os.MkdirAll(claudeConfigDir(), 0o700); err != nil {
+			return err
+		}
+		return claudeSwitcher().SetKeys(claudeSettingsPath(), map[string]json.RawMessage{"apiKeyHelper": value})
+	})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelfCommandForwardsOverrides(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_CONFIG_DIR", "/tmp/cs-config")
+	t.Setenv("CLAUDE_CONFIG_DIR", "/tmp/claude-config")
+	saved := backendOverride
+	backendOverride = "file"
+	defer func() { backendOverride = saved }()
+
+	command, err := selfCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unquoted := strings.NewReplacer("'", "", `"`, "").Replace(command)
+	for _, want := range []string{"--backend file", "--config-dir /tmp/cs-config", "--claude-dir /tmp/claude-config"} {
+		if !strings.Contains(unquoted, want) {
+			t.Errorf("selfCommand() = %q, missing %q", command, want)
+		}
+	}
+	if !isOwnAPIKeyHelper([]byte(`"` + command + " " + apiKeyHelperCommand + `"`)) {
+		t.Errorf("selfCommand() = %q isn't recognised as our helper", command)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// --- backup / restore of the whole profile store ---
+
+// An archive is one JSON document holding every profile with its secrets
+// in the clear (those kept in 1Password stay there; those in the keychain,
+// which doesn't leave this machine, are included; profiles sealed by
+// `encrypt enable` are decrypted) plus the state. With --encrypt, the
+// payload is sealed with AES-256-GCM under a key derived from a passphrase
+// of its own, independent of `encrypt enable`. An encrypted store is only
+// backed up unencrypted with --plaintext.
+
+const archiveVersion = 1
+
+// Limits on the key derivation settings of an archive, which comes from an
+// untrusted file: too many iterations would keep restore busy for hours.
+const (
+	minArchiveIterations = 100_000
+	maxArchiveIterations = 10_000_000
+	minArchiveSalt       = 16
+)
+
+type archiveFile struct {
+	Version int               `json:"claude_switch_backup"`
+	Created time.Time         `json:"created"`
+	KDF     *encryptionParams `json:"kdf,omitempty"`
+	Sealed  *sealedData       `json:"sealed,omitempty"`
+	Payload *archivePayload   `json:"payload,omitempty"`
+}
+
+type archivePayload struct {
+	Profiles map[string]*Profile `json:"profiles"`
+	State    State               `json:"state"`
+}
+
+// archivePassphrase reads the passphrase for an encrypted archive from
+// CLAUDE_SWITCH_BACKUP_PASSPHRASE or the terminal, asking twice when a new
+// archive is being written.
+func archivePassphrase(confirm bool) (string, error) {
+	if p := os.Getenv("CLAUDE_SWITCH_BACKUP_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	passphrase, err := readSecret("Backup passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", usageError("the passphrase can't be empty")
+	}
+	if confirm && stdinIsTerminal() {
+		again, err := readSecret("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", usageError("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+func cmdBackup(args []string) error {
+	var path string
+	encrypt, plaintext := false, false
+	for _, a := range args {
+		switch {
+		case a == "--encrypt" || a == "-e":
+			encrypt = true
+		case a == "--plaintext":
+			plaintext = true
+		case path == "":
+			path = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if path == "" {
+		return usageError("backup requires a file name")
+	}
+	if encrypt && plaintext {
+		return usageError("--encrypt and --plaintext can't be combined")
+	}
+	if !encrypt && !plaintext && encryptionEnabled() {
+		return &cliError{
+			Code:    errConfig,
+			Message: "profiles are encrypted, and an unencrypted backup would hold their tokens in plaintext",
+			Hint:    "pass --encrypt, or --plaintext to write them unencrypted anyway",
+		}
+	}
+
+	data, count, err := buildArchive(encrypt)
+	if err != nil {
+		return err
+	}
+	if err := writeSecure(expandHome(path), data); err != nil {
+		return err
+	}
+
+	if jsonOutput() {
+		return printJSON(map[string]any{"file": path, "profiles": count, "encrypted": encrypt})
+	}
+	infof("Backed up %d profile(s) to %s.\n", count, path)
+	if !encrypt {
+		fmt.Fprintln(os.Stderr, "The file holds plaintext tokens; keep it safe, or use --encrypt.")
+	}
+	return nil
+}
+
+// buildArchive serializes every profile and the state, sealed under a
+// passphrase if encrypt is set, and reports how many profiles it holds.
+func buildArchive(encrypt bool) ([]byte, int, error) {
+	store, err := storage()
+	if err != nil {
+		return nil, 0, err
+	}
+	names, err := listProfiles()
+	if err != nil {
+		return nil, 0, err
+	}
+	payload := archivePayload{Profiles: make(map[string]*Profile), State: loadState()}
+	for _, name := range names {
+		// Decrypted if encryption is enabled; profiles kept in 1Password carry
+		// only the reference.
+		var profile *Profile
+		if err := withLock(profileLock(name), func() (err error) {
+			profile, err = store.Load(name)
+			return err
+		}); err != nil {
+			return nil, 0, err
+		}
+		if item, ok := keychainItem(profile.Store); ok {
+			if err := loadKeychainSecrets(name, item, profile); err != nil {
+				return nil, 0, err
+			}
+		}
+		payload.Profiles[name] = profile
+	}
+
+	archive := archiveFile{Version: archiveVersion, Created: time.Now().UTC()}
+	if encrypt {
+		passphrase, err := archivePassphrase(true)
+		if err != nil {
+			return nil, 0, err
+		}
+		params := &encryptionParams{KDF: "pbkdf2-sha256", Iterations: kdfIterations, Salt: make([]byte, 16)}
+		if _, err := rand.Read(params.Salt); err != nil {
+			return nil, 0, err
+		}
+		key, err := deriveKey(passphrase, params)
+		if err != nil {
+			return nil, 0, err
+		}
+		plaintext, err := json.Marshal(payload)
+		if err != nil {
+			return nil, 0, err
+		}
+		if archive.Sealed, err = sealData(key, "backup", plaintext); err != nil {
+			return nil, 0, err
+		}
+		archive.KDF = params
+	} else {
+		archive.Payload = &payload
+	}
+	data, err := json.MarshalIndent(archive, "", "  ")
+	return data, len(names), err
+}
+
+func cmdRestore(args []string) error {
+	var path string
+	force := false
+	for _, a := range args {
+		switch {
+		case a == "--force" || a == "-f":
+			force = true
+		case path == "":
+			path = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if path == "" {
+		return usageError("restore requires a backup file")
+	}
+
+	data, err := readFile(expandHome(path))
+	if err != nil {
+		return &cliError{Code: errGeneric, Message: fmt.Sprintf("failed to read backup: %v", err), Err: err}
+	}
+	payload, err := parseArchive(data, path)
+	if err != nil {
+		return err
+	}
+	restored, skipped, err := restorePayload(payload, force)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput() {
+		return printJSON(map[string]any{"restored": restored, "skipped": skipped, "active": payload.State.ActiveProfile})
+	}
+	for _, name := range restored {
+		infof("Restored '%s'\n", name)
+	}
+	for _, name := range skipped {
+		infof("Skipped '%s' (already exists; --force overwrites it)\n", name)
+	}
+	infof("Restored %d of %d profile(s).\n", len(restored), len(payload.Profiles))
+	// Claude's own config isn't part of the backup, so switching is left to
+	// `use`, which hands Claude the tokens.
+	if active := payload.State.ActiveProfile; active != nil && profileExists(*active) {
+		infof("'%s' was active when the backup was made; run 'claude-switch use %s' to switch to it.\n", *active, *active)
+	}
+	return nil
+}
+
+// restorePayload saves the profiles of an archive, skipping those that
+// already exist unless force is set. Restored profiles rejoin their groups.
+func restorePayload(payload *archivePayload, force bool) (restored, skipped []string, err error) {
+	names := make([]string, 0, len(payload.Profiles))
+	for name := range payload.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	restored, skipped = []string{}, []string{}
+	for _, name := range names {
+		if err := validateProfileName(name); err != nil {
+			return restored, skipped, err
+		}
+		if payload.Profiles[name] == nil {
+			return restored, skipped, &cliError{Code: errConfig, Message: fmt.Sprintf("profile '%s' in the backup is empty", name), Profile: name}
+		}
+		if err := validateProfileEnv(payload.Profiles[name]); err != nil {
+			return restored, skipped, &cliError{Code: errConfig, Message: fmt.Sprintf("profile '%s' in the backup: %v", name, err), Profile: name}
+		}
+		if profileExists(name) && !force {
+			skipped = append(skipped, name)
+			continue
+		}
+		if err := restoreProfile(name, payload.Profiles[name]); err != nil {
+			return restored, skipped, err
+		}
+		restored = append(restored, name)
+	}
+	if len(restored) == 0 || len(payload.State.Groups) == 0 {
+		return restored, skipped, nil
+	}
+	err = updateState(func(state *State) {
+		for _, name := range restored {
+			for _, group := range profileGroups(payload.State, name) {
+				joinGroup(state, group, name)
+			}
+		}
+	})
+	return restored, skipped, err
+}
+
+// parseArchive decodes an archive, asking for the passphrase if it is
+// sealed. path only names the source in messages.
+func parseArchive(data []byte, path string) (*archivePayload, error) {
+	var archive archiveFile
+	if err := json.Unmarshal(data, &archive); err != nil || archive.Version == 0 {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("%s is not a claude-switch backup", path)}
+	}
+	if archive.Version > archiveVersion {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("%s was made by a newer claude-switch", path), Hint: "upgrade claude-switch to restore it"}
+	}
+	if archive.Sealed == nil {
+		if archive.Payload == nil {
+			return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("%s holds no profiles", path)}
+		}
+		return archive.Payload, nil
+	}
+
+	if kdf := archive.KDF; kdf == nil || kdf.KDF != "pbkdf2-sha256" ||
+		kdf.Iterations < minArchiveIterations || kdf.Iterations > maxArchiveIterations || len(kdf.Salt) < minArchiveSalt {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("unsupported encryption settings in %s", path)}
+	}
+	passphrase, err := archivePassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, archive.KDF)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := openData(key, "backup", archive.Sealed)
+	if err != nil {
+		return nil, &cliError{Code: errDecryptFailed, Message: "wrong passphrase or corrupt backup", Err: err}
+	}
+	var payload archivePayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// restoreProfile saves a profile from a backup. Profiles kept in 1Password
+// are written as stored, since saveProfile would overwrite the item with
+// the empty secrets the backup doesn't have. Those in the keychain come with
+// their secrets, which go back into their item.
+func restoreProfile(name string, profile *Profile) error {
+	if _, ok := keychainItem(profile.Store); ok || profile.Store == "" {
+		return saveProfile(name, profile)
+	}
+	store, err := storage()
+	if err != nil {
+		return err
+	}
+	if err := withLock(profileLock(name), func() error { return store.Save(name, profile) }); err != nil {
+		return err
+	}
+	meta := profileMeta(profile)
+	if full, err := loadProfile(name); err == nil {
+		meta = profileMeta(full)
+	}
+	return updateIndex(name, &meta)
+}
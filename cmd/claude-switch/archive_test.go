@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// sealedArchive builds an encrypted archive holding one profile, with the
+// given key derivation settings.
+func sealedArchive(t *testing.T, passphrase string, params *encryptionParams) []byte {
+	t.Helper()
+	key, err := deriveKey(passphrase, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := json.Marshal(archivePayload{Profiles: map[string]*Profile{"ci": {Type: "api_key", ApiKey: "sk-ant-api01-test"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealData(key, "backup", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(archiveFile{Version: archiveVersion, KDF: params, Sealed: sealed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseArchivePlain(t *testing.T) {
+	data := []byte(`{"claude_switch_backup": 1, "payload": {"profiles": {"ci": {"type": "api_key", "api_key": "k"}}, "state": {}}}`)
+	payload, err := parseArchive(data, "b.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := payload.Profiles["ci"]; p == nil || p.Type != "api_key" {
+		t.Errorf("profiles = %v", payload.Profiles)
+	}
+}
+
+func TestParseArchiveSealed(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_BACKUP_PASSPHRASE", "right")
+	params := &encryptionParams{KDF: "pbkdf2-sha256", Iterations: minArchiveIterations, Salt: bytes.Repeat([]byte{1}, 16)}
+	payload, err := parseArchive(sealedArchive(t, "right", params), "b.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := payload.Profiles["ci"]; p == nil || p.ApiKey != "sk-ant-api01-test" {
+		t.Errorf("profiles = %v", payload.Profiles)
+	}
+
+	_, err = parseArchive(sealedArchive(t, "wrong", params), "b.json")
+	var ce *cliError
+	if !errors.As(err, &ce) || ce.Code != errDecryptFailed {
+		t.Errorf("wrong passphrase: %v, want %s", err, errDecryptFailed)
+	}
+}
+
+func TestParseArchiveRejects(t *testing.T) {
+	// None of these get as far as asking for a passphrase or deriving a key.
+	t.Setenv("CLAUDE_SWITCH_BACKUP_PASSPHRASE", "right")
+	salt := bytes.Repeat([]byte{1}, 16)
+	sealed := `"sealed": {"encrypted": 1, "nonce": "AAAAAAAAAAAAAAAA", "ciphertext": "AAAA"}`
+	tests := []struct {
+		name, data string
+	}{
+		{"not json", `nope`},
+		{"no version", `{"payload": {"profiles": {}}}`},
+		{"newer version", `{"claude_switch_backup": 2, "payload": {"profiles": {}}}`},
+		{"no payload", `{"claude_switch_backup": 1}`},
+		{"no kdf", `{"claude_switch_backup": 1, ` + sealed + `}`},
+	}
+	for _, kdf := range []struct {
+		name   string
+		params encryptionParams
+	}{
+		{"unknown kdf", encryptionParams{KDF: "scrypt", Iterations: kdfIterations, Salt: salt}},
+		{"too few iterations", encryptionParams{KDF: "pbkdf2-sha256", Iterations: minArchiveIterations - 1, Salt: salt}},
+		{"too many iterations", encryptionParams{KDF: "pbkdf2-sha256", Iterations: 1<<31 - 1, Salt: salt}},
+		{"empty salt", encryptionParams{KDF: "pbkdf2-sha256", Iterations: kdfIterations}},
+		{"short salt", encryptionParams{KDF: "pbkdf2-sha256", Iterations: kdfIterations, Salt: salt[:8]}},
+	} {
+		params, err := json.Marshal(kdf.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, struct{ name, data string }{kdf.name, `{"claude_switch_backup": 1, "kdf": ` + string(params) + `, ` + sealed + `}`})
+	}
+	for _, tt := range tests {
+		_, err := parseArchive([]byte(tt.data), "b.json")
+		var ce *cliError
+		if !errors.As(err, &ce) || ce.Code != errConfig {
+			t.Errorf("%s: %v, want a %s error", tt.name, err, errConfig)
+		}
+	}
+}
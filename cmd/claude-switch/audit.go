@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Audit log (history) ---
+
+// With `audit = true` under [defaults], switching the active profile,
+// starting a command under a profile, and adding or removing one append a
+// JSON line to audit.jsonl: when, who, which profile and how it went. The
+// records are written where those things happen, whichever command (use,
+// the directory hook, ui, serve, ...) led there. A command that fails after
+// naming its profile but before any of them is recorded as an error.
+
+// auditProfile is the profile the running command acts on, once known.
+var auditProfile string
+
+// audited is set once the running command has written a record.
+var audited bool
+
+// AuditRecord is one line of audit.jsonl.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Profile string    `json:"profile,omitempty"`
+	Result  string    `json:"result"` // "ok" or "error"
+	Error   string    `json:"error,omitempty"`
+	Code    string    `json:"code,omitempty"`
+	User    string    `json:"user,omitempty"`
+	Host    string    `json:"host,omitempty"`
+}
+
+func auditPath() string {
+	return filepath.Join(configDir(), "audit.jsonl")
+}
+
+// recordAudit appends what was done to profile, and how it went, to the
+// audit log if it is enabled. The record names the running command. A log
+// that can't be written is warned about, but doesn't fail the command.
+func recordAudit(profile string, err error) {
+	if !defaults.Audit {
+		return
+	}
+	audited = true
+	var command string
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	record := AuditRecord{
+		Time:    time.Now().UTC(),
+		Command: command,
+		Profile: profile,
+		Result:  "ok",
+	}
+	if err != nil {
+		ce := classifyError(err)
+		record.Result, record.Error, record.Code = "error", err.Error(), ce.Code
+		record.Profile = cmp.Or(record.Profile, ce.Profile)
+	}
+	if u, err := user.Current(); err == nil {
+		record.User = u.Username
+	}
+	record.Host, _ = os.Hostname()
+
+	if werr := appendAudit(record); werr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't write to the audit log: %v\n", werr)
+	}
+}
+
+func appendAudit(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir(), 0o700); err != nil {
+		return err
+	}
+	// One write per record with O_APPEND, so records from concurrent runs
+	// don't interleave.
+	f, err := os.OpenFile(auditPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	debugf("appended to %s", auditPath())
+	return f.Close()
+}
+
+func loadAudit() ([]AuditRecord, error) {
+	f, err := os.Open(auditPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s, line %d: %w", auditPath(), line, err)
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+// cmdHistory prints the audit log, oldest first.
+func cmdHistory(args []string) error {
+	var profile string
+	var since time.Duration
+	limit := 0
+	timeMode := timeUTC
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		flag, value, hasValue := strings.Cut(a, "=")
+		takeValue := func(what string) (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", usageError("%s requires %s", flag, what)
+			}
+			i++
+			return args[i], nil
+		}
+		var err error
+		switch {
+		case parseTimeFlag(a, &timeMode):
+		case flag == "--profile" || flag == "-p":
+			profile, err = takeValue("a profile name")
+		case flag == "--since":
+			if value, err = takeValue("a duration (e.g. 24h)"); err == nil {
+				if since, err = time.ParseDuration(value); err != nil || since <= 0 {
+					err = usageError("invalid duration '%s' (e.g. 24h)", value)
+				}
+			}
+		case flag == "-n" || flag == "--limit":
+			if value, err = takeValue("a number"); err == nil {
+				if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+					err = usageError("invalid number '%s'", value)
+				}
+			}
+		default:
+			err = usageError("unexpected argument: %s", a)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	records, err := loadAudit()
+	if err != nil {
+		return err
+	}
+	records = slices.DeleteFunc(records, func(r AuditRecord) bool {
+		return (profile != "" && r.Profile != profile) || (since > 0 && time.Since(r.Time) > since)
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	if jsonOutput() {
+		if records == nil {
+			records = []AuditRecord{}
+		}
+		return printJSON(records)
+	}
+	if len(records) == 0 {
+		if !defaults.Audit {
+			infof("No history. Set audit = true under [defaults] in %s to record it.\n", configPath())
+		} else {
+			infof("No history.\n")
+		}
+		return nil
+	}
+
+	r := newRenderer(os.Stdout)
+	t := &table{}
+	t.add(r.header("TIME"), r.header("COMMAND"), r.header("PROFILE"), r.header("RESULT"), r.header("USER"))
+	for _, rec := range records {
+		result := rec.Result
+		if rec.Result != "ok" {
+			result = r.failure(cmp.Or(rec.Code, rec.Result))
+		}
+		who := rec.User
+		if rec.Host != "" {
+			who += "@" + rec.Host
+		}
+		t.add(formatTimestamp(uint64(rec.Time.UnixMilli()), timeMode), rec.Command, cmp.Or(rec.Profile, "-"), result, cmp.Or(who, "-"))
+	}
+	return t.write(os.Stdout)
+}
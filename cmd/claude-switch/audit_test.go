@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestActivateProfileIsAudited(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_SANDBOX", "1")
+	t.Setenv("CLAUDE_SWITCH_SANDBOX_DIR", t.TempDir())
+	if err := seedSandbox(); err != nil {
+		t.Fatal(err)
+	}
+	savedArgs, savedAudit := os.Args, defaults.Audit
+	t.Cleanup(func() { os.Args, defaults.Audit, audited = savedArgs, savedAudit, false })
+	// The directory hook switches profiles without being a "use".
+	os.Args = []string{"claude-switch", "hook"}
+	defaults.Audit = true
+
+	if _, err := activateProfile("work", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := activateProfile("revoked", false); err == nil {
+		t.Fatal("activating a revoked profile succeeded")
+	}
+
+	records, err := loadAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2: %+v", len(records), records)
+	}
+	if r := records[0]; r.Command != "hook" || r.Profile != "work" || r.Result != "ok" {
+		t.Errorf("first record = %+v, want an ok switch to work by hook", r)
+	}
+	if r := records[1]; r.Profile != "revoked" || r.Result != "error" || r.Code == "" {
+		t.Errorf("second record = %+v, want a failed switch to revoked", r)
+	}
+}
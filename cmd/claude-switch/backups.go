@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- Backups of overwritten files ---
+
+// Before Claude's config files or a profile are overwritten, the previous
+// contents are copied to backups/<file>.<timestamp> in the config directory.
+// Only the newest backupsKept per file are kept, and gc drops those older
+// than backupMaxAge.
+const (
+	backupsKept      = 10
+	backupMaxAge     = 30 * 24 * time.Hour
+	backupTimeFormat = "20060102T150405.000000Z"
+)
+
+func backupsDir() string {
+	return filepath.Join(configDir(), "backups")
+}
+
+// Backup names for each kind of file.
+const (
+	claudeCredentialsBackup = "credentials.json"
+	claudeJSONBackup        = "claude.json"
+	claudeSettingsBackup    = "settings.json"
+)
+
+func profileBackup(name string) string {
+	return "profile-" + name + ".json"
+}
+
+// writeWithBackup is writeSecure that first backs up the file's current
+// contents under name, unless they are unchanged.
+func writeWithBackup(path, name string, data []byte) error {
+	old, err := readFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case !bytes.Equal(old, data):
+		if err := os.MkdirAll(backupsDir(), 0o700); err != nil {
+			return err
+		}
+		stamp := time.Now().UTC().Format(backupTimeFormat)
+		if err := writeSecure(filepath.Join(backupsDir(), name+"."+stamp), old); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		pruneBackups(name)
+	}
+	return writeSecure(path, data)
+}
+
+// removeWithBackup removes the file at path, backing it up under name first.
+func removeWithBackup(path, name string) error {
+	old, err := readFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(backupsDir(), 0o700); err != nil {
+		return err
+	}
+	stamp := time.Now().UTC().Format(backupTimeFormat)
+	if err := writeSecure(filepath.Join(backupsDir(), name+"."+stamp), old); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	pruneBackups(name)
+	return os.Remove(path)
+}
+
+type backupFile struct {
+	Name string // what was backed up, e.g. "claude.json"
+	Path string
+	Time time.Time
+}
+
+// listBackups returns the backups in the backups directory, oldest first.
+func listBackups() ([]backupFile, error) {
+	entries, err := os.ReadDir(backupsDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, e := range entries {
+		file := e.Name()
+		// The stamp has a fixed width, so names containing dots still split.
+		if e.IsDir() || len(file) <= len(backupTimeFormat)+1 {
+			continue
+		}
+		cut := len(file) - len(backupTimeFormat)
+		t, err := time.Parse(backupTimeFormat, file[cut:])
+		if err != nil || file[cut-1] != '.' {
+			continue
+		}
+		backups = append(backups, backupFile{file[:cut-1], filepath.Join(backupsDir(), file), t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// pruneBackups removes all but the newest backupsKept backups of name.
+func pruneBackups(name string) {
+	backups, err := listBackups()
+	if err != nil {
+		return
+	}
+	var mine []backupFile
+	for _, b := range backups {
+		if b.Name == name {
+			mine = append(mine, b)
+		}
+	}
+	for len(mine) > backupsKept {
+		os.Remove(mine[0].Path)
+		mine = mine[1:]
+	}
+}
+
+// purgePlaintextBackups deletes profile backups that aren't sealed, once
+// encryption is turned on.
+func purgePlaintextBackups() (int, error) {
+	return purgeProfileBackups(func(data []byte) bool { return parseSealed(data) != nil })
+}
+
+// purgeSecretBackups deletes profile backups that hold secrets in the
+// clear, once they have moved to the keychain.
+func purgeSecretBackups() (int, error) {
+	return purgeProfileBackups(func(data []byte) bool {
+		var profile Profile
+		return parseSealed(data) != nil || (json.Unmarshal(data, &profile) == nil && !hasSecrets(&profile))
+	})
+}
+
+// purgeProfileBackups deletes the profile backups keep rejects, and those
+// that can't be read.
+func purgeProfileBackups(keep func(data []byte) bool) (int, error) {
+	backups, err := listBackups()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, b := range backups {
+		if !strings.HasPrefix(b.Name, "profile-") {
+			continue
+		}
+		data, err := readFile(b.Path)
+		if err == nil && keep(data) {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// gcBackups finds backups older than backupMaxAge and temporary files left
+// behind by writes that never finished.
+func gcBackups() ([]gcItem, error) {
+	backups, err := listBackups()
+	if err != nil {
+		return nil, err
+	}
+	var items []gcItem
+	for _, b := range backups {
+		info, err := os.Stat(b.Path)
+		if err != nil || time.Since(b.Time) < backupMaxAge {
+			continue
+		}
+		items = append(items, gcItem{
+			What:  fmt.Sprintf("backup of %s from %s", b.Name, b.Time.Format("2006-01-02")),
+			Bytes: info.Size(),
+			apply: func() error { return os.Remove(b.Path) },
+		})
+	}
+
+	// A temporary file younger than a minute may belong to a running write.
+	var leftovers []string
+	for _, pattern := range []string{
+		filepath.Join(configDir(), ".*"+tmpSuffix),
+		filepath.Join(profilesDir(), ".*"+tmpSuffix),
+		filepath.Join(backupsDir(), ".*"+tmpSuffix),
+		filepath.Join(trashDir(), ".*"+tmpSuffix),
+		filepath.Join(filepath.Dir(credentialsPath()), "."+filepath.Base(credentialsPath())+tmpSuffix),
+		filepath.Join(filepath.Dir(claudeJSONPath()), "."+filepath.Base(claudeJSONPath())+tmpSuffix),
+		filepath.Join(filepath.Dir(claudeSettingsPath()), "."+filepath.Base(claudeSettingsPath())+tmpSuffix),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		leftovers = append(leftovers, matches...)
+	}
+	for _, path := range leftovers {
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) < time.Minute {
+			continue
+		}
+		items = append(items, gcItem{
+			What:  fmt.Sprintf("unfinished write %s", path),
+			Bytes: info.Size(),
+			apply: func() error { return os.Remove(path) },
+		})
+	}
+	return items, nil
+}
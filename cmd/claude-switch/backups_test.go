@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestClaudeSettingsBackupName(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_SANDBOX", "1")
+	t.Setenv("CLAUDE_SWITCH_SANDBOX_DIR", t.TempDir())
+
+	if err := os.MkdirAll(claudeConfigDir(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(claudeSettingsPath(), []byte(`{"theme":"dark"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := claudeSwitcher().SetKeys(claudeSettingsPath(), map[string]json.RawMessage{"apiKeyHelper": json.RawMessage(`"x"`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, err := listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Name != claudeSettingsBackup {
+		t.Errorf("backups = %+v, want one named %q", backups, claudeSettingsBackup)
+	}
+}
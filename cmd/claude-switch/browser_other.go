@@ -0,0 +1,9 @@
+//go:build !darwin && !windows
+
+package main
+
+import "os/exec"
+
+func openBrowser(url string) error {
+	return exec.Command("xdg-open", url).Start()
+}
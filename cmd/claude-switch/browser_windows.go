@@ -0,0 +1,7 @@
+package main
+
+import "os/exec"
+
+func openBrowser(url string) error {
+	return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// --- Locating the claude binary ---
+
+// claudeCandidates lists where Claude Code commonly ends up when it isn't on
+// PATH: cron jobs and non-login shells often miss the directories that npm,
+// volta, asdf and mise add in interactive shell init.
+func claudeCandidates() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return windowsClaudeCandidates(home)
+	}
+	candidates := []string{
+		filepath.Join(home, ".claude", "local", "claude"),
+		filepath.Join(home, ".local", "bin", "claude"),
+		filepath.Join(home, ".volta", "bin", "claude"),
+		filepath.Join(home, ".asdf", "shims", "claude"),
+		filepath.Join(home, ".local", "share", "mise", "shims", "claude"),
+		filepath.Join(home, ".npm-global", "bin", "claude"),
+	}
+	if dir := os.Getenv("ASDF_DATA_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "shims", "claude"))
+	}
+	if dir := os.Getenv("MISE_DATA_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "shims", "claude"))
+	}
+	if out, err := exec.Command("npm", "prefix", "-g").Output(); err == nil {
+		if prefix := strings.TrimSpace(string(out)); prefix != "" {
+			candidates = append(candidates, filepath.Join(prefix, "bin", "claude"))
+		}
+	}
+	return append(candidates, "/opt/homebrew/bin/claude", "/usr/local/bin/claude")
+}
+
+// windowsClaudeCandidates covers the native installer and npm's global
+// prefix, where npm puts .cmd shims directly rather than under bin.
+func windowsClaudeCandidates(home string) []string {
+	candidates := []string{
+		filepath.Join(home, ".local", "bin", "claude.exe"),
+		filepath.Join(home, ".claude", "local", "claude.exe"),
+	}
+	if dir := os.Getenv("APPDATA"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "npm", "claude.cmd"))
+	}
+	if out, err := exec.Command("npm", "prefix", "-g").Output(); err == nil {
+		if prefix := strings.TrimSpace(string(out)); prefix != "" {
+			candidates = append(candidates, filepath.Join(prefix, "claude.cmd"))
+		}
+	}
+	return candidates
+}
+
+// isExecutable checks the mode bits, which Windows doesn't have; there the
+// candidates' extensions already say so.
+func isExecutable(info os.FileInfo) bool {
+	if info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0o111 != 0
+}
+
+// resolveClaude finds the claude binary to run for a profile: the profile's
+// claude_bin from config.toml if set, then the one under [defaults], then
+// PATH, then well-known install locations.
+func resolveClaude(profile string) (string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	bin, setting := cfg.Profiles[profile].ClaudeBin, fmt.Sprintf("claude_bin for profile '%s'", profile)
+	if bin == "" {
+		bin, setting = cfg.Defaults.ClaudeBin, "claude_bin under [defaults]"
+	}
+	if bin != "" {
+		path, err := exec.LookPath(expandHome(bin))
+		if err != nil {
+			return "", &cliError{
+				Code:    errConfig,
+				Message: fmt.Sprintf("%s is not executable: %v", setting, err),
+				Profile: profile,
+				Err:     err,
+			}
+		}
+		return path, nil
+	}
+
+	if path, err := exec.LookPath("claude"); err == nil {
+		return path, nil
+	}
+	for _, candidate := range claudeCandidates() {
+		if info, err := os.Stat(candidate); err == nil && isExecutable(info) {
+			return candidate, nil
+		}
+	}
+	return "", &cliError{
+		Code:    errClaudeFailed,
+		Message: "claude binary not found on PATH or in common install locations",
+		Profile: profile,
+		Hint:    fmt.Sprintf("set claude_bin under [profiles.%s] in %s", profile, configPath()),
+	}
+}
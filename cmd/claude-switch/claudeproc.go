@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Running Claude sessions ---
+
+// Switching rewrites the login in Claude's config directory, which a
+// running session keeps using: it carries on with the old account's tokens
+// in memory, then refreshes or writes them back over the new ones. `use`
+// and `logout` check for sessions first. Sessions started by exec or shell
+// with their own token, or with another CLAUDE_CONFIG_DIR (such as
+// `exec --isolated`), don't read that login and aren't counted.
+
+// claudeProcess is a running Claude process. Env is nil where its
+// environment can't be read: other users' processes, and platforms other
+// than Linux.
+type claudeProcess struct {
+	PID int
+	Env map[string]string
+}
+
+// processInfo is what inspectProcess could find out about a process.
+type processInfo struct {
+	Parent  int
+	Started time.Time
+	Dir     string
+}
+
+// loginEnvVars are the variables that give Claude its login directly,
+// overriding the one in its config directory.
+var loginEnvVars = []string{"CLAUDE_CODE_OAUTH_TOKEN", "ANTHROPIC_API_KEY", "ANTHROPIC_AUTH_TOKEN"}
+
+func runningClaude() []claudeProcess {
+	var procs []claudeProcess
+	for _, pid := range claudePIDs() {
+		if pid == os.Getpid() {
+			continue
+		}
+		procs = append(procs, claudeProcess{PID: pid, Env: processEnv(pid)})
+	}
+	return procs
+}
+
+// configDir is the config directory p reads, or "" if unknown.
+func (p claudeProcess) configDir() string {
+	if p.Env == nil {
+		return ""
+	}
+	if dir := p.Env["CLAUDE_CONFIG_DIR"]; dir != "" {
+		return dir
+	}
+	if home := p.Env["HOME"]; home != "" {
+		return filepath.Join(home, ".claude")
+	}
+	return ""
+}
+
+// sharesLogin reports whether p uses the login in dir. Processes whose
+// environment is unknown are assumed to.
+func (p claudeProcess) sharesLogin(dir string) bool {
+	if p.Env == nil {
+		return true
+	}
+	for _, key := range loginEnvVars {
+		if p.Env[key] != "" {
+			return false
+		}
+	}
+	other := p.configDir()
+	return other == "" || sameDir(other, dir)
+}
+
+func sameDir(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	return err == nil && os.SameFile(ai, bi)
+}
+
+// claudeSessions returns the running Claude processes that use the login
+// in Claude's config directory.
+func claudeSessions() []claudeProcess {
+	var sessions []claudeProcess
+	for _, p := range runningClaude() {
+		if p.sharesLogin(claudeConfigDir()) {
+			sessions = append(sessions, p)
+		}
+	}
+	return sessions
+}
+
+func describeSessions(sessions []claudeProcess) string {
+	pids := make([]string, len(sessions))
+	for i, p := range sessions {
+		pids[i] = strconv.Itoa(p.PID)
+	}
+	noun := "session"
+	if len(sessions) != 1 {
+		noun = "sessions"
+	}
+	return fmt.Sprintf("%d Claude %s (pid %s)", len(sessions), noun, strings.Join(pids, ", "))
+}
+
+// Ways `use` deals with running sessions.
+const (
+	sessionsAsk   = ""
+	sessionsForce = "force"
+	sessionsWait  = "wait"
+	sessionsKill  = "kill"
+)
+
+// stopGrace is how long terminated sessions get to exit before the switch
+// goes ahead anyway.
+const stopGrace = 5 * time.Second
+
+// settleSessions deals with the sessions using the login `use` is about to
+// replace: in a terminal it asks whether to wait for them, terminate them
+// or switch anyway; otherwise the switch needs --force, --wait or --kill.
+func settleSessions(mode string) error {
+	sessions := claudeSessions()
+	if len(sessions) == 0 {
+		return nil
+	}
+	what := describeSessions(sessions)
+	if mode == sessionsAsk {
+		if !stdinIsTerminal() || jsonOutput() {
+			return &cliError{
+				Code:    errClaudeRunning,
+				Message: fmt.Sprintf("%s using %s; switching now would leave Claude on the old account", what, claudeConfigDir()),
+				Hint:    "quit Claude first, or re-run with --wait, --kill or --force",
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%s using %s. Claude keeps the old account and may write it back over the new one.\n", what, claudeConfigDir())
+		answer, err := readLine("[w]ait for Claude to exit, [k]ill it, switch [a]nyway, or [c]ancel? ")
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(answer) {
+		case "w", "wait":
+			mode = sessionsWait
+		case "k", "kill":
+			mode = sessionsKill
+		case "a", "anyway":
+			mode = sessionsForce
+		default:
+			return &cliError{Code: errClaudeRunning, Message: "switch cancelled"}
+		}
+	}
+
+	switch mode {
+	case sessionsForce:
+		fmt.Fprintf(os.Stderr, "Warning: switching under %s. Restart Claude to pick up the new account.\n", what)
+	case sessionsWait:
+		infof("Waiting for %s to exit (Ctrl-C to cancel)...\n", what)
+		waitSessions(sessions, 0)
+	case sessionsKill:
+		for _, p := range sessions {
+			if err := stopProcess(p.PID); err != nil {
+				debugf("sessions: failed to stop pid %d: %v", p.PID, err)
+			}
+		}
+		if left := waitSessions(sessions, stopGrace); len(left) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s still running; Claude may overwrite the switched credentials.\n", describeSessions(left))
+		} else {
+			infof("Terminated %s.\n", what)
+		}
+	}
+	return nil
+}
+
+// waitSessions waits until sessions have exited, or for up to timeout if
+// it's nonzero, and returns those still running.
+func waitSessions(sessions []claudeProcess, timeout time.Duration) []claudeProcess {
+	deadline := time.Now().Add(timeout)
+	for {
+		var left []claudeProcess
+		for _, p := range sessions {
+			if processAlive(p.PID) {
+				left = append(left, p)
+			}
+		}
+		if len(left) == 0 || (timeout > 0 && time.Now().After(deadline)) {
+			return left
+		}
+		sessions = left
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- Per-profile Claude settings (settings <name>) ---
+
+// A profile can carry its own copy of Claude's settings.json (permissions,
+// allowed tools, hooks, model) and global CLAUDE.md, which `use` puts in
+// place along with the credentials. Edits made while a profile is active
+// are saved back to it when switching away. The files of profiles without
+// their own are kept as the shared copy in shared-settings.json while
+// another profile's are in place, and come back when switching to one of
+// them.
+
+// claudeSettingsFiles are the files in Claude's config directory that a
+// profile's settings replace.
+var claudeSettingsFiles = []string{"settings.json", "CLAUDE.md"}
+
+type ClaudeSettings = claudeswitch.ClaudeSettings
+
+func sharedSettingsPath() string {
+	return filepath.Join(configDir(), "shared-settings.json")
+}
+
+// captureClaudeSettings copies Claude's current settings files.
+func captureClaudeSettings() (*ClaudeSettings, error) {
+	settings := &ClaudeSettings{Files: make(map[string]string), CapturedAt: time.Now().UTC()}
+	for _, file := range claudeSettingsFiles {
+		data, err := readFile(filepath.Join(claudeConfigDir(), file))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		settings.Files[file] = string(data)
+	}
+	return settings, nil
+}
+
+// applyClaudeSettings makes Claude's settings files match settings,
+// backing up what they replace.
+func applyClaudeSettings(settings *ClaudeSettings) error {
+	return withLock(claudeSettingsLock, func() error {
+		if err := os.MkdirAll(claudeConfigDir(), 0o700); err != nil {
+			return err
+		}
+		for _, file := range claudeSettingsFiles {
+			path := filepath.Join(claudeConfigDir(), file)
+			content, ok := settings.Files[file]
+			var err error
+			if ok {
+				err = writeWithBackup(path, file, []byte(content))
+			} else {
+				err = removeWithBackup(path, file)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// swapClaudeSettings puts the settings of profile, about to become active
+// as name, in place of those of the active profile, saving the files in
+// place to wherever they came from first.
+func swapClaudeSettings(name string, profile *Profile) error {
+	from := ""
+	if state := loadState(); state.ActiveProfile != nil {
+		from = *state.ActiveProfile
+	}
+	if from == name {
+		// Edits since the switch are saved when switching away.
+		return nil
+	}
+	outgoing, err := loadProfileIf(from, func(p *Profile) bool { return p.Settings != nil })
+	if err != nil {
+		return err
+	}
+	if outgoing == nil && profile.Settings == nil {
+		return nil
+	}
+
+	current, err := captureClaudeSettings()
+	if err != nil {
+		return err
+	}
+	if outgoing != nil {
+		outgoing.Settings = current
+		if err := saveProfile(from, outgoing); err != nil {
+			return fmt.Errorf("failed to save Claude's settings to '%s': %w", from, err)
+		}
+	} else if err := saveSharedSettings(current); err != nil {
+		return err
+	}
+
+	incoming := profile.Settings
+	if incoming == nil {
+		shared, err := loadSharedSettings()
+		if err != nil || shared == nil {
+			return err
+		}
+		incoming = shared
+	}
+	if err := applyClaudeSettings(incoming); err != nil {
+		return err
+	}
+	if profile.Settings == nil {
+		os.Remove(sharedSettingsPath())
+	}
+	debugf("settings: switched Claude's settings from '%s' to '%s'", from, name)
+	return nil
+}
+
+// loadProfileIf loads the profile name if it exists and has returns true
+// for it, or returns nil. The stored form is checked first, so other
+// profiles don't have their secrets fetched.
+func loadProfileIf(name string, has func(*Profile) bool) (*Profile, error) {
+	if name == "" || !profileExists(name) {
+		return nil, nil
+	}
+	store, err := storage()
+	if err != nil {
+		return nil, err
+	}
+	var stored *Profile
+	if err := withLock(profileLock(name), func() error {
+		stored, err = store.Load(name)
+		return err
+	}); err != nil || !has(stored) {
+		return nil, err
+	}
+	return loadProfile(name)
+}
+
+func loadSharedSettings() (*ClaudeSettings, error) {
+	data, err := readFile(sharedSettingsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var settings ClaudeSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", sharedSettingsPath(), err)
+	}
+	return &settings, nil
+}
+
+func saveSharedSettings(settings *ClaudeSettings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSecure(sharedSettingsPath(), data)
+}
+
+func cmdSettings(args []string) error {
+	var name string
+	capture, clear := false, false
+	for _, a := range args {
+		switch {
+		case a == "--capture":
+			capture = true
+		case a == "--clear":
+			clear = true
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("settings requires a profile name")
+	}
+	if capture && clear {
+		return usageError("--capture and --clear can't be combined")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	if capture || clear {
+		unlock, err := lockProfileUpdate(name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+
+	active := false
+	if state := loadState(); state.ActiveProfile != nil && *state.ActiveProfile == name {
+		active = true
+	}
+	switch {
+	case capture:
+		captured, err := captureClaudeSettings()
+		if err != nil {
+			return err
+		}
+		// The files in place were the shared ones; they come back for
+		// profiles without settings of their own.
+		if active && profile.Settings == nil {
+			if err := saveSharedSettings(captured); err != nil {
+				return err
+			}
+		}
+		profile.Settings = captured
+	case clear:
+		if profile.Settings == nil {
+			break
+		}
+		profile.Settings = nil
+		// The files in place are now the shared copy; nothing to restore.
+		if active {
+			os.Remove(sharedSettingsPath())
+		}
+	default:
+		return printSettings(name, profile.Settings)
+	}
+	if err := saveProfile(name, profile); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printSettings(name, profile.Settings)
+	}
+	switch {
+	case clear:
+		infof("Cleared the settings of '%s'; it uses the shared ones from now on.\n", name)
+	case active:
+		infof("Captured Claude's settings for '%s'.\n", name)
+	default:
+		infof("Captured Claude's settings for '%s'; 'claude-switch use %s' puts them in place.\n", name, name)
+	}
+	return nil
+}
+
+func printSettings(name string, settings *ClaudeSettings) error {
+	if jsonOutput() {
+		result := map[string]any{"profile": name, "captured": settings != nil, "files": append([]string{}, settingsFileNames(settings)...)}
+		if settings != nil {
+			result["captured_at"] = settings.CapturedAt
+		}
+		return printJSON(result)
+	}
+	if settings == nil {
+		infof("'%s' uses the shared settings; 'claude-switch settings %s --capture' gives it its own.\n", name, name)
+		return nil
+	}
+	for _, file := range claudeSettingsFiles {
+		content, ok := settings.Files[file]
+		if !ok {
+			fmt.Printf("%-14s (none)\n", file)
+			continue
+		}
+		fmt.Printf("%-14s %s\n", file, formatBytes(int64(len(content))))
+	}
+	fmt.Printf("Captured %s\n", settings.CapturedAt.Local().Format(time.DateTime))
+	return nil
+}
+
+// settingsFileNames lists the files s holds, in claudeSettingsFiles order.
+func settingsFileNames(s *ClaudeSettings) []string {
+	if s == nil {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(claudeSettingsFiles), func(file string) bool {
+		_, ok := s.Files[file]
+		return !ok
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// --- Terminal colour ---
+
+// ANSI escape codes; only the renderer applies them.
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiGreen   = "\033[32m"
+	ansiRed     = "\033[31m"
+	ansiReverse = "\033[7m"
+)
+
+// colorMode is "auto", "always" or "never", set by the global --color flag.
+var colorMode = "auto"
+
+// useColor reports whether output written to f gets colour. --color=always
+// or never wins, then NO_COLOR, then color in config.toml; otherwise only a
+// terminal gets it.
+func useColor(f *os.File) bool {
+	switch {
+	case colorMode == "always":
+		return true
+	case colorMode == "never" || os.Getenv("NO_COLOR") != "":
+		return false
+	case defaults.Color != nil:
+		return *defaults.Color
+	}
+	return term.IsTerminal(int(f.Fd())) && os.Getenv("TERM") != "dumb"
+}
+
+// renderer styles text for one output stream, or leaves it plain when that
+// stream gets no colour.
+type renderer struct {
+	color bool
+}
+
+func newRenderer(f *os.File) renderer {
+	return renderer{color: useColor(f)}
+}
+
+func (r renderer) style(s string, codes ...string) string {
+	if !r.color {
+		return s
+	}
+	return strings.Join(codes, "") + s + ansiReset
+}
+
+func (r renderer) header(s string) string   { return r.style(s, ansiBold) }
+func (r renderer) active(s string) string   { return r.style(s, ansiGreen, ansiBold) }
+func (r renderer) failure(s string) string  { return r.style(s, ansiRed) }
+func (r renderer) selected(s string) string { return r.style(s, ansiReverse) }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// --- list columns ---
+
+// listRow is one profile as list shows it.
+type listRow struct {
+	name   string
+	active bool
+	meta   *ProfileMeta // nil if the profile couldn't be read
+	usage  usageResult
+	check  *VerifyResult // nil unless checked
+	// timeMode is how times are shown; see formatExpiry.
+	timeMode string
+	lastUsed uint64
+}
+
+// listColumn is a column of list's table, picked by key with --columns.
+type listColumn struct {
+	key    string
+	header string
+	// usage columns need each profile's usage looked up.
+	usage bool
+	// check columns need each profile's credentials verified.
+	check bool
+	// shrink columns may be cut short on a narrow terminal.
+	shrink bool
+	cell   func(r renderer, row listRow) string
+}
+
+var listColumns = []listColumn{
+	{key: "name", header: "NAME", cell: func(r renderer, row listRow) string {
+		if row.active {
+			return r.active(row.name)
+		}
+		return row.name
+	}},
+	{key: "label", header: "LABEL", shrink: true, cell: metaCell(func(m *ProfileMeta) string {
+		return cmp.Or(m.Label, "-")
+	})},
+	{key: "type", header: "TYPE", cell: func(r renderer, row listRow) string {
+		if row.meta == nil {
+			return r.failure("error")
+		}
+		return row.meta.DisplayType()
+	}},
+	{key: "email", header: "EMAIL", shrink: true, cell: metaCell((*ProfileMeta).DisplayEmail)},
+	{key: "org", header: "ORG", shrink: true, cell: metaCell((*ProfileMeta).DisplayOrg)},
+	{key: "plan", header: "PLAN", cell: metaCell((*ProfileMeta).DisplaySub)},
+	{key: "expires", header: "EXPIRES", cell: func(_ renderer, row listRow) string {
+		if row.meta == nil || row.meta.ExpiresAt == nil {
+			return "-"
+		}
+		return formatExpiry(*row.meta.ExpiresAt, row.timeMode)
+	}},
+	{key: "uuid", header: "ACCOUNT UUID", cell: metaCell(func(m *ProfileMeta) string {
+		return cmp.Or(m.AccountUUID, "-")
+	})},
+	{key: "scopes", header: "SCOPES", shrink: true, cell: metaCell(func(m *ProfileMeta) string {
+		return cmp.Or(strings.Join(m.Scopes, " "), "-")
+	})},
+	{key: "last-used", header: "LAST USED", cell: func(_ renderer, row listRow) string {
+		return formatLastUsed(row.lastUsed, row.timeMode)
+	}},
+	{key: "notes", header: "NOTES", shrink: true, cell: metaCell(func(m *ProfileMeta) string {
+		// Just the first line; show prints them all.
+		first, _, _ := strings.Cut(m.Notes, "\n")
+		return cmp.Or(first, "-")
+	})},
+	{key: "check", header: "STATUS", check: true, cell: func(r renderer, row listRow) string {
+		if row.check == nil {
+			return "-"
+		}
+		switch row.check.Status {
+		case verifyValid:
+			return "VALID"
+		case verifyExpired:
+			return r.failure("EXPIRED")
+		case verifyRevoked, verifyInvalid:
+			return r.failure("INVALID")
+		}
+		return "UNKNOWN"
+	}},
+	{key: "5h", header: "5H", usage: true, cell: func(r renderer, row listRow) string {
+		if row.usage.Err != nil {
+			return r.failure("error")
+		}
+		if w := fiveHourWindow(row); w != nil {
+			return fmt.Sprintf("%.0f%%", w.Utilization)
+		}
+		return "-"
+	}},
+	{key: "resets", header: "RESETS", usage: true, cell: func(_ renderer, row listRow) string {
+		if w := fiveHourWindow(row); w != nil && w.ResetsAt != nil {
+			return formatReset(*w.ResetsAt)
+		}
+		return "-"
+	}},
+	{key: "weekly", header: "WEEKLY", usage: true, cell: func(_ renderer, row listRow) string {
+		if row.usage.Usage != nil && row.usage.Usage.SevenDay != nil {
+			return fmt.Sprintf("%.0f%%", row.usage.Usage.SevenDay.Utilization)
+		}
+		return "-"
+	}},
+}
+
+var (
+	defaultListColumns = []string{"name", "type", "email", "org", "plan", "expires"}
+	wideListColumns    = slices.Concat([]string{"name", "label"}, defaultListColumns[1:], []string{"uuid", "scopes", "last-used", "notes"})
+	usageListColumns   = []string{"5h", "resets", "weekly"}
+)
+
+func metaCell(fn func(*ProfileMeta) string) func(renderer, listRow) string {
+	return func(_ renderer, row listRow) string {
+		if row.meta == nil {
+			return "-"
+		}
+		return fn(row.meta)
+	}
+}
+
+func fiveHourWindow(row listRow) *UsageWindow {
+	if row.usage.Usage == nil {
+		return nil
+	}
+	return row.usage.Usage.FiveHour
+}
+
+// selectListColumns looks up the columns named in keys.
+func selectListColumns(keys []string) ([]listColumn, error) {
+	var columns []listColumn
+	for _, key := range keys {
+		i := slices.IndexFunc(listColumns, func(c listColumn) bool { return c.key == key })
+		if i < 0 {
+			var known []string
+			for _, c := range listColumns {
+				known = append(known, c.key)
+			}
+			return nil, usageError("unknown column '%s' (expected %s)", key, strings.Join(known, ", "))
+		}
+		columns = append(columns, listColumns[i])
+	}
+	return columns, nil
+}
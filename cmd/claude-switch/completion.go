@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- Shell completion scripts ---
+
+// completionCommands are offered as the first word. Descriptions are shown
+// by zsh and fish.
+var completionCommands = [][2]string{
+	{"add", "Add a new profile"},
+	{"import", "Import the current Claude Code session"},
+	{"use", "Switch to a profile"},
+	{"logout", "Sign Claude out without removing profiles"},
+	{"list", "List all profiles"},
+	{"group", "Manage groups of profiles"},
+	{"current", "Print the active profile"},
+	{"prompt", "Print a short prompt segment for the active profile"},
+	{"sessions", "List running Claude sessions and their profiles"},
+	{"status", "Compare the active profile with Claude's credentials"},
+	{"refresh", "Refresh OAuth tokens now"},
+	{"service", "Schedule token refreshes with launchd or systemd"},
+	{"sync", "Save tokens Claude refreshed itself into the profile"},
+	{"watch", "Sync automatically whenever Claude rotates its tokens"},
+	{"show", "Show a profile's details"},
+	{"verify", "Check that a profile's credentials still work"},
+	{"export", "Print a profile as JSON for import --from-file"},
+	{"push", "Copy a profile to a remote host over SSH"},
+	{"ui", "Open the full-screen dashboard"},
+	{"remove", "Remove a profile"},
+	{"exec", "Run a command with a profile's credentials"},
+	{"exec-all", "Run a command once per profile"},
+	{"run", "Run a command under the profile for this directory"},
+	{"shell", "Start a shell with a profile's credentials"},
+	{"env", "Print shell exports for a profile"},
+	{"docker-env", "Print a profile's variables as a docker env file"},
+	{"docker", "Run a container with a profile's credentials"},
+	{"devcontainer", "Print devcontainer.json properties for a profile"},
+	{"token", "Print a profile's current access token"},
+	{"agent", "Serve unlocked credentials over a unix socket"},
+	{"credential-helper", "Answer key=value credential requests on stdin"},
+	{"api-key-helper", "Print the active profile's API key for Claude"},
+	{"serve", "Serve a local REST API for editors and scripts"},
+	{"api", "Call the API of a running serve"},
+	{"fallback-key", "Attach a backup API key to a profile"},
+	{"proxy", "Show or set a profile's proxy"},
+	{"vars", "Show or set a profile's extra environment variables"},
+	{"settings", "Show or capture a profile's Claude settings"},
+	{"mcp", "Show or set a profile's MCP servers"},
+	{"label", "Show or set a profile's label and notes"},
+	{"org", "List or switch the organization of an account"},
+	{"backup", "Back up all profiles to one file"},
+	{"restore", "Restore profiles from a backup"},
+	{"sync-remote", "Push or pull the profile store to an S3 bucket"},
+	{"gc", "Remove orphaned index entries and stale state"},
+	{"prune", "Remove profiles with revoked or unreadable credentials"},
+	{"doctor", "Diagnose configuration problems"},
+	{"history", "Show the audit log of profile use"},
+	{"default", "Show or set the default profile"},
+	{"config", "Read or change config.toml settings"},
+	{"encrypt", "Encrypt stored profiles"},
+	{"completion", "Print a shell completion script"},
+	{"init", "Print the .claude-profile shell hook"},
+	{"help", "Show usage"},
+}
+
+// profileCommands take a profile name as their first argument.
+var profileCommands = []string{"use", "remove", "exec", "show", "verify", "export", "push", "env", "docker-env", "devcontainer", "shell", "token", "refresh", "fallback-key", "proxy", "vars", "settings", "mcp", "label", "default"}
+
+// Profile names are completed by calling `claude-switch list --names`, so
+// the scripts never need regenerating when profiles change.
+const bashCompletion = `# bash completion for claude-switch
+_claude_switch() {
+    local cur prev cmd="" pos=0 i
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        -o|--output) COMPREPLY=($(compgen -W "text json" -- "$cur")); return ;;
+        --backend) COMPREPLY=($(compgen -W "file vault" -- "$cur")); return ;;
+        --shell) COMPREPLY=($(compgen -W "bash zsh sh fish nu powershell cmd" -- "$cur")); return ;;
+    esac
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            --) COMPREPLY=($(compgen -c -- "$cur" | sort -u)); return ;;
+            -o|--output|--backend) ((i++)) ;;
+            -*) ;;
+            *) if [[ -z "$cmd" ]]; then cmd="${COMP_WORDS[i]}"; else ((pos++)); fi ;;
+        esac
+    done
+    if [[ -z "$cmd" ]]; then
+        COMPREPLY=($(compgen -W "@COMMANDS@" -- "$cur"))
+        return
+    fi
+    [[ $pos -eq 0 && "$cur" != -* ]] || return
+    case "$cmd" in
+        @PROFILE_COMMANDS_BASH@) COMPREPLY=($(compgen -W "$(claude-switch list --names 2>/dev/null)" -- "$cur")) ;;
+        completion|init) COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur")) ;;
+        encrypt) COMPREPLY=($(compgen -W "enable disable status" -- "$cur")) ;;
+        service) COMPREPLY=($(compgen -W "install uninstall status" -- "$cur")) ;;
+        sync-remote) COMPREPLY=($(compgen -W "push pull" -- "$cur")) ;;
+        group) COMPREPLY=($(compgen -W "add remove list" -- "$cur")) ;;
+        org) COMPREPLY=($(compgen -W "list use" -- "$cur")) ;;
+    esac
+}
+complete -F _claude_switch claude-switch
+`
+
+const zshCompletion = `#compdef claude-switch
+_claude_switch() {
+    local -a commands profiles
+    local cmd="" pos=0 i
+    commands=(
+@COMMANDS_ZSH@
+    )
+    case $words[CURRENT-1] in
+        -o|--output) _values 'format' text json; return ;;
+        --backend) _values 'backend' file vault; return ;;
+        --shell) _values 'shell' bash zsh sh fish nu powershell cmd; return ;;
+    esac
+    for (( i = 2; i < CURRENT; i++ )); do
+        case $words[i] in
+            --) _command_names; return ;;
+            -o|--output|--backend) (( i++ )) ;;
+            -*) ;;
+            *) if [[ -z $cmd ]]; then cmd=$words[i]; else (( pos++ )); fi ;;
+        esac
+    done
+    if [[ -z $cmd ]]; then
+        _describe 'command' commands
+        return
+    fi
+    (( pos == 0 )) || return
+    case $cmd in
+        @PROFILE_COMMANDS_BASH@)
+            profiles=(${(f)"$(claude-switch list --names 2>/dev/null)"})
+            _describe 'profile' profiles ;;
+        completion|init) _values 'shell' bash zsh fish ;;
+        encrypt) _values 'action' enable disable status ;;
+        service) _values 'action' install uninstall status ;;
+        sync-remote) _values 'action' push pull ;;
+        group) _values 'action' add remove list ;;
+        org) _values 'action' list use ;;
+    esac
+}
+if [[ "$funcstack[1]" == "_claude_switch" ]]; then
+    _claude_switch "$@"
+else
+    compdef _claude_switch claude-switch
+fi
+`
+
+const fishCompletion = `# fish completion for claude-switch
+complete -c claude-switch -f
+complete -c claude-switch -s o -l output -x -a "text json" -d "Output format"
+complete -c claude-switch -l json -d "Shorthand for --output json"
+complete -c claude-switch -l backend -x -a "file vault" -d "Storage backend"
+@COMMANDS_FISH@
+complete -c claude-switch -n "__fish_seen_subcommand_from @PROFILE_COMMANDS@; and test (count (commandline -opc)) -le 2" -a "(claude-switch list --names 2>/dev/null)"
+complete -c claude-switch -n "__fish_seen_subcommand_from completion init" -a "bash zsh fish"
+complete -c claude-switch -n "__fish_seen_subcommand_from encrypt" -a "enable disable status"
+complete -c claude-switch -n "__fish_seen_subcommand_from service" -a "install uninstall status"
+complete -c claude-switch -n "__fish_seen_subcommand_from sync-remote" -a "push pull"
+complete -c claude-switch -n "__fish_seen_subcommand_from group" -a "add remove list"
+complete -c claude-switch -n "__fish_seen_subcommand_from org" -a "list use"
+complete -c claude-switch -n "__fish_seen_subcommand_from env" -l shell -x -a "bash zsh sh fish nu powershell cmd"
+`
+
+func cmdCompletion(args []string) error {
+	if len(args) != 1 {
+		return usageError("completion requires a shell: bash, zsh or fish")
+	}
+	var names, zsh, fish []string
+	for _, c := range completionCommands {
+		names = append(names, c[0])
+		zsh = append(zsh, fmt.Sprintf("        %q", c[0]+":"+c[1]))
+		fish = append(fish, fmt.Sprintf("complete -c claude-switch -n __fish_use_subcommand -a %s -d %q", c[0], c[1]))
+	}
+	r := strings.NewReplacer(
+		"@COMMANDS@", strings.Join(names, " "),
+		"@COMMANDS_ZSH@", strings.Join(zsh, "\n"),
+		"@COMMANDS_FISH@", strings.Join(fish, "\n"),
+		"@PROFILE_COMMANDS_BASH@", strings.Join(profileCommands, "|"),
+		"@PROFILE_COMMANDS@", strings.Join(profileCommands, " "),
+	)
+	switch args[0] {
+	case "bash":
+		fmt.Print(r.Replace(bashCompletion))
+	case "zsh":
+		fmt.Print(r.Replace(zshCompletion))
+	case "fish":
+		fmt.Print(r.Replace(fishCompletion))
+	default:
+		return usageError("unsupported shell '%s' (expected bash, zsh or fish)", args[0])
+	}
+	return nil
+}
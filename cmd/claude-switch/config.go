@@ -0,0 +1,518 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// --- User configuration (config.toml) ---
+
+type Config struct {
+	Defaults Defaults `toml:"defaults"`
+	// Named, ordered lists of profiles that exec --chain walks until one has
+	// usable credentials.
+	Chains map[string][]string `toml:"chains"`
+	// Per-profile overrides, keyed by profile name.
+	Profiles map[string]ProfileConfig `toml:"profiles"`
+	Storage  StorageConfig            `toml:"storage"`
+	Remote   RemoteConfig             `toml:"remote"`
+	OAuth    OAuthConfig              `toml:"oauth"`
+}
+
+// Defaults are tool-wide settings. Unset fields keep the built-in behaviour.
+type Defaults struct {
+	// Profile `use` switches to when given no name and no .claude-profile
+	// applies.
+	Profile string `toml:"profile"`
+	// Output format when neither --output nor --json is given.
+	Output string `toml:"output"`
+	// Color forces colour on (true) or off (false) instead of using it
+	// only on terminals. NO_COLOR and --color take precedence.
+	Color *bool `toml:"color"`
+	// claude binary for profiles without their own claude_bin.
+	ClaudeBin string `toml:"claude_bin"`
+	// Arguments add --claude passes to claude to log in, for profiles
+	// without their own login_args. Defaults to ["/login"].
+	LoginArgs []string `toml:"login_args"`
+	// Keychain set to false keeps claude-switch away from the system
+	// keychain, for hosts where Claude only uses .credentials.json and the
+	// keychain prompts or is locked.
+	Keychain *bool `toml:"keychain"`
+	// RefreshBuffer is how long before expiry a token is treated as expired
+	// and refreshed, e.g. "10m". Defaults to 5m.
+	RefreshBuffer string `toml:"refresh_buffer"`
+	// Audit records profile switches, commands started under a profile, and
+	// adds and removes in audit.jsonl, for `history`.
+	Audit bool `toml:"audit"`
+	// CABundle is a PEM file of CA certificates trusted for requests to
+	// Anthropic, besides the system's, e.g. for a TLS-inspecting proxy.
+	CABundle string `toml:"ca_bundle"`
+	// Store is where new profiles keep their secrets: "file" (the default)
+	// or "keychain", the system keychain. `config set store` moves
+	// existing profiles as well.
+	Store string `toml:"store"`
+	// Reconcile set to false stops every command from first saving tokens
+	// Claude rotated on its own to their profile; see reconcile.
+	Reconcile *bool `toml:"reconcile"`
+	// DPAPI set to false leaves profile files on Windows unprotected when
+	// no passphrase is set; see dpapi.go. It has no effect elsewhere.
+	DPAPI *bool `toml:"dpapi"`
+	// Picker is what use without a name picks the profile with on a
+	// terminal: "menu" (the default) or "fzf", when fzf is installed.
+	Picker string `toml:"picker"`
+	// APIKeyHelper makes use of an API key profile set Claude's
+	// apiKeyHelper to claude-switch, which Claude then asks for the key;
+	// see api-key-helper.
+	APIKeyHelper bool `toml:"api_key_helper"`
+
+	refreshBuffer time.Duration
+	rootCAs       *x509.CertPool // nil: the system's
+}
+
+// defaults is the [defaults] table, read once at startup by loadDefaults.
+var defaults = Defaults{refreshBuffer: 5 * time.Minute}
+
+// loadDefaults reads the [defaults] table. A config.toml that doesn't parse
+// is left for the commands that need it (and doctor) to report, and means
+// built-in defaults here.
+func loadDefaults() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return applyOAuthConfig(OAuthConfig{})
+	}
+	if err := applyOAuthConfig(cfg.OAuth); err != nil {
+		return err
+	}
+	d := cfg.Defaults
+	invalid := func(key string, value any, want string) error {
+		return &cliError{
+			Code:    errConfig,
+			Message: fmt.Sprintf("invalid %s '%v' under [defaults] in %s", key, value, configPath()),
+			Hint:    "expected " + want,
+		}
+	}
+	if d.Output != "" && d.Output != "text" && d.Output != "json" {
+		return invalid("output", d.Output, "text or json")
+	}
+	if d.Store != "" && d.Store != "file" && d.Store != keychainStore {
+		return invalid("store", d.Store, "file or keychain")
+	}
+	if d.Picker != "" && d.Picker != "menu" && d.Picker != "fzf" {
+		return invalid("picker", d.Picker, "menu or fzf")
+	}
+	d.refreshBuffer = defaults.refreshBuffer
+	if d.RefreshBuffer != "" {
+		if d.refreshBuffer, err = time.ParseDuration(d.RefreshBuffer); err != nil || d.refreshBuffer < 0 {
+			return invalid("refresh_buffer", d.RefreshBuffer, "a duration such as 10m")
+		}
+	}
+	if d.CABundle != "" {
+		if d.rootCAs, err = loadCABundle(expandHome(d.CABundle)); err != nil {
+			return &cliError{
+				Code:    errConfig,
+				Message: fmt.Sprintf("invalid ca_bundle under [defaults] in %s: %v", configPath(), err),
+				Hint:    "expected a PEM file of CA certificates",
+				Err:     err,
+			}
+		}
+	}
+	defaults = d
+	return nil
+}
+
+// loadCABundle returns the system's trusted roots plus the certificates in
+// the PEM file at path.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New(path + " holds no PEM certificates")
+	}
+	return pool, nil
+}
+
+// OAuthConfig points the OAuth client at other endpoints, for enterprise
+// gateways and test servers, or keeps working after Anthropic moves them.
+// Unset fields keep the built-in values.
+type OAuthConfig struct {
+	TokenURL     string `toml:"token_url"`
+	AuthorizeURL string `toml:"authorize_url"`
+	// RevokeURL defaults to "revoke" next to a token_url ending in "token".
+	RevokeURL string `toml:"revoke_url"`
+	ClientID  string `toml:"client_id"`
+	// Scopes are space-separated, as in the OAuth scope parameter.
+	Scopes string `toml:"scopes"`
+}
+
+// applyOAuthConfig overrides the OAuth client with c and then with the
+// CLAUDE_SWITCH_OAUTH_* variables, which take precedence.
+func applyOAuthConfig(c OAuthConfig) error {
+	for _, o := range []struct {
+		key, env string
+		value    *string
+		url      bool
+	}{
+		{"token_url", "CLAUDE_SWITCH_OAUTH_TOKEN_URL", &c.TokenURL, true},
+		{"authorize_url", "CLAUDE_SWITCH_OAUTH_AUTHORIZE_URL", &c.AuthorizeURL, true},
+		{"revoke_url", "CLAUDE_SWITCH_OAUTH_REVOKE_URL", &c.RevokeURL, true},
+		{"client_id", "CLAUDE_SWITCH_OAUTH_CLIENT_ID", &c.ClientID, false},
+		{"scopes", "CLAUDE_SWITCH_OAUTH_SCOPES", &c.Scopes, false},
+	} {
+		source := fmt.Sprintf("%s under [oauth] in %s", o.key, configPath())
+		if v := os.Getenv(o.env); v != "" {
+			*o.value, source = v, o.env
+		}
+		if !o.url || *o.value == "" {
+			continue
+		}
+		if u, err := url.Parse(*o.value); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return &cliError{
+				Code:    errConfig,
+				Message: fmt.Sprintf("invalid %s '%s'", source, *o.value),
+				Hint:    "expected an http(s) URL",
+			}
+		}
+	}
+
+	if c.TokenURL != "" {
+		tokenURL = c.TokenURL
+		// Revoking at Anthropic tokens issued elsewhere would leak them, so
+		// without a sibling revoke endpoint there is none.
+		revokeURL = ""
+		if base, ok := strings.CutSuffix(c.TokenURL, "/token"); ok {
+			revokeURL = base + "/revoke"
+		}
+	}
+	if c.RevokeURL != "" {
+		revokeURL = c.RevokeURL
+	}
+	if c.AuthorizeURL != "" {
+		authorizeURL = c.AuthorizeURL
+	}
+	if c.ClientID != "" {
+		clientID = c.ClientID
+	}
+	if c.Scopes != "" {
+		scopes = strings.Join(strings.Fields(c.Scopes), " ")
+	}
+	return nil
+}
+
+func (d *Defaults) keychainEnabled() bool {
+	return d.Keychain == nil || *d.Keychain
+}
+
+func (d *Defaults) reconcileEnabled() bool {
+	return d.Reconcile == nil || *d.Reconcile
+}
+
+func (d *Defaults) dpapiEnabled() bool {
+	return dpapiSupported && (d.DPAPI == nil || *d.DPAPI)
+}
+
+// newProfileStore is the store of profiles added or imported without
+// --store.
+func (d *Defaults) newProfileStore() string {
+	if d.Store == keychainStore {
+		return keychainStore
+	}
+	return ""
+}
+
+type StorageConfig struct {
+	// Where profiles are kept; see storageBackends. Defaults to "file".
+	Backend string      `toml:"backend"`
+	Vault   VaultConfig `toml:"vault"`
+}
+
+// VaultConfig locates profiles in a Vault KV secrets engine. Unset fields
+// fall back to the standard VAULT_* environment variables and defaults.
+type VaultConfig struct {
+	Address   string `toml:"address"`
+	Namespace string `toml:"namespace"`
+	Mount     string `toml:"mount"`
+	Path      string `toml:"path"`
+	KVVersion int    `toml:"kv_version"`
+}
+
+type ProfileConfig struct {
+	// claude binary to run for this profile, e.g. a pinned version.
+	ClaudeBin string `toml:"claude_bin"`
+	// Arguments that make it log in, for wrappers that don't take /login.
+	LoginArgs []string `toml:"login_args"`
+}
+
+func configPath() string {
+	return filepath.Join(configDir(), "config.toml")
+}
+
+// loadConfig reads config.toml. A missing file is an empty config; a file
+// that doesn't parse is an error, since silently ignoring it would apply
+// defaults the user didn't ask for.
+func loadConfig() (*Config, error) {
+	var cfg Config
+	if _, err := toml.DecodeFile(configPath(), &cfg); err != nil {
+		if os.IsNotExist(err) {
+			return &cfg, nil
+		}
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("failed to parse %s: %v", configPath(), err), Err: err}
+	}
+	return &cfg, nil
+}
+
+// expandHome expands a leading ~/ so config values can point into $HOME.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// --- config: read and change [defaults] ---
+
+// defaultsKey finds the [defaults] setting called key. Only single values
+// can be read and set; lists such as login_args are edited in the file.
+func defaultsKey(key string) (reflect.StructField, error) {
+	t := reflect.TypeFor[Defaults]()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Tag.Get("toml") != key || !f.IsExported() {
+			continue
+		}
+		if k := f.Type.Kind(); k == reflect.String || k == reflect.Bool || (k == reflect.Pointer && f.Type.Elem().Kind() == reflect.Bool) {
+			return f, nil
+		}
+		return f, usageError("%s is a list; edit it in %s", key, configPath())
+	}
+	return reflect.StructField{}, usageError("unknown setting '%s'", key)
+}
+
+func cmdConfig(args []string) error {
+	if len(args) == 0 {
+		return usageError("config requires one of: get, set, unset, path")
+	}
+	want := map[string]int{"get": 2, "set": 3, "unset": 2, "path": 1}[args[0]]
+	switch {
+	case want == 0:
+		return usageError("unknown config subcommand: %s", args[0])
+	case len(args) != want && args[0] == "set":
+		return usageError("usage: config set <key> <value>")
+	case len(args) != want && args[0] == "path":
+		return usageError("unexpected argument: %s", args[1])
+	case len(args) != want:
+		return usageError("usage: config %s <key>", args[0])
+	}
+	if args[0] == "path" {
+		fmt.Println(configPath())
+		return nil
+	}
+	field, err := defaultsKey(args[1])
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "get":
+		return configGet(field)
+	case "set":
+		return configSet(field, args[2])
+	}
+	return configSet(field, "")
+}
+
+// configGet prints a setting as written in config.toml, or nothing if it
+// isn't set.
+func configGet(field reflect.StructField) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(cfg.Defaults).FieldByIndex(field.Index)
+	var value any
+	switch {
+	case v.Kind() == reflect.Pointer && !v.IsNil():
+		value = v.Elem().Interface()
+	case v.Kind() != reflect.Pointer && !v.IsZero():
+		value = v.Interface()
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"key": field.Tag.Get("toml"), "value": value})
+	}
+	if value != nil {
+		fmt.Println(value)
+	}
+	return nil
+}
+
+// configSet writes key = value under [defaults] in config.toml, or removes
+// the line for an empty value, keeping the rest of the file as it is. A
+// value that loadDefaults would reject is not written.
+func configSet(field reflect.StructField, value string) error {
+	key := field.Tag.Get("toml")
+	var line string
+	if value != "" {
+		var typed any = value
+		if field.Type.Kind() != reflect.String {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return usageError("%s must be true or false", key)
+			}
+			typed = b
+		}
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(map[string]any{key: typed}); err != nil {
+			return err
+		}
+		line = strings.TrimSpace(buf.String())
+	}
+
+	var updated []byte
+	previous := defaults
+	err := withLock(configLock, func() error {
+		old, err := readFile(configPath())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		updated = setDefaultsLine(old, key, line)
+		var check Config
+		if _, err := toml.Decode(string(updated), &check); err != nil {
+			return &cliError{Code: errConfig, Message: fmt.Sprintf("failed to parse %s: %v", configPath(), err), Hint: "fix the syntax by hand first", Err: err}
+		}
+		if err := writeSecure(configPath(), updated); err != nil {
+			return err
+		}
+		if err := loadDefaults(); err != nil {
+			if old == nil {
+				os.Remove(configPath())
+			} else if werr := writeSecure(configPath(), old); werr != nil {
+				return werr
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Changing where secrets are kept moves the existing ones too.
+	moved := -1
+	if key == "store" && previous.newProfileStore() != defaults.newProfileStore() {
+		if moved, err = moveProfileSecrets(defaults.newProfileStore() == keychainStore); err != nil {
+			return fmt.Errorf("%s was updated, but moving the profiles' secrets failed: %w; run 'claude-switch config set store %s' again to finish", configPath(), err, cmp.Or(defaults.Store, "file"))
+		}
+	}
+
+	// So does turning DPAPI on or off, unless a passphrase seals them anyway.
+	rewritten := -1
+	if key == "dpapi" && previous.dpapiEnabled() != defaults.dpapiEnabled() && !encryptionEnabled() {
+		if rewritten, err = rewriteProfiles(); err == nil && defaults.dpapiEnabled() {
+			_, err = purgePlaintextBackups()
+		}
+		if err != nil {
+			return fmt.Errorf("%s was updated, but rewriting the profiles failed: %w", configPath(), err)
+		}
+	}
+
+	if jsonOutput() {
+		result := map[string]any{"key": key, "value": nil}
+		if value != "" {
+			result["value"] = value
+		}
+		if moved >= 0 {
+			result["moved"] = moved
+		}
+		if rewritten >= 0 {
+			result["rewritten"] = rewritten
+		}
+		return printJSON(result)
+	}
+	if line == "" {
+		infof("Removed %s from %s.\n", key, configPath())
+	} else {
+		infof("Set %s in %s.\n", line, configPath())
+	}
+	switch {
+	case moved < 0:
+	case defaults.newProfileStore() == keychainStore:
+		infof("Moved the secrets of %d profile(s) to the keychain.\n", moved)
+	default:
+		infof("Moved the secrets of %d profile(s) out of the keychain into their files.\n", moved)
+	}
+	switch {
+	case rewritten < 0:
+	case defaults.dpapiEnabled():
+		infof("Protected %d profile(s) with DPAPI.\n", rewritten)
+	default:
+		infof("Wrote %d profile(s) back as plaintext.\n", rewritten)
+	}
+	return nil
+}
+
+// setDefaultsLine replaces key's line in the [defaults] table of a TOML
+// document with line, removes it if line is empty, or adds it, appending the
+// table if there is none.
+func setDefaultsLine(doc []byte, key, line string) []byte {
+	lines := strings.Split(strings.TrimRight(string(doc), "\n"), "\n")
+	if len(doc) == 0 {
+		lines = nil
+	}
+	table, last := "", -1
+	found := -1
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "[") {
+			table = strings.TrimSpace(strings.Trim(strings.SplitN(trimmed, "#", 2)[0], " []"))
+			if table == "defaults" {
+				last = i
+			}
+			continue
+		}
+		if table != "defaults" {
+			continue
+		}
+		if trimmed != "" {
+			last = i
+		}
+		if k, _, ok := strings.Cut(trimmed, "="); ok && strings.Trim(strings.TrimSpace(k), `"'`) == key {
+			found = i
+		}
+	}
+	switch {
+	case found >= 0 && line == "":
+		lines = slices.Delete(lines, found, found+1)
+	case found >= 0:
+		lines[found] = line
+	case line == "":
+	case last >= 0:
+		lines = slices.Insert(lines, last+1, line)
+	default:
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "[defaults]", line)
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
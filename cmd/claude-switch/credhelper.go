@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- credential-helper: git-credential-style protocol ---
+
+// credential-helper answers requests in the style of git's credential
+// helpers, for wrappers and SDK shims that want a fresh credential without
+// parsing output meant for people. The request is key=value lines on stdin,
+// ended by a blank line or EOF; keys it doesn't know are ignored, so callers
+// can send more than it needs. The reply is key=value lines on stdout:
+//
+//	profile=work
+//	variable=CLAUDE_CODE_OAUTH_TOKEN
+//	token=sk-ant-oat01-...
+//	expires_at=1792249196
+//	env[]=HTTPS_PROXY=http://proxy:3128
+//
+// expires_at is in Unix seconds on the local clock and only present for
+// OAuth tokens; env[] repeats for each extra variable of the profile.
+
+func cmdCredentialHelper(args []string) error {
+	action := "get"
+	switch len(args) {
+	case 0:
+	case 1:
+		action = args[0]
+	default:
+		return usageError("credential-helper takes at most one action (get)")
+	}
+	request, err := readCredentialRequest(os.Stdin)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "get":
+	case "store", "erase":
+		// git sends these after using a credential; tokens are only ever
+		// stored by claude-switch itself.
+		return nil
+	default:
+		return usageError("unknown action '%s' (expected get)", action)
+	}
+
+	name := request["profile"]
+	if name == "" {
+		if name, err = runProfile(); err != nil {
+			return err
+		}
+	}
+	// No prefix matching: a script must get the account it asked for.
+	if name, err = resolveProfileName(name, false); err != nil {
+		return err
+	}
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		return err
+	}
+	if len(vars) == 0 {
+		return &cliError{Code: errNoCredentials, Message: "no credential found", Profile: name}
+	}
+
+	reply := [][2]string{{"profile", name}, {"variable", vars[0].Key}, {"token", vars[0].Value}}
+	if vars[0].Key == "CLAUDE_CODE_OAUTH_TOKEN" {
+		index := loadIndex()
+		if meta, ok := index.Profiles[name]; ok && meta.ExpiresAt != nil {
+			expires := time.UnixMilli(int64(*meta.ExpiresAt) - clockSkewMs())
+			reply = append(reply, [2]string{"expires_at", strconv.FormatInt(expires.Unix(), 10)})
+		}
+	}
+	for _, v := range vars[1:] {
+		reply = append(reply, [2]string{"env[]", v.Key + "=" + v.Value})
+	}
+	var b strings.Builder
+	for _, kv := range reply {
+		if strings.ContainsAny(kv[1], "\r\n\x00") {
+			return &cliError{Code: errGeneric, Message: fmt.Sprintf("%s of '%s' contains a line break, which the protocol can't carry", kv[0], name), Profile: name}
+		}
+		fmt.Fprintf(&b, "%s=%s\n", kv[0], kv[1])
+	}
+	_, err = io.WriteString(os.Stdout, b.String())
+	return err
+}
+
+// readCredentialRequest reads key=value lines up to a blank line or EOF.
+// A repeated key keeps its last value.
+func readCredentialRequest(r io.Reader) (map[string]string, error) {
+	request := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || key == "" {
+			return nil, usageError("malformed request line '%s' (expected key=value)", line)
+		}
+		request[key] = value
+	}
+	return request, scanner.Err()
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// --- Encryption at rest (encrypt enable) ---
+
+// Profiles are sealed with AES-256-GCM under a key derived from a
+// passphrase. The KDF parameters live in encryption.json; its presence is
+// what turns encryption on. The index stays plaintext, so list never needs
+// the passphrase.
+
+const (
+	kdfIterations = 600_000
+	// checkPlaintext is sealed into encryption.json so a wrong passphrase is
+	// reported as such rather than as a corrupt profile.
+	checkPlaintext = "claude-switch"
+)
+
+type encryptionParams struct {
+	KDF        string      `json:"kdf"`
+	Iterations int         `json:"iterations"`
+	Salt       []byte      `json:"salt"`
+	Check      *sealedData `json:"check"`
+}
+
+// sealedData is the on-disk form of an encrypted profile.
+type sealedData struct {
+	Encrypted  int    `json:"encrypted"`
+	Nonce      []byte `json:"nonce,omitempty"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Values of sealedData.Encrypted.
+const (
+	sealedPassphrase = 1
+	sealedDPAPI      = 2 // see dpapi.go
+)
+
+// profileKey is derived at most once per invocation.
+var profileKey []byte
+
+func encryptionPath() string {
+	return filepath.Join(configDir(), "encryption.json")
+}
+
+func encryptionEnabled() bool {
+	_, err := os.Stat(encryptionPath())
+	return err == nil
+}
+
+func loadEncryptionParams() (*encryptionParams, error) {
+	data, err := readFile(encryptionPath())
+	if err != nil {
+		return nil, err
+	}
+	var params encryptionParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("invalid %s: %v", encryptionPath(), err), Err: err}
+	}
+	if params.KDF != "pbkdf2-sha256" || params.Check == nil {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("unsupported encryption settings in %s", encryptionPath())}
+	}
+	return &params, nil
+}
+
+// readPassphrase takes the passphrase from CLAUDE_SWITCH_PASSPHRASE or the
+// terminal. It never reads piped stdin, which belongs to the command.
+func readPassphrase(prompt string) (string, error) {
+	if p := os.Getenv("CLAUDE_SWITCH_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	if !stdinIsTerminal() {
+		return "", &cliError{
+			Code:    errDecryptFailed,
+			Message: "profiles are encrypted and no passphrase is available",
+			Hint:    "set CLAUDE_SWITCH_PASSPHRASE when running non-interactively",
+		}
+	}
+	return readSecret(prompt)
+}
+
+func deriveKey(passphrase string, params *encryptionParams) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, params.Salt, params.Iterations, 32)
+}
+
+// unlockDaemon unlocks the profiles before agent or watch starts, since a
+// background process can't prompt. A spawned daemon reads the passphrase from
+// stdin (fromStdin) so it never sits in its environment, and the variable is
+// cleared once the profiles are unlocked so nothing started later inherits it.
+// It returns the passphrase to hand on to a daemon.
+func unlockDaemon(fromStdin bool) (string, error) {
+	params, err := loadEncryptionParams()
+	if err != nil {
+		return "", err
+	}
+	var passphrase string
+	if fromStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		passphrase = string(data)
+	} else if passphrase, err = readPassphrase("Passphrase: "); err != nil {
+		return "", err
+	}
+	if _, err := unlockWith(passphrase, params); err != nil {
+		return "", err
+	}
+	os.Unsetenv("CLAUDE_SWITCH_PASSPHRASE")
+	return passphrase, nil
+}
+
+func unlockProfiles() ([]byte, error) {
+	if profileKey != nil {
+		return profileKey, nil
+	}
+	params, err := loadEncryptionParams()
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	return unlockWith(passphrase, params)
+}
+
+func unlockWith(passphrase string, params *encryptionParams) ([]byte, error) {
+	key, err := deriveKey(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := openData(key, "check", params.Check); err != nil || string(check) != checkPlaintext {
+		return nil, &cliError{Code: errDecryptFailed, Message: "wrong passphrase"}
+	}
+	profileKey = key
+	return key, nil
+}
+
+// sealData encrypts plaintext, binding it to label (the profile name) so a
+// sealed file can't be swapped in under another name.
+func sealData(key []byte, label string, plaintext []byte) (*sealedData, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &sealedData{
+		Encrypted:  sealedPassphrase,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(label)),
+	}, nil
+}
+
+func openData(key []byte, label string, sealed *sealedData) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+	return aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(label))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// parseSealed returns the sealed form of a profile file, or nil if the file
+// is plaintext.
+func parseSealed(data []byte) *sealedData {
+	var sealed sealedData
+	if json.Unmarshal(data, &sealed) != nil || sealed.Encrypted == 0 {
+		return nil
+	}
+	return &sealed
+}
+
+func sealProfile(name string, data []byte) ([]byte, error) {
+	key, err := unlockProfiles()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealData(key, name, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(sealed, "", "  ")
+}
+
+func openProfile(name string, sealed *sealedData) ([]byte, error) {
+	if sealed.Encrypted == sealedDPAPI {
+		return unprotectProfile(name, sealed)
+	}
+	key, err := unlockProfiles()
+	if err != nil {
+		return nil, err
+	}
+	data, err := openData(key, name, sealed)
+	if err != nil {
+		return nil, &cliError{Code: errDecryptFailed, Message: fmt.Sprintf("failed to decrypt profile '%s'", name), Profile: name, Err: err}
+	}
+	return data, nil
+}
+
+func cmdEncrypt(args []string) error {
+	if len(args) != 1 {
+		return usageError("encrypt requires one of: enable, disable, status")
+	}
+	if !usingFileStore() {
+		return &cliError{Code: errConfig, Message: "encryption only applies to the file storage backend"}
+	}
+	switch args[0] {
+	case "enable":
+		return encryptEnable()
+	case "disable":
+		return encryptDisable()
+	case "status":
+		return encryptStatus()
+	}
+	return usageError("unknown encrypt subcommand: %s", args[0])
+}
+
+// encryptEnable writes the KDF parameters and then re-saves every profile,
+// which seals it. Loading accepts both forms, so an interrupted migration
+// is finished by simply running it again.
+func encryptEnable() error {
+	if encryptionEnabled() {
+		if _, err := unlockProfiles(); err != nil {
+			return err
+		}
+		return reportEncrypted()
+	}
+	passphrase, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return usageError("the passphrase can't be empty")
+	}
+	if os.Getenv("CLAUDE_SWITCH_PASSPHRASE") == "" {
+		again, err := readSecret("Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if again != passphrase {
+			return usageError("passphrases don't match")
+		}
+	}
+
+	params := &encryptionParams{KDF: "pbkdf2-sha256", Iterations: kdfIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(params.Salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, params)
+	if err != nil {
+		return err
+	}
+	if params.Check, err = sealData(key, "check", []byte(checkPlaintext)); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeSecure(encryptionPath(), data); err != nil {
+		return err
+	}
+	profileKey = key
+	return reportEncrypted()
+}
+
+func reportEncrypted() error {
+	count, err := rewriteProfiles()
+	if err != nil {
+		return err
+	}
+	// Re-saving backed up the plaintext versions; they must not outlive them.
+	if _, err := purgePlaintextBackups(); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"encrypted": true, "profiles": count})
+	}
+	infof("Encryption enabled; %d profile(s) encrypted.\n", count)
+	infof("Set CLAUDE_SWITCH_PASSPHRASE to use profiles non-interactively.\n")
+	return nil
+}
+
+// encryptDisable writes every profile back as plaintext, or protected with
+// DPAPI on Windows, before removing the parameters, so nothing is left
+// sealed under a key that can't be derived. Profiles in the trash are
+// decrypted too, so remove --undo still works.
+func encryptDisable() error {
+	if !encryptionEnabled() {
+		return &cliError{Code: errConfig, Message: "encryption is not enabled"}
+	}
+	if _, err := unlockProfiles(); err != nil {
+		return err
+	}
+	names, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		profile, err := loadProfile(name)
+		if err != nil {
+			return err
+		}
+		if profile.Store != "" {
+			profile = profile.WithoutSecrets()
+		}
+		data, err := json.MarshalIndent(profile, "", "  ")
+		if err != nil {
+			return err
+		}
+		if defaults.dpapiEnabled() {
+			if data, err = protectProfile(name, data); err != nil {
+				return err
+			}
+		}
+		if err := writeSecure(profilePath(name), data); err != nil {
+			return err
+		}
+	}
+	if err := decryptTrash(); err != nil {
+		return err
+	}
+	if err := os.Remove(encryptionPath()); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"encrypted": false, "profiles": len(names)})
+	}
+	infof("Encryption disabled; decrypted %d profile(s).\n", len(names))
+	return nil
+}
+
+func encryptStatus() error {
+	names, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	sealed, protected := 0, 0
+	for _, name := range names {
+		data, err := readFile(profilePath(name))
+		if err != nil {
+			continue
+		}
+		switch s := parseSealed(data); {
+		case s == nil:
+		case s.Encrypted == sealedDPAPI:
+			protected++
+		default:
+			sealed++
+		}
+	}
+	plaintext := len(names) - sealed - protected
+	enabled := encryptionEnabled()
+	if jsonOutput() {
+		result := map[string]any{"enabled": enabled, "encrypted_profiles": sealed, "plaintext_profiles": plaintext}
+		if dpapiSupported {
+			result["dpapi_profiles"] = protected
+		}
+		return printJSON(result)
+	}
+	if enabled {
+		fmt.Printf("enabled (%d of %d profile(s) encrypted)\n", sealed, len(names))
+	} else {
+		fmt.Printf("disabled (%d profile(s) in plaintext)\n", plaintext)
+	}
+	if protected > 0 {
+		fmt.Printf("%d profile(s) protected with DPAPI\n", protected)
+	}
+	if enabled && sealed < len(names) {
+		fmt.Fprintln(os.Stderr, "Some profiles aren't encrypted with the passphrase yet; run 'claude-switch encrypt enable' to encrypt them.")
+	}
+	return nil
+}
+
+// rewriteProfiles loads and saves every profile, converting it to the
+// current storage form.
+func rewriteProfiles() (int, error) {
+	names, err := listProfiles()
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range names {
+		profile, err := loadProfile(name)
+		if err != nil {
+			return 0, err
+		}
+		if err := saveProfile(name, profile); err != nil {
+			return 0, err
+		}
+	}
+	return len(names), nil
+}
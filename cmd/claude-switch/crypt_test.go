@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	plaintext := []byte(`{"type":"oauth"}`)
+	sealed, err := sealData(key, "work", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.Encrypted == 0 {
+		t.Error("sealed data isn't marked encrypted")
+	}
+	if bytes.Contains(sealed.Ciphertext, plaintext) {
+		t.Error("ciphertext contains the plaintext")
+	}
+	got, err := openData(key, "work", sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("openData = %q, want %q", got, plaintext)
+	}
+}
+
+func TestOpenDataRejects(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	sealed, err := sealData(key, "work", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openData(key, "personal", sealed); err == nil {
+		t.Error("opened under another label")
+	}
+	if _, err := openData(bytes.Repeat([]byte{8}, 32), "work", sealed); err == nil {
+		t.Error("opened with another key")
+	}
+	tampered := *sealed
+	tampered.Ciphertext = bytes.Clone(sealed.Ciphertext)
+	tampered.Ciphertext[0] ^= 1
+	if _, err := openData(key, "work", &tampered); err == nil {
+		t.Error("opened tampered ciphertext")
+	}
+	short := *sealed
+	short.Nonce = sealed.Nonce[:4]
+	if _, err := openData(key, "work", &short); err == nil {
+		t.Error("opened with a short nonce")
+	}
+}
+
+func TestSealDataFreshNonce(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	a, err := sealData(key, "work", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := sealData(key, "work", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.Nonce, b.Nonce) {
+		t.Error("two seals share a nonce")
+	}
+}
+
+func TestUnlockDaemonClearsPassphrase(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_SANDBOX", "1")
+	t.Setenv("CLAUDE_SWITCH_SANDBOX_DIR", t.TempDir())
+	t.Setenv("CLAUDE_SWITCH_PASSPHRASE", "hunter2")
+	t.Cleanup(func() { profileKey = nil })
+	if err := encryptEnable(); err != nil {
+		t.Fatal(err)
+	}
+
+	profileKey = nil
+	passphrase, err := unlockDaemon(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "hunter2" || profileKey == nil {
+		t.Errorf("unlockDaemon = %q with key %v, want the passphrase and a key", passphrase, profileKey != nil)
+	}
+	if _, ok := os.LookupEnv("CLAUDE_SWITCH_PASSPHRASE"); ok {
+		t.Error("CLAUDE_SWITCH_PASSPHRASE is still set after unlocking")
+	}
+
+	profileKey = nil
+	t.Setenv("CLAUDE_SWITCH_PASSPHRASE", "wrong")
+	if _, err := unlockDaemon(false); err == nil {
+		t.Error("unlocked with the wrong passphrase")
+	}
+	if os.Getenv("CLAUDE_SWITCH_PASSPHRASE") != "wrong" {
+		t.Error("CLAUDE_SWITCH_PASSPHRASE was cleared after a failed unlock")
+	}
+}
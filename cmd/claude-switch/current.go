@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// cmdCurrent prints the active profile for shell prompts. It only reads
+// state.json, plus the metadata index when --format asks for more than the
+// name, so it stays cheap enough to run on every prompt.
+func cmdCurrent(args []string) error {
+	format := ""
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--format" || a == "-f":
+			if i+1 >= len(args) {
+				return usageError("%s requires a value", a)
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(a, "--format="):
+			format = strings.TrimPrefix(a, "--format=")
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+
+	state := loadState()
+	if state.ActiveProfile == nil {
+		if jsonOutput() {
+			return printJSON(nil)
+		}
+		// No output and a failing status, so prompts can simply omit the segment
+		os.Exit(1)
+	}
+	name := *state.ActiveProfile
+	if format == "" && !jsonOutput() {
+		fmt.Println(name)
+		return nil
+	}
+
+	index := loadIndex()
+	summary := ProfileSummary{Name: name, Active: true}
+	if meta, ok := index.Profiles[name]; ok {
+		summary = summarize(name, &meta)
+	}
+	if jsonOutput() {
+		return printJSON(summary)
+	}
+
+	fields := map[string]string{
+		"name":    summary.Name,
+		"type":    summary.Type,
+		"email":   summary.Email,
+		"org":     summary.Org,
+		"plan":    summary.Plan,
+		"expires": summary.ExpiresAt,
+	}
+	if value, ok := fields[format]; ok {
+		fmt.Println(value)
+		return nil
+	}
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return usageError("invalid --format template: %v", err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, summary); err != nil {
+		return usageError("invalid --format template: %v", err)
+	}
+	fmt.Println(out.String())
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// --- Debug logging (--verbose, CLAUDE_SWITCH_DEBUG=1) ---
+
+// With debug logging on, stderr gets a line for every file read or
+// written, keychain call and HTTP request, each stamped with the time since
+// start, so "use said it worked but Claude is still on the old account" can
+// be traced to the file or keychain item that didn't change. Secrets are
+// masked.
+
+var (
+	verbose    bool
+	debugStart = time.Now()
+)
+
+func debugEnabled() bool {
+	if verbose {
+		return true
+	}
+	v := os.Getenv("CLAUDE_SWITCH_DEBUG")
+	return v != "" && v != "0" && v != "false"
+}
+
+func debugf(format string, args ...any) {
+	if !debugEnabled() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "debug: %7.3fs "+format+"\n", append([]any{time.Since(debugStart).Seconds()}, args...)...)
+}
+
+// readFile is os.ReadFile, logged.
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		debugf("read %s (%d bytes)", path, len(data))
+	case os.IsNotExist(err):
+		debugf("read %s: not found", path)
+	default:
+		debugf("read %s: %v", path, err)
+	}
+	return data, err
+}
+
+// debugKeychain logs a keychain call and what came of it.
+func debugKeychain(op string, err error) {
+	if err != nil {
+		debugf("keychain: %s: %v", op, err)
+	} else {
+		debugf("keychain: %s", op)
+	}
+}
+
+// debugTransport logs each request's method, URL without the query,
+// status and duration. Headers and bodies, which carry the secrets, are
+// left out.
+type debugTransport struct {
+	base http.RoundTripper
+}
+
+// withDebug wraps base, which may be nil for the default transport, when
+// debug logging is on.
+func withDebug(base http.RoundTripper) http.RoundTripper {
+	if !debugEnabled() {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return debugTransport{base}
+}
+
+func (t debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := *req.URL
+	u.RawQuery = ""
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	took := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		debugf("HTTP %s %s: %v (%s)", req.Method, u.String(), err, took)
+	} else {
+		debugf("HTTP %s %s: %s (%s)", req.Method, u.String(), resp.Status, took)
+	}
+	return resp, err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// --- default: the profile used when none is named ---
+
+// The default profile is profile under [defaults] in config.toml. run,
+// exec -- and use without a name fall back to it, and prompt shows it while
+// no profile is active. default reads and sets it without editing the file
+// by hand.
+
+func cmdDefault(args []string) error {
+	var name string
+	unset := false
+	for _, a := range args {
+		switch {
+		case a == "--clear":
+			unset = true
+		case name == "" && !strings.HasPrefix(a, "-"):
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	field, err := defaultsKey("profile")
+	if err != nil {
+		return err
+	}
+	switch {
+	case unset && name != "":
+		return usageError("--clear takes no profile name")
+	case unset:
+		return configSet(field, "")
+	case name != "":
+		if name, err = resolveProfileName(name, true); err != nil {
+			return err
+		}
+		return configSet(field, name)
+	}
+
+	if jsonOutput() {
+		var profile any
+		if defaults.Profile != "" {
+			profile = defaults.Profile
+		}
+		return printJSON(map[string]any{"profile": profile})
+	}
+	if defaults.Profile == "" {
+		return &cliError{Code: errProfileNotFound, Message: "no default profile", Hint: "set one with 'claude-switch default <name>'"}
+	}
+	fmt.Println(defaults.Profile)
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Devcontainers (devcontainer <name>) ---
+
+// devcontainer prints the devcontainer.json properties that expose a
+// profile inside the container. Its initializeCommand runs
+// `devcontainer <name> --write` on the host every time the container
+// starts, which refreshes the token and writes the profile's variables to
+// <dir>/claude-switch: claude.env for docker's --env-file when the
+// container is created, and env.sh, mounted into the container, which new
+// shells source so they get the token of the latest start rather than the
+// first.
+
+const (
+	devcontainerSubdir = "claude-switch"
+	devcontainerMount  = "/run/claude-switch"
+)
+
+func cmdDevcontainer(args []string) error {
+	var name string
+	dir := ".devcontainer"
+	write := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--write":
+			write = true
+		case a == "--dir":
+			if i+1 >= len(args) {
+				return usageError("%s requires a directory", a)
+			}
+			i++
+			dir = args[i]
+		case strings.HasPrefix(a, "--dir="):
+			dir = strings.TrimPrefix(a, "--dir=")
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("devcontainer requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	if write {
+		return writeDevcontainerEnv(name, filepath.Join(dir, devcontainerSubdir))
+	}
+	if !profileExists(name) {
+		return notFoundError(name)
+	}
+
+	// Paths in devcontainer.json are relative to the workspace folder.
+	files := "${localWorkspaceFolder}/" + filepath.ToSlash(filepath.Join(dir, devcontainerSubdir))
+	initialize := "claude-switch devcontainer " + name + " --write"
+	if dir != ".devcontainer" {
+		initialize += " --dir " + quotePosix(dir)
+	}
+	source := fmt.Sprintf("[ -r %[1]s/env.sh ] && . %[1]s/env.sh", devcontainerMount)
+	snippet := map[string]any{
+		"initializeCommand": initialize,
+		"runArgs":           []string{"--env-file", files + "/claude.env"},
+		"mounts":            []string{fmt.Sprintf("source=%s,target=%s,type=bind,readonly", files, devcontainerMount)},
+		"postCreateCommand": fmt.Sprintf("for rc in ~/.bashrc ~/.zshrc; do echo %s >> \"$rc\"; done", quotePosix(source)),
+	}
+	// Unlike printJSON, keeps && and >> readable for pasting.
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(snippet); err != nil {
+		return err
+	}
+	if !jsonOutput() {
+		infof("Merge these properties into %s. The token is refreshed on the host each time the container starts.\n", filepath.Join(dir, "devcontainer.json"))
+		infof("Keep %s out of version control; --write adds a .gitignore there.\n", filepath.Join(dir, devcontainerSubdir))
+	}
+	return nil
+}
+
+// writeDevcontainerEnv writes the profile's variables to dir in both forms,
+// refreshing the token first if needed.
+func writeDevcontainerEnv(name, dir string) error {
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		return err
+	}
+	envFile, err := dockerEnvFile(name, vars)
+	if err != nil {
+		return err
+	}
+	var script strings.Builder
+	fmt.Fprintf(&script, "# claude-switch profile '%s'\n", name)
+	for _, v := range vars {
+		line, err := exportLine("sh", v.Key, v.Value)
+		if err != nil {
+			return err
+		}
+		script.WriteString(line + "\n")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	for file, data := range map[string][]byte{
+		"claude.env": envFile,
+		"env.sh":     []byte(script.String()),
+		".gitignore": []byte("*\n"),
+	} {
+		if err := writeSecure(filepath.Join(dir, file), data); err != nil {
+			return err
+		}
+	}
+	markUsed(name)
+	if jsonOutput() {
+		return printJSON(map[string]any{"profile": name, "dir": dir, "variables": len(vars)})
+	}
+	infof("Wrote %d variable(s) for '%s' to %s\n", len(vars), name, dir)
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// --- Per-directory profiles (.claude-profile + shell hook) ---
+
+const dirProfileFile = ".claude-profile"
+
+// Environment variables the shell hook uses to remember what it applied.
+const (
+	hookProfileVar  = "CLAUDE_SWITCH_DIR_PROFILE"
+	hookPreviousVar = "CLAUDE_SWITCH_PREV_PROFILE"
+	hookVarsVar     = "CLAUDE_SWITCH_DIR_VARS"
+)
+
+var hookShells = []string{"bash", "zsh", "fish"}
+
+// findDirProfile looks for a .claude-profile in dir and its parents and
+// returns the profile it names along with the file's path. The first line
+// that isn't blank or a # comment is the profile name.
+func findDirProfile(dir string) (name, path string, err error) {
+	for {
+		path = filepath.Join(dir, dirProfileFile)
+		f, err := os.Open(path)
+		if err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				if err := validateProfileName(line); err != nil {
+					return "", path, fmt.Errorf("%s: %w", path, err)
+				}
+				return line, path, nil
+			}
+			return "", path, fmt.Errorf("%s doesn't name a profile", path)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", path, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// cwdProfile is findDirProfile for the working directory.
+func cwdProfile() (name, path string, err error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", "", err
+	}
+	return findDirProfile(dir)
+}
+
+const bashHook = `_claude_switch_hook() {
+    local status=$?
+    if [[ "$PWD" != "$_CLAUDE_SWITCH_PWD" ]]; then
+        _CLAUDE_SWITCH_PWD=$PWD
+        eval "$(command claude-switch hook bash@FLAGS@)"
+    fi
+    return $status
+}
+if [[ ";${PROMPT_COMMAND:-};" != *";_claude_switch_hook;"* ]]; then
+    PROMPT_COMMAND="_claude_switch_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`
+
+const zshHook = `_claude_switch_hook() {
+    eval "$(command claude-switch hook zsh@FLAGS@)"
+}
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_claude_switch_hook]} )); then
+    chpwd_functions+=(_claude_switch_hook)
+fi
+_claude_switch_hook
+`
+
+const fishHook = `function _claude_switch_hook --on-variable PWD
+    command claude-switch hook fish@FLAGS@ | source
+end
+_claude_switch_hook
+`
+
+// parseHookArgs parses "<shell> [--env]" for init and hook.
+func parseHookArgs(cmd string, args []string) (shell string, envMode bool, err error) {
+	for _, a := range args {
+		switch {
+		case a == "--env":
+			envMode = true
+		case shell == "":
+			shell = a
+		default:
+			return "", false, usageError("unexpected argument: %s", a)
+		}
+	}
+	if shell == "" {
+		return "", false, usageError("%s requires a shell: %s", cmd, strings.Join(hookShells, ", "))
+	}
+	for _, s := range hookShells {
+		if s == shell {
+			return shell, envMode, nil
+		}
+	}
+	return "", false, usageError("unsupported shell '%s' (expected %s)", shell, strings.Join(hookShells, ", "))
+}
+
+// cmdInit prints the shell hook that applies .claude-profile files on cd.
+func cmdInit(args []string) error {
+	shell, envMode, err := parseHookArgs("init", args)
+	if err != nil {
+		return err
+	}
+	flags := ""
+	if envMode {
+		flags = " --env"
+	}
+	script := map[string]string{"bash": bashHook, "zsh": zshHook, "fish": fishHook}[shell]
+	fmt.Print(strings.ReplaceAll(script, "@FLAGS@", flags))
+	return nil
+}
+
+// cmdHook is run by the shell hook on every directory change. It prints
+// shell code to eval and keeps its own state in environment variables, so
+// each shell tracks the directory it is in.
+func cmdHook(args []string) error {
+	shell, envMode, err := parseHookArgs("hook", args)
+	if err != nil {
+		return err
+	}
+	name, path, err := cwdProfile()
+	if err != nil {
+		return err
+	}
+	applied := os.Getenv(hookProfileVar)
+	if name == applied {
+		return nil
+	}
+
+	var lines []string
+	if envMode {
+		lines, err = hookEnv(shell, name, path)
+	} else {
+		lines, err = hookUse(shell, name, path, applied)
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return err
+}
+
+// hookUse switches the active profile on entering a directory with a
+// .claude-profile and switches back to the previous one on leaving it.
+func hookUse(shell, name, path, applied string) ([]string, error) {
+	var lines []string
+	export := func(key, val string) {
+		line, _ := exportLine(shell, key, val)
+		lines = append(lines, line)
+	}
+
+	if name == "" {
+		lines = append(lines, unsetLine(shell, hookProfileVar), unsetLine(shell, hookPreviousVar))
+		previous := os.Getenv(hookPreviousVar)
+		if previous == "" || !profileExists(previous) {
+			return lines, nil
+		}
+		if _, err := activateProfile(previous, false); err != nil {
+			return lines, err
+		}
+		infof("claude-switch: switched back to '%s'\n", previous)
+		return lines, nil
+	}
+
+	if applied == "" {
+		if state := loadState(); state.ActiveProfile != nil {
+			export(hookPreviousVar, *state.ActiveProfile)
+		}
+	}
+	// Record the profile even if switching fails, so the error is reported
+	// once rather than at every prompt.
+	export(hookProfileVar, name)
+	profile, err := activateProfile(name, false)
+	if err != nil {
+		return lines, err
+	}
+	infof("claude-switch: switched to '%s' (%s)\n", name, path)
+	if profile.Type != "oauth" {
+		infof("claude-switch: '%s' is a %s profile; use 'init %s --env' to have the hook export it\n", name, profile.DescribeType(), shell)
+	}
+	return lines, nil
+}
+
+// hookEnv exports a profile's environment variables into the shell on
+// entering a directory with a .claude-profile and unsets them on leaving.
+func hookEnv(shell, name, path string) ([]string, error) {
+	var lines []string
+	for _, key := range strings.Split(os.Getenv(hookVarsVar), ":") {
+		if envKeyPattern.MatchString(key) {
+			lines = append(lines, unsetLine(shell, key))
+		}
+	}
+	if name == "" {
+		return append(lines, unsetLine(shell, hookProfileVar), unsetLine(shell, hookVarsVar)), nil
+	}
+
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		line, _ := exportLine(shell, hookProfileVar, name)
+		return append(lines, line, unsetLine(shell, hookVarsVar)), err
+	}
+	var keys []string
+	for _, v := range append(vars, envVar{hookProfileVar, name}) {
+		line, _ := exportLine(shell, v.Key, v.Value)
+		lines = append(lines, line)
+		keys = append(keys, v.Key)
+	}
+	line, _ := exportLine(shell, hookVarsVar, strings.Join(keys[:len(keys)-1], ":"))
+	infof("claude-switch: loaded '%s' into the environment (%s)\n", name, path)
+	return append(lines, line), nil
+}
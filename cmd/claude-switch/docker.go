@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --- Docker (docker-env <name>, docker run <name>) ---
+
+// Containers get a profile's variables at start, so images never hold
+// credentials. docker-env writes them as an --env-file; docker run passes
+// them to `docker run` by name only (-e KEY), with the values in docker's
+// own environment, so they don't show up in the process list.
+
+// dockerEnvFile renders vars in the --env-file format: KEY=VALUE lines,
+// taken literally, so values can't contain newlines.
+func dockerEnvFile(name string, vars []envVar) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# claude-switch profile '%s'\n", name)
+	for _, v := range vars {
+		if strings.ContainsAny(v.Value, "\r\n") {
+			return nil, &cliError{
+				Code:    errConfig,
+				Message: fmt.Sprintf("the value of %s for '%s' spans lines, which an env file can't hold", v.Key, name),
+				Profile: name,
+				Hint:    fmt.Sprintf("use 'claude-switch docker run %s -- ...' instead", name),
+			}
+		}
+		fmt.Fprintf(&b, "%s=%s\n", v.Key, v.Value)
+	}
+	return []byte(b.String()), nil
+}
+
+func cmdDockerEnv(args []string) error {
+	var name, file string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--file" || a == "-f":
+			if i+1 >= len(args) {
+				return usageError("%s requires a path", a)
+			}
+			i++
+			file = args[i]
+		case strings.HasPrefix(a, "--file="):
+			file = strings.TrimPrefix(a, "--file=")
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("docker-env requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		return err
+	}
+	if jsonOutput() && file == "" {
+		env := make(map[string]string, len(vars))
+		for _, v := range vars {
+			env[v.Key] = v.Value
+		}
+		return printJSON(env)
+	}
+	data, err := dockerEnvFile(name, vars)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	path := expandHome(file)
+	if err := writeSecure(path, data); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"profile": name, "file": path, "variables": len(vars)})
+	}
+	infof("Wrote %d variable(s) for '%s' to %s; pass it with --env-file and delete it afterwards.\n", len(vars), name, path)
+	return nil
+}
+
+func cmdDocker(args []string) error {
+	if len(args) == 0 || args[0] != "run" {
+		return usageError("docker requires: run <name> -- <docker run arguments>")
+	}
+	args = args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usageError("docker run requires a profile name")
+	}
+	name, args := args[0], args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return usageError("no image specified")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	auditProfile = name
+	vars, err := credentialEnvVars(name, true)
+	if err != nil {
+		return err
+	}
+	cmdArgs := []string{"docker", "run"}
+	for _, v := range vars {
+		cmdArgs = append(cmdArgs, "-e", v.Key)
+	}
+	markUsed(name)
+	return execWithEnv(append(cmdArgs, args...), vars)
+}
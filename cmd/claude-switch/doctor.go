@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// --- doctor: diagnose why switching doesn't work ---
+
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+type doctorFinding struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// doctorChecks run in order; each reports one or more findings and never
+// stops the others from running.
+var doctorChecks = []func() []doctorFinding{
+	doctorConfigDir,
+	doctorConfigFile,
+	doctorProfiles,
+	doctorState,
+	doctorClaudeBinary,
+	doctorKeychain,
+	doctorClaudeConfig,
+	doctorTokenEndpoint,
+}
+
+func cmdDoctor(args []string) error {
+	if len(args) > 0 {
+		return usageError("unexpected argument: %s", args[0])
+	}
+
+	var findings []doctorFinding
+	for _, check := range doctorChecks {
+		findings = append(findings, check()...)
+	}
+	problems := 0
+	failed := false
+	for _, f := range findings {
+		switch f.Status {
+		case doctorFail:
+			failed = true
+			problems++
+		case doctorWarn:
+			problems++
+		}
+	}
+
+	if jsonOutput() {
+		if err := printJSON(map[string]any{"ok": !failed, "findings": findings}); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Printf("%-6s %s: %s\n", "["+f.Status+"]", f.Check, f.Message)
+			if f.Fix != "" {
+				fmt.Printf("       fix: %s\n", f.Fix)
+			}
+		}
+		if problems == 0 {
+			infof("No problems found.\n")
+		} else {
+			infof("%d problem(s) found.\n", problems)
+		}
+	}
+	if failed {
+		return &cliError{Code: errGeneric, Message: "doctor found problems that will stop claude-switch from working"}
+	}
+	return nil
+}
+
+func doctorConfigDir() []doctorFinding {
+	const check = "config dir"
+	dir := configDir()
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []doctorFinding{{check, doctorOK, dir + " doesn't exist yet (no profiles saved)", ""}}
+	}
+	if err != nil {
+		return []doctorFinding{{check, doctorFail, err.Error(), ""}}
+	}
+	if !info.IsDir() {
+		return []doctorFinding{{check, doctorFail, dir + " is not a directory", "move it out of the way"}}
+	}
+
+	var findings []doctorFinding
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		findings = append(findings, doctorFinding{check, doctorFail, fmt.Sprintf("%s is not writable: %v", dir, err), fmt.Sprintf("chown -R %s %s", os.Getenv("USER"), dir)})
+	} else {
+		probe.Close()
+		os.Remove(probe.Name())
+	}
+	if runtime.GOOS == "windows" {
+		return append(findings, doctorFinding{check, doctorOK, dir, ""})
+	}
+
+	// Secrets are written 0600, so anything broader was changed by hand or
+	// by a copy that didn't preserve modes.
+	var exposed []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && info.Mode().Perm()&0o022 != 0 {
+			findings = append(findings, doctorFinding{check, doctorWarn, fmt.Sprintf("%s is writable by other users (%04o)", path, info.Mode().Perm()), "chmod go-w " + path})
+		}
+		if !d.IsDir() && info.Mode().Perm()&0o077 != 0 {
+			exposed = append(exposed, path)
+		}
+		return nil
+	})
+	if len(exposed) > 0 {
+		findings = append(findings, doctorFinding{check, doctorWarn, fmt.Sprintf("%d file(s) readable by other users, e.g. %s", len(exposed), exposed[0]), fmt.Sprintf("chmod -R go-rwx %s", dir)})
+	}
+	if len(findings) == 0 {
+		findings = append(findings, doctorFinding{check, doctorOK, fmt.Sprintf("%s (%04o)", dir, info.Mode().Perm()), ""})
+	}
+	return findings
+}
+
+func doctorConfigFile() []doctorFinding {
+	const check = "config.toml"
+	if _, err := os.Stat(configPath()); errors.Is(err, fs.ErrNotExist) {
+		return []doctorFinding{{check, doctorOK, "not present (using defaults)", ""}}
+	}
+	if _, err := loadConfig(); err != nil {
+		return []doctorFinding{{check, doctorFail, err.Error(), "fix the syntax in " + configPath()}}
+	}
+	return []doctorFinding{{check, doctorOK, configPath(), ""}}
+}
+
+func doctorProfiles() []doctorFinding {
+	const check = "profiles"
+	names, err := listProfiles()
+	if err != nil {
+		return []doctorFinding{{check, doctorFail, fmt.Sprintf("failed to list profiles: %v", err), ""}}
+	}
+	if len(names) == 0 {
+		return []doctorFinding{{check, doctorWarn, "no profiles saved", "claude-switch add <name> or claude-switch import <name>"}}
+	}
+
+	var findings []doctorFinding
+	expired := 0
+	for _, name := range names {
+		profile, err := loadProfile(name)
+		if err != nil {
+			fix := ""
+			if ce := classifyError(err); ce.Hint != "" {
+				fix = ce.Hint
+			}
+			findings = append(findings, doctorFinding{check, doctorFail, fmt.Sprintf("'%s' can't be read: %v", name, err), fix})
+			continue
+		}
+		switch profile.Type {
+		case "oauth":
+			creds := profile.Credentials
+			if creds == nil || creds.AccessToken == "" || creds.RefreshToken == "" {
+				findings = append(findings, doctorFinding{check, doctorFail, fmt.Sprintf("'%s' has no OAuth tokens", name), fmt.Sprintf("claude-switch remove %s && claude-switch add %s", name, name)})
+			} else if isExpired(creds) {
+				expired++
+			}
+		case "oauth_token":
+			if profile.OAuthToken == "" {
+				findings = append(findings, doctorFinding{check, doctorFail, fmt.Sprintf("'%s' has no token", name), fmt.Sprintf("claude setup-token | claude-switch import %s --token-stdin --force", name)})
+			}
+		case "api_key":
+			if profile.ApiKey == "" {
+				findings = append(findings, doctorFinding{check, doctorFail, fmt.Sprintf("'%s' has no API key", name), fmt.Sprintf("claude-switch remove %s && claude-switch add %s --claude", name, name)})
+			}
+		default:
+			findings = append(findings, doctorFinding{check, doctorFail, fmt.Sprintf("'%s' has unknown type '%s'", name, profile.Type), ""})
+		}
+	}
+	msg := fmt.Sprintf("%d of %d profile(s) usable", len(names)-len(findings), len(names))
+	if expired > 0 {
+		msg += fmt.Sprintf(", %d with an expired access token (refreshed on next use)", expired)
+	}
+	return append([]doctorFinding{{check, doctorOK, msg, ""}}, findings...)
+}
+
+func doctorState() []doctorFinding {
+	const check = "state"
+	state := loadState()
+	var findings []doctorFinding
+	if state.ActiveProfile != nil && !profileExists(*state.ActiveProfile) {
+		findings = append(findings, doctorFinding{check, doctorWarn, fmt.Sprintf("active profile '%s' no longer exists", *state.ActiveProfile), "claude-switch gc"})
+	}
+	if now := nowMs(); state.RefreshCooldownUntil > now {
+		wait := time.Duration(state.RefreshCooldownUntil-now) * time.Millisecond
+		findings = append(findings, doctorFinding{check, doctorWarn, fmt.Sprintf("token refreshes are paused for another %s after the endpoint rate limited us", wait.Round(time.Second)), "wait for the cooldown; switching to profiles with valid tokens still works"})
+	}
+	if skew := time.Duration(state.ClockSkewMs) * time.Millisecond; skew.Abs() >= significantSkew {
+		findings = append(findings, doctorFinding{check, doctorWarn, fmt.Sprintf("local clock was %s off the token server at the last refresh", skew.Round(time.Second)), "enable NTP time sync"})
+	}
+	if len(findings) == 0 {
+		findings = append(findings, doctorFinding{check, doctorOK, "no cooldown or clock skew recorded", ""})
+	}
+	return findings
+}
+
+func doctorClaudeBinary() []doctorFinding {
+	const check = "claude binary"
+	active := ""
+	if state := loadState(); state.ActiveProfile != nil {
+		active = *state.ActiveProfile
+	}
+	path, err := resolveClaude(active)
+	if err != nil {
+		fix := "install Claude Code: npm install -g @anthropic-ai/claude-code"
+		if ce := classifyError(err); ce.Code == errConfig {
+			fix = "fix claude_bin in " + configPath()
+		}
+		return []doctorFinding{{check, doctorWarn, err.Error() + " (needed by exec and add --claude)", fix}}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return []doctorFinding{{check, doctorWarn, fmt.Sprintf("%s --version failed: %v", path, err), "reinstall Claude Code"}}
+	}
+	return []doctorFinding{{check, doctorOK, fmt.Sprintf("%s (%s)", path, strings.TrimSpace(string(out))), ""}}
+}
+
+func doctorKeychain() []doctorFinding {
+	const check = "keychain"
+	if sandboxed() {
+		return []doctorFinding{{check, doctorSkip, "not used in sandbox mode", ""}}
+	}
+	if !defaults.keychainEnabled() {
+		return []doctorFinding{{check, doctorSkip, "turned off in " + configPath(), ""}}
+	}
+	status, err := keychainStatus()
+	if err != nil {
+		return []doctorFinding{{check, doctorFail, err.Error(), "unlock the keychain (e.g. 'security unlock-keychain' over SSH) and retry"}}
+	}
+	return []doctorFinding{{check, doctorOK, status, ""}}
+}
+
+// doctorClaudeConfig checks that Claude Code's own files parse and agree with
+// each other and with the active profile.
+func doctorClaudeConfig() []doctorFinding {
+	const check = "claude config"
+	var findings []doctorFinding
+
+	readDoc := func(path string) map[string]json.RawMessage {
+		data, err := readFile(path)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				findings = append(findings, doctorFinding{check, doctorFail, err.Error(), ""})
+			}
+			return nil
+		}
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(data, &doc); err != nil {
+			findings = append(findings, doctorFinding{check, doctorFail, fmt.Sprintf("%s is not valid JSON: %v", path, err), "restore it from a backup or delete it and log in again"})
+			return nil
+		}
+		return doc
+	}
+	readDoc(claudeJSONPath())
+	credsDoc := readDoc(credentialsPath())
+
+	var fileCreds, keychainCreds *OAuthCredentials
+	if raw := credsDoc["claudeAiOauth"]; raw != nil {
+		json.Unmarshal(raw, &fileCreds)
+	}
+	if raw := readKeychainCredentials(); raw != nil && defaults.keychainEnabled() {
+		json.Unmarshal(raw, &keychainCreds)
+	}
+	if fileCreds != nil && keychainCreds != nil && fileCreds.RefreshToken != keychainCreds.RefreshToken {
+		findings = append(findings, doctorFinding{check, doctorWarn, fmt.Sprintf("%s and the system keychain hold different credentials", credentialsPath()), "claude-switch use <name> writes both"})
+	}
+
+	report, _, _, err := activeDrift()
+	switch {
+	case err != nil:
+		findings = append(findings, doctorFinding{check, doctorFail, err.Error(), ""})
+	case report.State == driftNoActive:
+		findings = append(findings, doctorFinding{check, doctorOK, "no active profile to compare against", ""})
+	case report.State == driftAPIKey:
+		findings = append(findings, doctorFinding{check, doctorOK, "active profile isn't an OAuth login and doesn't use Claude's config files", ""})
+	case report.InSync:
+		findings = append(findings, doctorFinding{check, doctorOK, fmt.Sprintf("Claude's credentials and oauthAccount match '%s'", report.Active), ""})
+	default:
+		var ce *cliError
+		errors.As(driftError(report), &ce)
+		status := doctorWarn
+		if report.State == driftLoggedOut || report.State == driftAccount {
+			status = doctorFail
+		}
+		findings = append(findings, doctorFinding{check, status, ce.Message, ce.Hint})
+	}
+	return findings
+}
+
+// doctorTokenEndpoint checks that the OAuth token endpoint answers at all,
+// through the active profile's proxy, and compares clocks while at it.
+func doctorTokenEndpoint() []doctorFinding {
+	const check = "token endpoint"
+	if sandboxed() {
+		return []doctorFinding{{check, doctorSkip, "not contacted in sandbox mode", ""}}
+	}
+	var proxy *ProxySettings
+	if state := loadState(); state.ActiveProfile != nil {
+		if profile, err := loadProfile(*state.ActiveProfile); err == nil {
+			proxy = profile.Proxy
+		}
+	}
+	client := &http.Client{Transport: proxyTransport(proxy), Timeout: 15 * time.Second}
+	sent := time.Now()
+	resp, err := client.Head(tokenURL)
+	if err != nil {
+		fix := "check your network connection"
+		if !proxy.Empty() {
+			fix = "check the profile's proxy settings (claude-switch proxy <name>)"
+		} else if os.Getenv("HTTPS_PROXY") == "" && os.Getenv("https_proxy") == "" {
+			fix += ", or set a proxy with claude-switch proxy <name> --https <url>"
+		}
+		return []doctorFinding{{check, doctorFail, fmt.Sprintf("%s is unreachable: %v", tokenURL, err), fix}}
+	}
+	resp.Body.Close()
+	findings := []doctorFinding{{check, doctorOK, fmt.Sprintf("%s reachable in %s", tokenURL, time.Since(sent).Round(time.Millisecond)), ""}}
+
+	if serverTime, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		skew := serverTime.Sub(sent.Add(time.Since(sent) / 2))
+		if skew.Abs() >= significantSkew {
+			findings = append(findings, doctorFinding{"clock", doctorWarn, fmt.Sprintf("local clock is %s off the token server", skew.Round(time.Second)), "enable NTP time sync"})
+		}
+	}
+	return findings
+}
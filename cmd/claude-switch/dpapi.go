@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// --- DPAPI (Windows) ---
+
+// On Windows, profile files not sealed with a passphrase are protected
+// with DPAPI (CryptProtectData): only the same Windows user on the same
+// machine can read them back, without a passphrase. The profile name is
+// the extra entropy, so a file can't be swapped in under another name.
+// Plaintext files are protected the first time they are read. A
+// passphrase, once set with `encrypt enable`, takes over.
+
+// protectProfile wraps a profile file's plaintext with DPAPI.
+func protectProfile(name string, data []byte) ([]byte, error) {
+	ciphertext, err := dpapiProtect(data, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to protect profile '%s' with DPAPI: %w", name, err)
+	}
+	return json.MarshalIndent(&sealedData{Encrypted: sealedDPAPI, Ciphertext: ciphertext}, "", "  ")
+}
+
+func unprotectProfile(name string, sealed *sealedData) ([]byte, error) {
+	data, err := dpapiUnprotect(sealed.Ciphertext, name)
+	if err != nil {
+		return nil, &cliError{
+			Code:    errDecryptFailed,
+			Message: fmt.Sprintf("failed to decrypt profile '%s' with DPAPI", name),
+			Profile: name,
+			Hint:    "DPAPI-protected profiles can only be read by the Windows user that saved them, on the same machine",
+			Err:     err,
+		}
+	}
+	return data, nil
+}
+
+// storedForm is what a profile file holds for the plaintext data: sealed
+// with the passphrase, protected with DPAPI, or as is.
+func storedForm(name string, data []byte) ([]byte, error) {
+	switch {
+	case encryptionEnabled():
+		return sealProfile(name, data)
+	case defaults.dpapiEnabled():
+		return protectProfile(name, data)
+	}
+	return data, nil
+}
+
+// protectPlaintext rewrites a plaintext profile file with DPAPI once it
+// has been read, and drops the plaintext backups. Failures only matter to
+// the next read, so they are just logged.
+func protectPlaintext(name string, data []byte) {
+	protected, err := protectProfile(name, data)
+	if err == nil {
+		err = writeSecure(profilePath(name), protected)
+	}
+	if err == nil {
+		_, err = purgePlaintextBackups()
+	}
+	if err != nil {
+		debugf("dpapi: %v", err)
+		return
+	}
+	debugf("dpapi: protected '%s'", name)
+}
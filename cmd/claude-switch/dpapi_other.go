@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+const dpapiSupported = false
+
+var errNoDPAPI = errors.New("DPAPI is only available on Windows")
+
+func dpapiProtect([]byte, string) ([]byte, error) {
+	return nil, errNoDPAPI
+}
+
+func dpapiUnprotect([]byte, string) ([]byte, error) {
+	return nil, errNoDPAPI
+}
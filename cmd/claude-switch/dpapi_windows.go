@@ -0,0 +1,38 @@
+//go:build windows
+
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const dpapiSupported = true
+
+func dataBlob(b []byte) *windows.DataBlob {
+	if len(b) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(b)), Data: &b[0]}
+}
+
+// dpapiProtect encrypts data with CryptProtectData for the current user,
+// with label as the extra entropy.
+func dpapiProtect(data []byte, label string) ([]byte, error) {
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(dataBlob(data), nil, dataBlob([]byte(label)), 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
+
+func dpapiUnprotect(data []byte, label string) ([]byte, error) {
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(dataBlob(data), nil, dataBlob([]byte(label)), 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
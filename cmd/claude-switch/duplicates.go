@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --- Duplicate accounts ---
+
+// Adding or importing an account that is already saved under another name
+// would leave two profiles whose tokens go stale in turn, since only the
+// one in use gets refreshed. OAuth profiles are matched by account UUID,
+// API keys and long-lived tokens by a fingerprint of the secret.
+
+// keyFingerprint identifies a secret without revealing it: the first 8
+// bytes of its SHA-256, in hex.
+func keyFingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// identity is what two profiles of the same account have in common, or ""
+// if nothing is known.
+func (m *ProfileMeta) identity() string {
+	switch {
+	case m.AccountUUID != "":
+		return "account:" + m.AccountUUID
+	case m.KeyFingerprint != "":
+		return "key:" + m.KeyFingerprint
+	}
+	return ""
+}
+
+// findDuplicate returns another profile for the same account as profile,
+// or "".
+func findDuplicate(name string, profile *Profile) string {
+	meta := profileMeta(profile)
+	id := meta.identity()
+	if id == "" {
+		return ""
+	}
+	names, err := listProfiles()
+	if err != nil {
+		return ""
+	}
+	index := loadIndex()
+	for _, other := range names {
+		if other == name {
+			continue
+		}
+		if m, err := loadProfileMeta(&index, other); err == nil && m.identity() == id {
+			return other
+		}
+	}
+	return ""
+}
+
+// saveNewProfile saves an added or imported profile under name. If it's the
+// same account as another profile, the user is offered to update that one
+// instead, keeping its settings. It returns the name and profile saved.
+func saveNewProfile(name string, profile *Profile) (string, *Profile, error) {
+	if dup := findDuplicate(name, profile); dup != "" && updateDuplicate(name, dup, profile.Type == "oauth") {
+		existing, err := loadProfile(dup)
+		if err != nil {
+			return "", nil, err
+		}
+		existing.ReplaceCredentials(profile)
+		previous := existing.Store
+		if profile.Store != "" {
+			existing.Store = mergeStore(previous, profile.Store)
+		}
+		if err := saveProfile(dup, existing); err != nil {
+			return "", nil, err
+		}
+		if existing.Store != previous {
+			if err := deleteKeychainStore(previous); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: couldn't delete the old keychain item of '%s': %v\n", dup, err)
+			}
+		}
+		auditProfile = dup
+		recordAudit(dup, nil)
+		return dup, existing, nil
+	}
+	if err := saveProfile(name, profile); err != nil {
+		return "", nil, err
+	}
+	auditProfile = name
+	recordAudit(name, nil)
+	return name, profile, nil
+}
+
+// updateDuplicate warns that name duplicates dup and, on a terminal, asks
+// whether to update dup instead. Scripts get the warning and a new profile.
+func updateDuplicate(name, dup string, oauth bool) bool {
+	fmt.Fprintf(os.Stderr, "Warning: this is the same account as profile '%s'.\n", dup)
+	if !stdinIsTerminal() || jsonOutput() {
+		if oauth {
+			fmt.Fprintf(os.Stderr, "Saving it as '%s' anyway; only the profile in use gets refreshed, so the other's tokens will go stale.\n", name)
+		} else {
+			fmt.Fprintf(os.Stderr, "Saving it as '%s' anyway.\n", name)
+		}
+		return false
+	}
+	answer, err := readLine(fmt.Sprintf("Update '%s' instead of creating '%s'? [Y/n] ", dup, name))
+	if err != nil {
+		return false
+	}
+	a := strings.ToLower(answer)
+	return a == "" || a == "y" || a == "yes"
+}
+
+// reportSaved is reportProfileSaved for saveNewProfile's result.
+func reportSaved(action, name, saved string, profile *Profile) error {
+	if saved != name {
+		action = "Updated"
+	}
+	return reportProfileSaved(action, saved, profile)
+}
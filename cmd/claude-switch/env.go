@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var supportedShells = []string{"bash", "zsh", "sh", "fish", "nu", "powershell", "cmd"}
+
+func cmdEnv(args []string) error {
+	var name, shell string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--shell" || a == "-s":
+			if i+1 >= len(args) {
+				return usageError("%s requires a value (%s)", a, strings.Join(supportedShells, "|"))
+			}
+			i++
+			shell = args[i]
+		case strings.HasPrefix(a, "--shell="):
+			shell = strings.TrimPrefix(a, "--shell=")
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("env requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	if shell == "" {
+		shell = detectShell()
+	}
+
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		env := make(map[string]string, len(vars))
+		for _, v := range vars {
+			env[v.Key] = v.Value
+		}
+		return printJSON(env)
+	}
+
+	for _, v := range vars {
+		line, err := exportLine(shell, v.Key, v.Value)
+		if err != nil {
+			return err
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// detectShell guesses the caller's shell from $SHELL, defaulting to POSIX sh
+// (PowerShell on Windows, where $SHELL is only set by Unix-like shells).
+func detectShell() string {
+	base := strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe")
+	switch base {
+	case "bash", "zsh", "fish", "nu":
+		return base
+	case "pwsh", "powershell":
+		return "powershell"
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "sh"
+}
+
+// evalCommand is how to load a profile's env output into the given shell.
+func evalCommand(shell, name string) string {
+	switch shell {
+	case "fish":
+		return fmt.Sprintf("claude-switch env %s | source", name)
+	case "powershell", "pwsh":
+		return fmt.Sprintf("claude-switch env %s | Invoke-Expression", name)
+	case "nu", "cmd":
+		return fmt.Sprintf("claude-switch env %s --shell %s", name, shell)
+	}
+	return fmt.Sprintf(`eval "$(claude-switch env %s)"`, name)
+}
+
+// exportLine renders a single variable assignment in the syntax of the given
+// shell, quoted so that eval-ing it yields exactly val.
+func exportLine(shell, key, val string) (string, error) {
+	if !envKeyPattern.MatchString(key) {
+		return "", usageError("invalid variable name '%s'", key)
+	}
+	switch shell {
+	case "bash", "zsh", "sh":
+		return fmt.Sprintf("export %s=%s", key, quotePosix(val)), nil
+	case "fish":
+		return fmt.Sprintf("set -gx %s %s", key, quoteFish(val)), nil
+	case "nu":
+		return fmt.Sprintf("$env.%s = %s", key, quoteDouble(val)), nil
+	case "powershell", "pwsh":
+		return fmt.Sprintf("$env:%s = %s", key, quotePowerShell(val)), nil
+	case "cmd":
+		if strings.ContainsAny(val, "\r\n") {
+			return "", usageError("the value of %s contains a line break, which cmd can't set", key)
+		}
+		return fmt.Sprintf("set %s=%s", key, quoteCmd(val)), nil
+	}
+	return "", usageError("unsupported shell: '%s' (expected %s)", shell, strings.Join(supportedShells, "|"))
+}
+
+// unsetLine removes a variable in the syntax of the given shell.
+func unsetLine(shell, key string) string {
+	switch shell {
+	case "fish":
+		return "set -e " + key
+	case "nu":
+		return "hide-env -i " + key
+	case "powershell", "pwsh":
+		return "Remove-Item Env:" + key + " -ErrorAction SilentlyContinue"
+	case "cmd":
+		return fmt.Sprintf(`set "%s="`, key)
+	}
+	return "unset " + key
+}
+
+func quotePosix(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func quoteFish(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+func quoteDouble(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// cmdEscaper escapes a value for an unquoted set in a batch file: % is
+// doubled and cmd's other metacharacters are escaped with ^. Quoting with
+// "..." wouldn't do, since a " in the value ends the quotes. Batch files run
+// with delayed expansion (!var!) enabled are out of reach either way.
+var cmdEscaper = strings.NewReplacer(
+	"%", "%%",
+	"^", "^^",
+	`"`, `^"`,
+	"&", "^&",
+	"|", "^|",
+	"<", "^<",
+	">", "^>",
+	"(", "^(",
+	")", "^)",
+)
+
+func quoteCmd(s string) string {
+	return cmdEscaper.Replace(s)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExportLine(t *testing.T) {
+	tests := []struct {
+		shell, val, want string
+	}{
+		{"sh", "plain", `export K='plain'`},
+		{"bash", "it's", `export K='it'\''s'`},
+		{"zsh", "$HOME `id`", "export K='$HOME `id`'"},
+		{"fish", `a\b'c`, `set -gx K 'a\\b\'c'`},
+		{"nu", `a\b"c`, `$env.K = "a\\b\"c"`},
+		{"powershell", "it's $x", `$env:K = 'it''s $x'`},
+		{"pwsh", "v", `$env:K = 'v'`},
+		{"cmd", "plain", `set K=plain`},
+		{"cmd", "100%", `set K=100%%`},
+		{"cmd", `%PATH%`, `set K=%%PATH%%`},
+		{"cmd", `a"b`, `set K=a^"b`},
+		{"cmd", "a^b", "set K=a^^b"},
+		{"cmd", "a&b|c<d>e(f)", "set K=a^&b^|c^<d^>e^(f^)"},
+	}
+	for _, tt := range tests {
+		got, err := exportLine(tt.shell, "K", tt.val)
+		if err != nil {
+			t.Errorf("exportLine(%q, %q): %v", tt.shell, tt.val, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("exportLine(%q, %q) = %s, want %s", tt.shell, tt.val, got, tt.want)
+		}
+	}
+}
+
+func TestExportLineRejects(t *testing.T) {
+	tests := []struct {
+		shell, val string
+	}{
+		{"cmd", "a\nb"},
+		{"cmd", "a\r\nb"},
+		{"cmd", "a\rb"},
+		{"tcsh", "v"},
+	}
+	for _, tt := range tests {
+		_, err := exportLine(tt.shell, "K", tt.val)
+		var ce *cliError
+		if !errors.As(err, &ce) || ce.Code != errUsage {
+			t.Errorf("exportLine(%q, %q) = %v, want a usage error", tt.shell, tt.val, err)
+		}
+	}
+}
+
+func TestExportLineMultiline(t *testing.T) {
+	// Quoted shells carry a line break through unchanged.
+	for shell, want := range map[string]string{
+		"sh":         "export K='a\nb'",
+		"fish":       "set -gx K 'a\nb'",
+		"powershell": "$env:K = 'a\nb'",
+	} {
+		got, err := exportLine(shell, "K", "a\nb")
+		if err != nil || got != want {
+			t.Errorf("exportLine(%q) = %q, %v; want %q", shell, got, err, want)
+		}
+	}
+}
+
+func TestExportLineRejectsKeys(t *testing.T) {
+	for _, key := range []string{"", "1X", "X=1; echo PWNED; Y", "A B", "A-B", "$(id)", "K\n"} {
+		for _, shell := range supportedShells {
+			if _, err := exportLine(shell, key, "v"); err == nil {
+				t.Errorf("exportLine(%q, %q) accepted the name", shell, key)
+			}
+		}
+	}
+}
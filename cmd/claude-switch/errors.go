@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- Structured errors ---
+
+// Error codes reported in the --json error envelope. Wrappers branch on
+// these, so existing values must not change.
+const (
+	errGeneric         = "error"
+	errUsage           = "usage"
+	errProfileNotFound = "profile_not_found"
+	errProfileExists   = "profile_exists"
+	errInvalidName     = "invalid_name"
+	errNoCredentials   = "no_credentials"
+	errRefreshFailed   = "refresh_failed"
+	errReauthRequired  = "reauth_required"
+	errRateLimited     = "rate_limited"
+	errNetwork         = "network"
+	errClaudeFailed    = "claude_failed"
+	errChainExhausted  = "chain_exhausted"
+	errConfig          = "config"
+	errTokenRejected   = "token_rejected"
+	errDecryptFailed   = "decrypt_failed"
+	errDrift           = "drift"
+	errLocked          = "locked"
+	errGroupNotFound   = "group_not_found"
+	errOrgNotFound     = "org_not_found"
+	errAmbiguousName   = "ambiguous_name"
+	errTokenExpired    = "token_expired"
+	errClaudeRunning   = "claude_running"
+	errUnauthorized    = "unauthorized"
+)
+
+// Exit codes for the failures scripts most often branch on. Anything else
+// exits 1, and exec and run exit with their command's code.
+const (
+	exitError          = 1
+	exitUsage          = 2
+	exitProfileMissing = 3
+	exitInvalidName    = 4
+	exitRefreshFailed  = 5
+	exitReauthRequired = 6
+	exitNetwork        = 7
+	exitClaudeFailed   = 8
+)
+
+var exitCodes = map[string]int{
+	errUsage:           exitUsage,
+	errProfileNotFound: exitProfileMissing,
+	errInvalidName:     exitInvalidName,
+	errRefreshFailed:   exitRefreshFailed,
+	errReauthRequired:  exitReauthRequired,
+	errNetwork:         exitNetwork,
+	errClaudeFailed:    exitClaudeFailed,
+}
+
+// exitCode is the status to exit with after err.
+func exitCode(err error) int {
+	if code, ok := exitCodes[classifyError(err).Code]; ok {
+		return code
+	}
+	return exitError
+}
+
+type cliError struct {
+	Code    string
+	Message string
+	Profile string
+	Hint    string
+	Err     error
+}
+
+func (e *cliError) Error() string {
+	return e.Message
+}
+
+func (e *cliError) Unwrap() error {
+	return e.Err
+}
+
+func usageError(format string, args ...any) error {
+	return &cliError{Code: errUsage, Message: fmt.Sprintf(format, args...), Hint: "run 'claude-switch help' for usage"}
+}
+
+func notFoundError(name string) error {
+	return &cliError{
+		Code:    errProfileNotFound,
+		Message: fmt.Sprintf("profile '%s' not found", name),
+		Profile: name,
+		Hint:    "run 'claude-switch list' to see available profiles",
+	}
+}
+
+func existsError(name string) error {
+	return &cliError{
+		Code:    errProfileExists,
+		Message: fmt.Sprintf("profile '%s' already exists", name),
+		Profile: name,
+		Hint:    fmt.Sprintf("run 'claude-switch remove %s' first", name),
+	}
+}
+
+// classifyError maps any error onto the envelope fields, recognising
+// cliError, refresh failures and network errors.
+func classifyError(err error) *cliError {
+	var ce *cliError
+	if errors.As(err, &ce) {
+		return ce
+	}
+	var timedOut *claudeswitch.LockTimeoutError
+	if errors.As(err, &timedOut) {
+		return lockedError(timedOut)
+	}
+	var re *RefreshError
+	if errors.As(err, &re) {
+		switch re.Kind {
+		case refreshInvalidGrant:
+			return &cliError{Code: errReauthRequired, Message: err.Error(), Hint: "run 'claude-switch use <name>' to re-authenticate"}
+		case refreshRateLimited:
+			return &cliError{Code: errRateLimited, Message: err.Error(), Hint: "wait for the cooldown to pass before refreshing again"}
+		}
+		return &cliError{Code: errRefreshFailed, Message: err.Error()}
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return &cliError{Code: errNetwork, Message: err.Error(), Hint: "check your network connection and proxy settings"}
+	}
+	return &cliError{Code: errGeneric, Message: err.Error()}
+}
+
+func writeErrorJSON(w io.Writer, err error) {
+	ce := classifyError(err)
+	envelope := struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+			Profile string `json:"profile,omitempty"`
+			Hint    string `json:"hint,omitempty"`
+		} `json:"error"`
+	}{}
+	envelope.Error.Code = ce.Code
+	envelope.Error.Message = err.Error()
+	envelope.Error.Profile = ce.Profile
+	envelope.Error.Hint = ce.Hint
+	data, _ := json.Marshal(envelope)
+	fmt.Fprintln(w, string(data))
+}
+
+func writeErrorText(w io.Writer, err error) {
+	fmt.Fprintf(w, "error: %v\n", err)
+	if ce := classifyError(err); ce.Hint != "" && ce.Code != errUsage {
+		fmt.Fprintf(w, "hint: %s\n", ce.Hint)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --- exec-all: run a command once per profile ---
+
+// ExecResult is one profile's outcome in exec-all's JSON output.
+type ExecResult struct {
+	Profile  string `json:"profile"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+func cmdExecAll(args []string) error {
+	var group string
+	jobs := 1
+	i := 0
+	for ; i < len(args) && args[i] != "--"; i++ {
+		a := args[i]
+		switch {
+		case a == "--group" || a == "-g" || strings.HasPrefix(a, "--group="):
+			value, ok := strings.CutPrefix(a, "--group=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a group name", a)
+				}
+				i++
+				value = args[i]
+			}
+			group = value
+		case a == "--jobs" || a == "-j" || strings.HasPrefix(a, "--jobs=") || strings.HasPrefix(a, "-j="):
+			_, value, ok := strings.Cut(a, "=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a number", a)
+				}
+				i++
+				value = args[i]
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return usageError("invalid job count '%s'", value)
+			}
+			jobs = n
+		default:
+			return usageError("unexpected argument: %s (put the command after --)", a)
+		}
+	}
+	if i >= len(args)-1 {
+		return usageError("no command specified")
+	}
+	cmdArgs := args[i+1:]
+
+	var names []string
+	var err error
+	if group != "" {
+		names, err = groupMembers(group)
+	} else {
+		names, err = listProfiles()
+	}
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return &cliError{Code: errProfileNotFound, Message: "no profiles", Hint: "use 'claude-switch add <name>' to create one"}
+	}
+
+	// One profile at a time passes the output straight through; in
+	// parallel each line is prefixed with its profile instead. With --json
+	// the commands' output goes to stderr, leaving stdout for the results.
+	stdoutBase := io.Writer(os.Stdout)
+	if jsonOutput() {
+		stdoutBase = os.Stderr
+	}
+	results := make([]ExecResult, len(names))
+	var outMu sync.Mutex
+	run := func(i int) {
+		name := names[i]
+		stdout, stderr := stdoutBase, io.Writer(os.Stderr)
+		if jobs > 1 {
+			out, errOut := &prefixWriter{prefix: name + " | ", out: stdoutBase, mu: &outMu}, &prefixWriter{prefix: name + " | ", out: os.Stderr, mu: &outMu}
+			defer out.Flush()
+			defer errOut.Flush()
+			stdout, stderr = out, errOut
+		} else {
+			infof("==> %s <==\n", name)
+		}
+		results[i] = runForProfile(name, cmdArgs, stdout, stderr)
+	}
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i := range names {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			run(i)
+		})
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" || (r.ExitCode != nil && *r.ExitCode != 0) {
+			failed++
+		}
+	}
+	if jsonOutput() {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		infof("\n")
+		for _, r := range results {
+			switch {
+			case r.Error != "":
+				fmt.Fprintf(os.Stderr, "%s: failed: %s\n", r.Profile, r.Error)
+			case *r.ExitCode != 0:
+				fmt.Fprintf(os.Stderr, "%s: exit status %d\n", r.Profile, *r.ExitCode)
+			default:
+				infof("%s: ok\n", r.Profile)
+			}
+		}
+	}
+	if failed > 0 {
+		return &cliError{Code: errGeneric, Message: fmt.Sprintf("%d of %d profile(s) failed", failed, len(names))}
+	}
+	return nil
+}
+
+// runForProfile runs cmdArgs with name's credentials and without stdin,
+// which the runs can't share.
+func runForProfile(name string, cmdArgs []string, stdout, stderr io.Writer) ExecResult {
+	result := ExecResult{Profile: name}
+	fail := func(err error) ExecResult {
+		result.Error, result.Code = err.Error(), classifyError(err).Code
+		return result
+	}
+	vars, err := credentialEnvVars(name, false)
+	if err != nil {
+		return fail(err)
+	}
+	args := slices.Clone(cmdArgs)
+	if args[0] == "claude" {
+		if args[0], err = resolveClaude(name); err != nil {
+			return fail(err)
+		}
+	}
+	binary, err := exec.LookPath(args[0])
+	if err != nil {
+		return fail(fmt.Errorf("exec failed: %w", err))
+	}
+	cmd := exec.Command(binary, args[1:]...)
+	cmd.Env = mergeEnv(os.Environ(), vars)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	markUsed(name)
+	recordAudit(name, nil)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fail(fmt.Errorf("exec failed: %w", err))
+	}
+	code := cmd.ProcessState.ExitCode()
+	result.ExitCode = &code
+	return result
+}
+
+// prefixWriter writes whole lines to out, each starting with prefix, so
+// output from parallel runs doesn't interleave mid-line.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.mu.Lock()
+		_, err := fmt.Fprintf(w.out, "%s%s\n", w.prefix, w.buf[:i])
+		w.mu.Unlock()
+		w.buf = w.buf[i+1:]
+		if err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes a final line that had no newline.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.Write([]byte("\n"))
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// --- export <name> / import <name> --from-file ---
+
+const profileFileVersion = 1
+
+// profileFile is the format export writes and import --from-file reads.
+type profileFile struct {
+	Version  int      `json:"claude_switch_profile"`
+	Name     string   `json:"name"`
+	Redacted bool     `json:"redacted,omitempty"`
+	Profile  *Profile `json:"profile"`
+}
+
+func cmdExport(args []string) error {
+	var name string
+	redact := false
+	for _, a := range args {
+		switch {
+		case a == "--redact":
+			redact = true
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("export requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	file, err := exportProfile(name, redact)
+	if err != nil {
+		return err
+	}
+	return printJSON(file)
+}
+
+// exportProfile loads a profile into the export format. The file is
+// self-contained: secrets kept in 1Password or the keychain are included
+// and the reference dropped, so it can be imported anywhere.
+func exportProfile(name string, redact bool) (*profileFile, error) {
+	profile, err := loadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	profile.Store = ""
+	if redact {
+		if creds := profile.Credentials; creds != nil {
+			masked := *creds
+			masked.AccessToken = maskSecret(creds.AccessToken)
+			masked.RefreshToken = maskSecret(creds.RefreshToken)
+			profile.Credentials = &masked
+		}
+		profile.ApiKey = maskSecret(profile.ApiKey)
+		profile.OAuthToken = maskSecret(profile.OAuthToken)
+		profile.FallbackApiKey = maskSecret(profile.FallbackApiKey)
+		for key, value := range profile.Env {
+			profile.Env[key] = maskSecret(value)
+		}
+	}
+	return &profileFile{Version: profileFileVersion, Name: name, Redacted: redact, Profile: profile}, nil
+}
+
+// readProfileFile reads an exported profile from path, or stdin for "-".
+func readProfileFile(path string) (*Profile, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdinReader)
+	} else {
+		data, err = readFile(expandHome(path))
+	}
+	if path == "-" {
+		path = "stdin"
+	}
+	if err != nil {
+		return nil, &cliError{Code: errGeneric, Message: fmt.Sprintf("failed to read %s: %v", path, err), Err: err}
+	}
+	var file profileFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version == 0 || file.Profile == nil {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("%s is not an exported claude-switch profile", path), Hint: "create one with 'claude-switch export <name> > profile.json'"}
+	}
+	if file.Version > profileFileVersion {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("%s was exported by a newer claude-switch", path)}
+	}
+	if file.Redacted {
+		return nil, &cliError{Code: errNoCredentials, Message: fmt.Sprintf("%s was exported with --redact and holds no usable secrets", path)}
+	}
+
+	profile := file.Profile
+	profile.Store = ""
+	switch profile.Type {
+	case "oauth":
+		if profile.Credentials == nil || profile.Credentials.RefreshToken == "" {
+			return nil, &cliError{Code: errNoCredentials, Message: fmt.Sprintf("%s has no OAuth tokens", path)}
+		}
+	case "api_key":
+		if profile.ApiKey == "" {
+			return nil, &cliError{Code: errNoCredentials, Message: fmt.Sprintf("%s has no API key", path)}
+		}
+	case "oauth_token":
+		if profile.OAuthToken == "" {
+			return nil, &cliError{Code: errNoCredentials, Message: fmt.Sprintf("%s has no token", path)}
+		}
+	default:
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("%s has unknown profile type '%s'", path, profile.Type)}
+	}
+	if err := validateProfileEnv(profile); err != nil {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("%s: %v", path, err)}
+	}
+	return profile, nil
+}
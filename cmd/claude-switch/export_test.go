@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProfileFileEnv(t *testing.T) {
+	tests := []struct {
+		env string
+		ok  bool
+	}{
+		{`{"ANTHROPIC_BASE_URL": "https://gateway.example.com"}`, true},
+		{`{"X=1; echo PWNED; Y": "v"}`, false},
+		{`{"A B": "v"}`, false},
+		{`{"ANTHROPIC_API_KEY": "other"}`, false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "profile.json")
+		data := `{"claude_switch_profile": 1, "profile": {"type": "api_key", "api_key": "sk-ant-api01-x", "env": ` + tt.env + `}}`
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readProfileFile(path)
+		if (err == nil) != tt.ok {
+			t.Errorf("env %s: err = %v, want ok = %v", tt.env, err, tt.ok)
+		}
+	}
+}
+
+func TestExtraEnvVarsSkipsInvalid(t *testing.T) {
+	p := &Profile{Env: map[string]string{"GOOD": "1", "X=1; echo PWNED; Y": "2", "ANTHROPIC_API_KEY": "3"}}
+	vars := extraEnvVars(p)
+	if len(vars) != 1 || vars[0].Key != "GOOD" {
+		t.Errorf("extraEnvVars = %v, want only GOOD", vars)
+	}
+}
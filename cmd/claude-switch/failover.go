@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// --- exec --failover: re-run under the next profile on a usage limit ---
+
+// The command runs as a child rather than replacing this process, with its
+// output copied through and the tail of it kept. When it exits non-zero
+// after printing one of limitMarkers, it is started again under the next
+// profile of the group.
+//
+// Output going to a terminal is left attached so interactive programs keep
+// working, which means only stderr is checked there; in print mode
+// (`claude -p`, output piped or redirected) both streams are.
+
+// limitMarkers are matched case-insensitively against the end of the output.
+var limitMarkers = []string{
+	"limit reached",
+	"hit your limit",
+	"rate_limit_error",
+	"api error: 429",
+}
+
+// failoverTail is how much of each stream is kept for matching.
+const failoverTail = 16 << 10
+
+func execFailover(group string, cmdArgs []string) error {
+	members, err := groupMembers(group)
+	if err != nil {
+		return err
+	}
+	// Start from the active profile if it's in the group, then go round.
+	if state := loadState(); state.ActiveProfile != nil {
+		if i := slices.Index(members, *state.ActiveProfile); i > 0 {
+			members = slices.Concat(members[i:], members[:i])
+		}
+	}
+
+	// Piped input can only be read once, so keep it for every attempt.
+	var input []byte
+	if !stdinIsTerminal() {
+		if input, err = io.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	limited := 0
+	for i, name := range members {
+		vars, err := credentialEnvVars(name, false)
+		if err != nil {
+			infof("Skipping '%s': %v\n", name, err)
+			continue
+		}
+		args := slices.Clone(cmdArgs)
+		if args[0] == "claude" {
+			if args[0], err = resolveClaude(name); err != nil {
+				return err
+			}
+		}
+		infof("Running under profile '%s' from group '%s'\n", name, group)
+		markUsed(name)
+		recordAudit(name, nil)
+		code, hitLimit, err := runSupervised(args, append(vars, envVar{"CLAUDE_SWITCH_PROFILE", name}), input)
+		if err != nil {
+			return err
+		}
+		if code == 0 || !hitLimit {
+			if code != 0 {
+				os.Exit(code)
+			}
+			return nil
+		}
+		limited++
+		if i < len(members)-1 {
+			infof("'%s' hit a usage limit; retrying under the next profile.\n", name)
+		}
+	}
+	if limited == 0 {
+		return &cliError{
+			Code:    errChainExhausted,
+			Message: fmt.Sprintf("no profile in group '%s' has usable credentials", group),
+			Hint:    "run 'claude-switch list --group " + group + "' to check its profiles",
+		}
+	}
+	return &cliError{
+		Code:    errRateLimited,
+		Message: fmt.Sprintf("every usable profile in group '%s' hit a usage limit", group),
+		Hint:    "run 'claude-switch list --usage --group " + group + "' to see when the limits reset",
+	}
+}
+
+// runSupervised runs args with vars set and reports its exit code and
+// whether its output ended in a usage-limit error.
+func runSupervised(args []string, vars []envVar, input []byte) (int, bool, error) {
+	binary, err := exec.LookPath(args[0])
+	if err != nil {
+		return 0, false, fmt.Errorf("exec failed: %w", err)
+	}
+	cmd := exec.Command(binary, args[1:]...)
+	cmd.Env = mergeEnv(os.Environ(), vars)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	} else {
+		cmd.Stdin = os.Stdin
+	}
+	var outTail, errTail tailBuffer
+	cmd.Stdout = os.Stdout
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		cmd.Stdout = io.MultiWriter(os.Stdout, &outTail)
+	}
+	cmd.Stderr = io.MultiWriter(os.Stderr, &errTail)
+
+	// Ctrl-C is for the child, which shares the terminal; it decides
+	// whether to exit, and its exit status is passed on.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return 0, false, fmt.Errorf("exec failed: %w", err)
+	}
+	recordLaunch(cmd.Process.Pid, args)
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return 0, false, fmt.Errorf("exec failed: %w", err)
+	}
+	code := cmd.ProcessState.ExitCode()
+	if code < 0 {
+		// Killed by a signal, not a limit.
+		return 1, false, nil
+	}
+	return code, outTail.hasLimit() || errTail.hasLimit(), nil
+}
+
+// tailBuffer keeps the last failoverTail bytes written to it.
+type tailBuffer struct {
+	data []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.data = append(t.data, p...)
+	if over := len(t.data) - failoverTail; over > 0 {
+		t.data = t.data[over:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) hasLimit() bool {
+	text := strings.ToLower(string(t.data))
+	for _, marker := range limitMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// --- Garbage collection of orphaned artifacts ---
+
+type gcItem struct {
+	What  string
+	Bytes int64
+	apply func() error
+}
+
+// gcCollectors each find one kind of leftover. They only look; removal
+// happens through the returned items so --dry-run shares the same code.
+var gcCollectors = []func() ([]gcItem, error){
+	gcIndexEntries,
+	gcState,
+	gcWatchPID,
+	gcBackups,
+	gcTrash,
+	gcIsolated,
+}
+
+func cmdGC(args []string) error {
+	dryRun := false
+	for _, a := range args {
+		switch a {
+		case "--dry-run", "-n":
+			dryRun = true
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+
+	var items []gcItem
+	for _, collect := range gcCollectors {
+		found, err := collect()
+		if err != nil {
+			return err
+		}
+		items = append(items, found...)
+	}
+	if len(items) == 0 && !jsonOutput() {
+		infof("Nothing to clean up.\n")
+		return nil
+	}
+
+	type gcResult struct {
+		What  string `json:"what"`
+		Bytes int64  `json:"bytes"`
+	}
+	results := []gcResult{}
+	var total int64
+	for _, item := range items {
+		if !dryRun {
+			if err := item.apply(); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", item.What, err)
+			}
+		}
+		total += item.Bytes
+		results = append(results, gcResult{item.What, item.Bytes})
+		if jsonOutput() {
+			continue
+		}
+		if dryRun {
+			infof("Would remove %s (%s)\n", item.What, formatBytes(item.Bytes))
+		} else {
+			infof("Removed %s (%s)\n", item.What, formatBytes(item.Bytes))
+		}
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"dry_run": dryRun, "items": results, "reclaimed_bytes": total})
+	}
+	if dryRun {
+		infof("%d item(s), %s reclaimable. Re-run without --dry-run to remove them.\n", len(items), formatBytes(total))
+	} else {
+		infof("Removed %d item(s), reclaimed %s.\n", len(items), formatBytes(total))
+	}
+	return nil
+}
+
+// gcIndexEntries finds metadata index entries whose profile is gone from
+// storage, e.g. after a profile file was deleted by hand.
+func gcIndexEntries() ([]gcItem, error) {
+	index := loadIndex()
+	var names []string
+	for name := range index.Profiles {
+		if !profileExists(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var items []gcItem
+	for _, name := range names {
+		entry, _ := json.Marshal(index.Profiles[name])
+		items = append(items, gcItem{
+			What:  fmt.Sprintf("index entry for missing profile '%s'", name),
+			Bytes: int64(len(entry)),
+			apply: func() error { return updateIndex(name, nil) },
+		})
+	}
+	return items, nil
+}
+
+// gcState finds state.json fields that no longer mean anything: an active
+// profile, group members or timestamps of deleted profiles and a refresh
+// cooldown that has passed.
+func gcState() ([]gcItem, error) {
+	state := loadState()
+	var items []gcItem
+	if state.ActiveProfile != nil {
+		name := *state.ActiveProfile
+		if !profileExists(name) {
+			items = append(items, gcItem{
+				What:  fmt.Sprintf("active-profile marker for missing profile '%s'", name),
+				Bytes: int64(len(name)),
+				apply: func() error {
+					return updateState(func(state *State) { state.ActiveProfile = nil })
+				},
+			})
+		}
+	}
+	groups := make([]string, 0, len(state.Groups))
+	for group := range state.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		for _, name := range state.Groups[group] {
+			if profileExists(name) {
+				continue
+			}
+			items = append(items, gcItem{
+				What:  fmt.Sprintf("group '%s' member for missing profile '%s'", group, name),
+				Bytes: int64(len(name)),
+				apply: func() error {
+					return updateState(func(state *State) { dropFromGroups(state, name) })
+				},
+			})
+		}
+	}
+	items = append(items, gcTimestamps(state.PoolLastUsed, "pool timestamp", func(s *State) map[string]uint64 { return s.PoolLastUsed })...)
+	items = append(items, gcTimestamps(state.LastUsed, "last-used timestamp", func(s *State) map[string]uint64 { return s.LastUsed })...)
+	if state.RefreshCooldownUntil != 0 && state.RefreshCooldownUntil <= nowMs() {
+		items = append(items, gcItem{
+			What:  "expired refresh cooldown",
+			Bytes: 8,
+			apply: func() error {
+				return updateState(func(state *State) { state.RefreshCooldownUntil = 0 })
+			},
+		})
+	}
+	return items, nil
+}
+
+// gcTimestamps finds entries of a per-profile timestamp map, which field
+// returns from the state, whose profile is gone.
+func gcTimestamps(stamps map[string]uint64, what string, field func(*State) map[string]uint64) []gcItem {
+	var missing []string
+	for name := range stamps {
+		if !profileExists(name) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	var items []gcItem
+	for _, name := range missing {
+		items = append(items, gcItem{
+			What:  fmt.Sprintf("%s for missing profile '%s'", what, name),
+			Bytes: 8,
+			apply: func() error {
+				return updateState(func(state *State) { delete(field(state), name) })
+			},
+		})
+	}
+	return items
+}
+
+// gcWatchPID finds the pid file of a watch that was killed without
+// cleaning up after itself.
+func gcWatchPID() ([]gcItem, error) {
+	if !watchPIDStale() {
+		return nil, nil
+	}
+	info, err := os.Stat(watchPIDPath())
+	if err != nil {
+		return nil, nil
+	}
+	return []gcItem{{
+		What:  "stale watch pid file",
+		Bytes: info.Size(),
+		apply: func() error { return os.Remove(watchPIDPath()) },
+	}}, nil
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
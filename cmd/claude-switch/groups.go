@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// --- Profile groups ---
+
+// Groups are named sets of profiles, kept in state.json. list, refresh and
+// exec take --group to work on one; exec uses its first usable member, in
+// name order, the way it walks a chain.
+
+func cmdGroup(args []string) error {
+	action := "list"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	switch action {
+	case "list":
+		if len(args) > 1 {
+			return usageError("unexpected argument: %s", args[1])
+		}
+		return listGroups(args)
+	case "add":
+		if len(args) < 2 {
+			return usageError("group add requires a group name and at least one profile")
+		}
+		return addToGroup(args[0], args[1:])
+	case "remove", "rm":
+		if len(args) < 1 {
+			return usageError("group remove requires a group name")
+		}
+		return removeFromGroup(args[0], args[1:])
+	}
+	return usageError("unknown group action '%s' (expected add, remove or list)", action)
+}
+
+func listGroups(args []string) error {
+	groups := loadState().Groups
+	if len(args) == 1 {
+		members, err := groupMembers(args[0])
+		if err != nil {
+			return err
+		}
+		groups = map[string][]string{args[0]: members}
+	}
+	if jsonOutput() {
+		if groups == nil {
+			groups = map[string][]string{}
+		}
+		return printJSON(groups)
+	}
+	if len(groups) == 0 {
+		infof("No groups. Use 'claude-switch group add <group> <profile>...' to create one.\n")
+		return nil
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, strings.Join(groups[name], ", "))
+	}
+	return nil
+}
+
+func addToGroup(group string, profiles []string) error {
+	if err := validateGroupName(group); err != nil {
+		return err
+	}
+	for _, name := range profiles {
+		if !profileExists(name) {
+			return notFoundError(name)
+		}
+	}
+	var members []string
+	if err := updateState(func(state *State) {
+		for _, name := range profiles {
+			joinGroup(state, group, name)
+		}
+		members = state.Groups[group]
+	}); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"group": group, "profiles": members})
+	}
+	infof("Group '%s': %s\n", group, strings.Join(members, ", "))
+	return nil
+}
+
+// removeFromGroup takes profiles out of a group, or deletes the group when
+// none are given. A group left empty is deleted too.
+func removeFromGroup(group string, profiles []string) error {
+	if _, err := groupMembers(group); err != nil {
+		return err
+	}
+	var members []string
+	if err := updateState(func(state *State) {
+		if len(profiles) > 0 {
+			members = slices.DeleteFunc(state.Groups[group], func(name string) bool {
+				return slices.Contains(profiles, name)
+			})
+		}
+		if len(members) == 0 {
+			delete(state.Groups, group)
+		} else {
+			state.Groups[group] = members
+		}
+	}); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"group": group, "profiles": members, "deleted": len(members) == 0})
+	}
+	if len(members) == 0 {
+		infof("Deleted group '%s'\n", group)
+	} else {
+		infof("Group '%s': %s\n", group, strings.Join(members, ", "))
+	}
+	return nil
+}
+
+// groupMembers returns a group's profiles, in name order.
+func groupMembers(group string) ([]string, error) {
+	members := loadState().Groups[group]
+	if len(members) == 0 {
+		return nil, &cliError{
+			Code:    errGroupNotFound,
+			Message: fmt.Sprintf("group '%s' not found", group),
+			Hint:    "run 'claude-switch group list' to see the groups",
+		}
+	}
+	return members, nil
+}
+
+// profileGroups returns the groups a profile belongs to.
+func profileGroups(state State, name string) []string {
+	var groups []string
+	for group, members := range state.Groups {
+		if slices.Contains(members, name) {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// joinGroup adds a profile to a group, keeping the members sorted.
+func joinGroup(state *State, group, name string) {
+	if state.Groups == nil {
+		state.Groups = make(map[string][]string)
+	}
+	members := state.Groups[group]
+	if !slices.Contains(members, name) {
+		members = append(members, name)
+		sort.Strings(members)
+		state.Groups[group] = members
+	}
+}
+
+// dropFromGroups removes a profile from every group, deleting groups it
+// leaves empty.
+func dropFromGroups(state *State, name string) {
+	for group, members := range state.Groups {
+		members = slices.DeleteFunc(members, func(m string) bool { return m == name })
+		if len(members) == 0 {
+			delete(state.Groups, group)
+		} else {
+			state.Groups[group] = members
+		}
+	}
+}
+
+func validateGroupName(group string) error {
+	if group == "" || strings.HasPrefix(group, "-") || strings.ContainsAny(group, " \t,") {
+		return &cliError{
+			Code:    errInvalidName,
+			Message: fmt.Sprintf("invalid group name: '%s'", group),
+			Hint:    "group names can't start with '-' or contain spaces or commas",
+		}
+	}
+	return nil
+}
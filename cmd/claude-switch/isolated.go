@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// --- Isolated config directories (exec --isolated) ---
+
+// `exec --isolated` points CLAUDE_CONFIG_DIR at a directory of the
+// profile's own, so sessions under different profiles keep separate
+// settings, history, projects and login state instead of sharing
+// ~/.claude. The credential is still injected through the environment,
+// so nothing secret is written there. A new directory starts with the
+// profile's own settings files, or a copy of the user's; after that it is
+// Claude's to manage.
+
+func isolatedRoot() string {
+	return filepath.Join(configDir(), "isolated")
+}
+
+func isolatedDir(name string) string {
+	return filepath.Join(isolatedRoot(), name)
+}
+
+func isolatedLock(name string) string {
+	return "isolated-" + name
+}
+
+// prepareIsolated creates or updates the isolated directory of a profile
+// and returns the variable pointing Claude at it.
+func prepareIsolated(name string) (envVar, error) {
+	dir := isolatedDir(name)
+	store, err := storage()
+	if err != nil {
+		return envVar{}, err
+	}
+	// Only the account is needed, so external secrets aren't fetched again.
+	var profile *Profile
+	if err := withLock(profileLock(name), func() error {
+		profile, err = store.Load(name)
+		return err
+	}); err != nil {
+		return envVar{}, err
+	}
+	err = withLock(isolatedLock(name), func() error {
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			if err := seedIsolated(dir, profile.Settings); err != nil {
+				return err
+			}
+			debugf("isolated: created %s", dir)
+		}
+		return writeIsolatedConfig(dir, profile)
+	})
+	if err != nil {
+		return envVar{}, fmt.Errorf("failed to prepare the isolated config directory of '%s': %w", name, err)
+	}
+	return envVar{"CLAUDE_CONFIG_DIR", dir}, nil
+}
+
+// seedIsolated creates dir with the profile's own settings files, or
+// copies of the user's. It is built next to dir and renamed, so an
+// interrupted run leaves nothing half-seeded behind.
+func seedIsolated(dir string, settings *ClaudeSettings) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".seed-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if settings != nil {
+		for file, content := range settings.Files {
+			if err := writeSecure(filepath.Join(tmp, file), []byte(content)); err != nil {
+				return err
+			}
+		}
+		return os.Rename(tmp, dir)
+	}
+	for _, file := range claudeSettingsFiles {
+		data, err := readFile(filepath.Join(claudeConfigDir(), file))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeSecure(filepath.Join(tmp, file), data); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, dir)
+}
+
+// writeIsolatedConfig keeps the directory's .claude.json showing the
+// profile's account and MCP servers, and marks onboarding as done so
+// Claude doesn't start with its first-run screens.
+func writeIsolatedConfig(dir string, profile *Profile) error {
+	path := filepath.Join(dir, ".claude.json")
+	doc := readJSONDoc(path)
+	if doc == nil {
+		doc = make(map[string]json.RawMessage)
+	}
+	changed := false
+	if _, ok := doc["hasCompletedOnboarding"]; !ok {
+		doc["hasCompletedOnboarding"] = json.RawMessage("true")
+		changed = true
+	}
+	for key, value := range map[string]json.RawMessage{"oauthAccount": profile.Account, "mcpServers": profile.MCPServers} {
+		if value != nil && !jsonEqual(doc[key], value) {
+			doc[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSecure(path, out)
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	ax, _ := json.Marshal(x)
+	by, _ := json.Marshal(y)
+	return string(ax) == string(by)
+}
+
+// renameIsolated moves a profile's isolated directory along with it.
+func renameIsolated(from, to string) error {
+	err := os.Rename(isolatedDir(from), isolatedDir(to))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// gcIsolated finds isolated directories of profiles that are gone, and not
+// in the trash either.
+func gcIsolated() ([]gcItem, error) {
+	entries, err := os.ReadDir(isolatedRoot())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	trash, err := loadTrash()
+	if err != nil {
+		return nil, err
+	}
+	var items []gcItem
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || profileExists(name) || slices.ContainsFunc(trash, func(t trashEntry) bool { return t.Name == name }) {
+			continue
+		}
+		path := filepath.Join(isolatedRoot(), name)
+		what := fmt.Sprintf("isolated config directory of '%s'", name)
+		if validateProfileName(name) != nil {
+			what = fmt.Sprintf("leftover directory %s", path)
+		}
+		items = append(items, gcItem{
+			What:  what,
+			Bytes: dirSize(path),
+			apply: func() error { return os.RemoveAll(path) },
+		})
+	}
+	return items, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
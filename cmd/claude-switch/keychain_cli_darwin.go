@@ -0,0 +1,164 @@
+//go:build darwin && !cgo
+
+package main
+
+// Builds without cgo, such as those cross-compiled from another OS, reach
+// the keychain through the security tool. The secrets pass through its
+// arguments, and the items it creates trust it rather than claude-switch.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func readKeychainCredentials() json.RawMessage {
+	if sandboxed() {
+		return nil
+	}
+	account := os.Getenv("USER")
+	if account == "" {
+		return nil
+	}
+	out, err := exec.Command("security", "find-generic-password",
+		"-s", "Claude Code-credentials", "-a", account, "-w").Output()
+	debugKeychain("security find-generic-password -s 'Claude Code-credentials'", err)
+	if err != nil {
+		return nil
+	}
+	var doc map[string]json.RawMessage
+	if json.Unmarshal([]byte(strings.TrimSpace(string(out))), &doc) != nil {
+		return nil
+	}
+	if raw, ok := doc["claudeAiOauth"]; ok {
+		return raw
+	}
+	return nil
+}
+
+func writeKeychainCredentials(creds *OAuthCredentials) error {
+	if sandboxed() {
+		return nil
+	}
+	account := os.Getenv("USER")
+	if account == "" {
+		return nil
+	}
+	credsJSON, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	doc := map[string]json.RawMessage{"claudeAiOauth": credsJSON}
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	err = exec.Command("security", "add-generic-password",
+		"-U", "-s", "Claude Code-credentials", "-a", account, "-w", string(docJSON)).Run()
+	debugKeychain("security add-generic-password -s 'Claude Code-credentials'", err)
+	return err
+}
+
+// deleteKeychainCredentials removes Claude Code's item; a missing item is
+// not an error.
+func deleteKeychainCredentials() error {
+	if sandboxed() {
+		return nil
+	}
+	account := os.Getenv("USER")
+	if account == "" {
+		return nil
+	}
+	err := exec.Command("security", "delete-generic-password",
+		"-s", "Claude Code-credentials", "-a", account).Run()
+	debugKeychain("security delete-generic-password -s 'Claude Code-credentials'", err)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return nil
+	}
+	return err
+}
+
+// readKeychainSecret reads a profile's secrets from the claude-switch item
+// for it in the login keychain.
+func readKeychainSecret(item string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", profileKeychainService, "-a", item, "-w")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	debugKeychain("security find-generic-password -s '"+profileKeychainService+"' -a '"+item+"'", err)
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 44:
+		return nil, errKeychainItemNotFound
+	case err != nil:
+		return nil, securityError(err, &stderr)
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func writeKeychainSecret(item string, data []byte) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "add-generic-password", "-U",
+		"-s", profileKeychainService, "-a", item, "-l", profileKeychainService+": "+item, "-w", string(data))
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	debugKeychain("security add-generic-password -s '"+profileKeychainService+"' -a '"+item+"'", err)
+	if err != nil {
+		return securityError(err, &stderr)
+	}
+	return nil
+}
+
+// deleteKeychainSecret removes a profile's item; a missing item is not an
+// error.
+func deleteKeychainSecret(item string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "delete-generic-password", "-s", profileKeychainService, "-a", item)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	debugKeychain("security delete-generic-password -s '"+profileKeychainService+"' -a '"+item+"'", err)
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 44:
+		return nil
+	case err != nil:
+		return securityError(err, &stderr)
+	}
+	return nil
+}
+
+// securityError prefers what security printed over its exit status.
+func securityError(err error, stderr *bytes.Buffer) error {
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
+
+// keychainStatus reports whether the login keychain can be queried for
+// Claude Code's item, for doctor.
+func keychainStatus() (string, error) {
+	account := os.Getenv("USER")
+	if account == "" {
+		return "", errors.New("$USER is not set, so the keychain item can't be looked up")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", "Claude Code-credentials", "-a", account)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "Claude Code item found in the login keychain", nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 44:
+		return "no Claude Code item in the login keychain", nil
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return "", errors.New(msg)
+	}
+	return "", err
+}
@@ -0,0 +1,344 @@
+//go:build darwin && cgo
+
+package main
+
+/*
+#cgo CFLAGS: -Wno-deprecated-declarations
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+
+#include <stdlib.h>
+#include <string.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+
+static CFStringRef kc_string(const char *s) {
+	return CFStringCreateWithCString(NULL, s, kCFStringEncodingUTF8);
+}
+
+// kc_query matches the generic password of service and account.
+static CFMutableDictionaryRef kc_query(const char *service, const char *account) {
+	CFMutableDictionaryRef q = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFStringRef s = kc_string(service), a = kc_string(account);
+	CFDictionarySetValue(q, kSecClass, kSecClassGenericPassword);
+	CFDictionarySetValue(q, kSecAttrService, s);
+	CFDictionarySetValue(q, kSecAttrAccount, a);
+	CFRelease(s);
+	CFRelease(a);
+	return q;
+}
+
+// kc_read copies the item's data to *out, which the caller frees.
+static OSStatus kc_read(const char *service, const char *account, void **out, size_t *len) {
+	CFMutableDictionaryRef q = kc_query(service, account);
+	CFDictionarySetValue(q, kSecReturnData, kCFBooleanTrue);
+	CFDictionarySetValue(q, kSecMatchLimit, kSecMatchLimitOne);
+	CFTypeRef result = NULL;
+	OSStatus status = SecItemCopyMatching(q, &result);
+	CFRelease(q);
+	if (status != errSecSuccess) {
+		return status;
+	}
+	*len = CFDataGetLength((CFDataRef)result);
+	*out = malloc(*len > 0 ? *len : 1);
+	memcpy(*out, CFDataGetBytePtr((CFDataRef)result), *len);
+	CFRelease(result);
+	return errSecSuccess;
+}
+
+// kc_exists looks the item up without reading its data, which doesn't
+// need the item's permission.
+static OSStatus kc_exists(const char *service, const char *account) {
+	CFMutableDictionaryRef q = kc_query(service, account);
+	CFDictionarySetValue(q, kSecMatchLimit, kSecMatchLimitOne);
+	OSStatus status = SecItemCopyMatching(q, NULL);
+	CFRelease(q);
+	return status;
+}
+
+// kc_access trusts the calling program and the applications at paths that
+// exist. Paths that can't be trusted are skipped.
+static OSStatus kc_access(CFStringRef label, const char **trusted, int ntrusted, SecAccessRef *access) {
+	CFMutableArrayRef apps = CFArrayCreateMutable(NULL, 0, &kCFTypeArrayCallBacks);
+	SecTrustedApplicationRef app = NULL;
+	OSStatus status = SecTrustedApplicationCreateFromPath(NULL, &app);
+	if (status != errSecSuccess) {
+		CFRelease(apps);
+		return status;
+	}
+	CFArrayAppendValue(apps, app);
+	CFRelease(app);
+	for (int i = 0; i < ntrusted; i++) {
+		if (SecTrustedApplicationCreateFromPath(trusted[i], &app) == errSecSuccess) {
+			CFArrayAppendValue(apps, app);
+			CFRelease(app);
+		}
+	}
+	status = SecAccessCreate(label, apps, access);
+	CFRelease(apps);
+	return status;
+}
+
+// kc_write replaces the data of an existing item, keeping its access list,
+// or adds the item with one from kc_access.
+static OSStatus kc_write(const char *service, const char *account, const char *label,
+		const void *data, size_t len, const char **trusted, int ntrusted) {
+	CFDataRef value = CFDataCreate(NULL, data, len);
+	CFMutableDictionaryRef q = kc_query(service, account);
+	CFMutableDictionaryRef update = CFDictionaryCreateMutable(NULL, 0, &kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFDictionarySetValue(update, kSecValueData, value);
+	OSStatus status = SecItemUpdate(q, update);
+	CFRelease(update);
+	if (status == errSecItemNotFound) {
+		CFStringRef l = kc_string(label);
+		SecAccessRef access = NULL;
+		status = kc_access(l, trusted, ntrusted, &access);
+		if (status == errSecSuccess) {
+			CFDictionarySetValue(q, kSecAttrLabel, l);
+			CFDictionarySetValue(q, kSecValueData, value);
+			CFDictionarySetValue(q, kSecAttrAccess, access);
+			status = SecItemAdd(q, NULL);
+			CFRelease(access);
+		}
+		CFRelease(l);
+	}
+	CFRelease(q);
+	CFRelease(value);
+	return status;
+}
+
+static OSStatus kc_delete(const char *service, const char *account) {
+	CFMutableDictionaryRef q = kc_query(service, account);
+	OSStatus status = SecItemDelete(q);
+	CFRelease(q);
+	return status;
+}
+
+// kc_message describes status, or returns NULL. The caller frees it.
+static char *kc_message(OSStatus status) {
+	CFStringRef msg = SecCopyErrorMessageString(status, NULL);
+	if (msg == NULL) {
+		return NULL;
+	}
+	CFIndex size = CFStringGetMaximumSizeForEncoding(CFStringGetLength(msg), kCFStringEncodingUTF8) + 1;
+	char *buf = malloc(size);
+	if (!CFStringGetCString(msg, buf, size, kCFStringEncodingUTF8)) {
+		free(buf);
+		buf = NULL;
+	}
+	CFRelease(msg);
+	return buf;
+}
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"unsafe"
+)
+
+// The keychain is used through the Security framework, so secrets never
+// show up in another process's arguments, and failures come with the
+// framework's own message. Items claude-switch creates get an access list:
+// claude-switch may read them without asking, and for Claude Code's item
+// also the claude binary and the security tool, which Claude Code reads it
+// with. Anything else gets the macOS prompt. Items that already exist keep
+// their access list, so the first read of one Claude Code created prompts
+// once; "Always Allow" lets claude-switch in from then on.
+
+const claudeKeychainService = "Claude Code-credentials"
+
+const (
+	errSecItemNotFound          = -25300
+	errSecInteractionNotAllowed = -25308
+)
+
+// keychainStatusError is a Security framework call that failed.
+type keychainStatusError struct {
+	op     string
+	status C.OSStatus
+}
+
+func (e *keychainStatusError) Error() string {
+	msg := fmt.Sprintf("OSStatus %d", int(e.status))
+	if text := C.kc_message(e.status); text != nil {
+		msg = C.GoString(text) + " (" + msg + ")"
+		C.free(unsafe.Pointer(text))
+	}
+	if e.status == errSecInteractionNotAllowed {
+		msg += "; the keychain is locked and can't prompt from here, so run 'security unlock-keychain' first"
+	}
+	return e.op + ": " + msg
+}
+
+func keychainCall(op, service, account string, status C.OSStatus) error {
+	var err error
+	if status != 0 {
+		err = &keychainStatusError{op, status}
+	}
+	debugKeychain(fmt.Sprintf("%s '%s' account '%s'", op, service, account), err)
+	return err
+}
+
+func isItemNotFound(err error) bool {
+	var se *keychainStatusError
+	return errors.As(err, &se) && se.status == errSecItemNotFound
+}
+
+func keychainRead(service, account string) ([]byte, error) {
+	cService, cAccount := C.CString(service), C.CString(account)
+	defer C.free(unsafe.Pointer(cService))
+	defer C.free(unsafe.Pointer(cAccount))
+	var out unsafe.Pointer
+	var n C.size_t
+	if err := keychainCall("SecItemCopyMatching", service, account, C.kc_read(cService, cAccount, &out, &n)); err != nil {
+		return nil, err
+	}
+	defer C.free(out)
+	return C.GoBytes(out, C.int(n)), nil
+}
+
+// keychainWrite stores data, creating the item trusting claude-switch and
+// the programs at trusted.
+func keychainWrite(service, account, label string, data []byte, trusted []string) error {
+	cService, cAccount, cLabel := C.CString(service), C.CString(account), C.CString(label)
+	defer C.free(unsafe.Pointer(cService))
+	defer C.free(unsafe.Pointer(cAccount))
+	defer C.free(unsafe.Pointer(cLabel))
+	cTrusted := make([]*C.char, len(trusted))
+	for i, path := range trusted {
+		cTrusted[i] = C.CString(path)
+		defer C.free(unsafe.Pointer(cTrusted[i]))
+	}
+	var cData unsafe.Pointer
+	if len(data) > 0 {
+		cData = C.CBytes(data)
+		defer C.free(cData)
+	}
+	var trustedPtr **C.char
+	if len(cTrusted) > 0 {
+		trustedPtr = &cTrusted[0]
+	}
+	status := C.kc_write(cService, cAccount, cLabel, cData, C.size_t(len(data)), trustedPtr, C.int(len(cTrusted)))
+	return keychainCall("SecItemUpdate/SecItemAdd", service, account, status)
+}
+
+// keychainDelete removes an item; a missing item is not an error.
+func keychainDelete(service, account string) error {
+	cService, cAccount := C.CString(service), C.CString(account)
+	defer C.free(unsafe.Pointer(cService))
+	defer C.free(unsafe.Pointer(cAccount))
+	err := keychainCall("SecItemDelete", service, account, C.kc_delete(cService, cAccount))
+	if isItemNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// claudeTrustedApps are the programs besides claude-switch that may read
+// Claude Code's item without a prompt.
+func claudeTrustedApps() []string {
+	apps := []string{"/usr/bin/security"}
+	if path, err := exec.LookPath("claude"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			apps = append(apps, resolved)
+		}
+	}
+	return apps
+}
+
+func readKeychainCredentials() json.RawMessage {
+	if sandboxed() {
+		return nil
+	}
+	account := os.Getenv("USER")
+	if account == "" {
+		return nil
+	}
+	data, err := keychainRead(claudeKeychainService, account)
+	if err != nil {
+		return nil
+	}
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(data, &doc) != nil {
+		return nil
+	}
+	return doc["claudeAiOauth"]
+}
+
+func writeKeychainCredentials(creds *OAuthCredentials) error {
+	if sandboxed() {
+		return nil
+	}
+	account := os.Getenv("USER")
+	if account == "" {
+		return nil
+	}
+	credsJSON, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	doc := map[string]json.RawMessage{"claudeAiOauth": credsJSON}
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return keychainWrite(claudeKeychainService, account, claudeKeychainService, docJSON, claudeTrustedApps())
+}
+
+// deleteKeychainCredentials removes Claude Code's item; a missing item is
+// not an error.
+func deleteKeychainCredentials() error {
+	if sandboxed() {
+		return nil
+	}
+	account := os.Getenv("USER")
+	if account == "" {
+		return nil
+	}
+	return keychainDelete(claudeKeychainService, account)
+}
+
+// readKeychainSecret reads a profile's secrets from the claude-switch item
+// for it in the login keychain.
+func readKeychainSecret(item string) ([]byte, error) {
+	data, err := keychainRead(profileKeychainService, item)
+	if isItemNotFound(err) {
+		return nil, errKeychainItemNotFound
+	}
+	return data, err
+}
+
+func writeKeychainSecret(item string, data []byte) error {
+	return keychainWrite(profileKeychainService, item, profileKeychainService+": "+item, data, nil)
+}
+
+// deleteKeychainSecret removes a profile's item; a missing item is not an
+// error.
+func deleteKeychainSecret(item string) error {
+	return keychainDelete(profileKeychainService, item)
+}
+
+// keychainStatus reports whether the login keychain can be queried for
+// Claude Code's item, for doctor.
+func keychainStatus() (string, error) {
+	account := os.Getenv("USER")
+	if account == "" {
+		return "", errors.New("$USER is not set, so the keychain item can't be looked up")
+	}
+	cService, cAccount := C.CString(claudeKeychainService), C.CString(account)
+	defer C.free(unsafe.Pointer(cService))
+	defer C.free(unsafe.Pointer(cAccount))
+	err := keychainCall("SecItemCopyMatching", claudeKeychainService, account, C.kc_exists(cService, cAccount))
+	switch {
+	case err == nil:
+		return "Claude Code item found in the login keychain", nil
+	case isItemNotFound(err):
+		return "no Claude Code item in the login keychain", nil
+	}
+	return "", err
+}
@@ -0,0 +1,171 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The freedesktop Secret Service (gnome-keyring, KWallet) is reached through
+// libsecret's secret-tool, using the same service/account attributes as
+// Claude Code's keychain item on macOS.
+const secretService = "Claude Code-credentials"
+
+// secretTool returns the path to secret-tool, or "" when the Secret Service
+// can't be used: the tool isn't installed or there's no session bus.
+func secretTool() string {
+	if sandboxed() || os.Getenv("USER") == "" || os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return ""
+	}
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func lookupSecret(tool string) json.RawMessage {
+	out, err := exec.Command(tool, "lookup", "service", secretService, "account", os.Getenv("USER")).Output()
+	debugKeychain("secret-tool lookup service '"+secretService+"'", err)
+	if err != nil {
+		return nil
+	}
+	var doc map[string]json.RawMessage
+	if json.Unmarshal([]byte(strings.TrimSpace(string(out))), &doc) != nil {
+		return nil
+	}
+	return doc["claudeAiOauth"]
+}
+
+func readKeychainCredentials() json.RawMessage {
+	tool := secretTool()
+	if tool == "" {
+		return nil
+	}
+	return lookupSecret(tool)
+}
+
+// writeKeychainCredentials only updates an existing item. Most Linux installs
+// keep credentials in the flat file alone, and creating a keyring entry for
+// them would just leave a stale copy behind.
+func writeKeychainCredentials(creds *OAuthCredentials) error {
+	tool := secretTool()
+	if tool == "" || lookupSecret(tool) == nil {
+		return nil
+	}
+	credsJSON, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	doc := map[string]json.RawMessage{"claudeAiOauth": credsJSON}
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(tool, "store", "--label="+secretService, "service", secretService, "account", os.Getenv("USER"))
+	cmd.Stdin = strings.NewReader(string(docJSON))
+	err = cmd.Run()
+	debugKeychain("secret-tool store service '"+secretService+"'", err)
+	return err
+}
+
+// deleteKeychainCredentials removes Claude Code's item, if there is one.
+func deleteKeychainCredentials() error {
+	tool := secretTool()
+	if tool == "" || lookupSecret(tool) == nil {
+		return nil
+	}
+	err := exec.Command(tool, "clear", "service", secretService, "account", os.Getenv("USER")).Run()
+	debugKeychain("secret-tool clear service '"+secretService+"'", err)
+	return err
+}
+
+// profileSecretTool is secretTool for profile secrets, which have nowhere
+// else to go, so its absence is an error.
+func profileSecretTool() (string, error) {
+	if tool := secretTool(); tool != "" {
+		return tool, nil
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", errors.New("secret-tool is not installed (it comes with libsecret-tools or libsecret)")
+	}
+	return "", errors.New("the Secret Service can't be reached: there is no D-Bus session")
+}
+
+// readKeychainSecret reads a profile's secrets from the claude-switch item
+// for it in the Secret Service.
+func readKeychainSecret(item string) ([]byte, error) {
+	tool, err := profileSecretTool()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, "lookup", "service", profileKeychainService, "account", item)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	debugKeychain("secret-tool lookup service '"+profileKeychainService+"' account '"+item+"'", err)
+	// A missing item fails without a message.
+	msg := strings.TrimSpace(stderr.String())
+	switch {
+	case err != nil && msg != "":
+		return nil, errors.New(msg)
+	case err != nil || len(bytes.TrimSpace(out)) == 0:
+		return nil, errKeychainItemNotFound
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func writeKeychainSecret(item string, data []byte) error {
+	tool, err := profileSecretTool()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, "store", "--label="+profileKeychainService+": "+item, "service", profileKeychainService, "account", item)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	debugKeychain("secret-tool store service '"+profileKeychainService+"' account '"+item+"'", err)
+	if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
+
+// deleteKeychainSecret removes a profile's item; a missing item is not an
+// error.
+func deleteKeychainSecret(item string) error {
+	tool, err := profileSecretTool()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, "clear", "service", profileKeychainService, "account", item)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	debugKeychain("secret-tool clear service '"+profileKeychainService+"' account '"+item+"'", err)
+	if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// keychainStatus reports whether the Secret Service is in use and holds
+// Claude Code's item, for doctor.
+func keychainStatus() (string, error) {
+	if tool := secretTool(); tool != "" {
+		if lookupSecret(tool) != nil {
+			return "Claude Code item found in the Secret Service", nil
+		}
+		return "no Claude Code item in the Secret Service", nil
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "secret-tool not installed; credentials are only kept in the flat file", nil
+	}
+	return "no session bus; credentials are only kept in the flat file", nil
+}
@@ -0,0 +1,38 @@
+//go:build !darwin && !windows && !linux
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+func readKeychainCredentials() json.RawMessage {
+	return nil
+}
+
+func writeKeychainCredentials(_ *OAuthCredentials) error {
+	return nil
+}
+
+func deleteKeychainCredentials() error {
+	return nil
+}
+
+func keychainStatus() (string, error) {
+	return "no system keychain on this platform; credentials are only kept in the flat file", nil
+}
+
+var errNoKeychain = errors.New("there is no system keychain on this platform")
+
+func readKeychainSecret(string) ([]byte, error) {
+	return nil, errNoKeychain
+}
+
+func writeKeychainSecret(string, []byte) error {
+	return errNoKeychain
+}
+
+func deleteKeychainSecret(string) error {
+	return errNoKeychain
+}
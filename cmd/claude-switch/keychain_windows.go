@@ -0,0 +1,188 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows Credential Manager, under the same name Claude Code uses for its
+// macOS keychain item.
+const credentialTarget = "Claude Code-credentials"
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+)
+
+// credential mirrors CREDENTIALW.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credRead returns the blob of the generic credential target, or
+// windows.ERROR_NOT_FOUND.
+func credRead(target string) ([]byte, error) {
+	name, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return nil, err
+	}
+	var cred *credential
+	ret, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		debugKeychain("CredReadW '"+target+"'", callErr)
+		return nil, callErr
+	}
+	debugKeychain("CredReadW '"+target+"'", nil)
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	return append([]byte(nil), unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)...), nil
+}
+
+func credWrite(target string, blob []byte) error {
+	name, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return err
+	}
+	user, err := windows.UTF16PtrFromString(os.Getenv("USERNAME"))
+	if err != nil {
+		return err
+	}
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         name,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	ret, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		debugKeychain("CredWriteW '"+target+"'", callErr)
+		return errors.New("failed to write to Windows Credential Manager: " + callErr.Error())
+	}
+	debugKeychain("CredWriteW '"+target+"'", nil)
+	return nil
+}
+
+// credDelete removes the generic credential target; a missing entry is not
+// an error.
+func credDelete(target string) error {
+	name, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return err
+	}
+	ret, _, callErr := procCredDeleteW.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0)
+	if ret == 0 {
+		debugKeychain("CredDeleteW '"+target+"'", callErr)
+	} else {
+		debugKeychain("CredDeleteW '"+target+"'", nil)
+	}
+	if ret == 0 && !errors.Is(callErr, windows.ERROR_NOT_FOUND) {
+		return errors.New("failed to delete from Windows Credential Manager: " + callErr.Error())
+	}
+	return nil
+}
+
+func readKeychainCredentials() json.RawMessage {
+	if sandboxed() {
+		return nil
+	}
+	blob, err := credRead(credentialTarget)
+	if err != nil {
+		return nil
+	}
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(blob, &doc) != nil {
+		return nil
+	}
+	return doc["claudeAiOauth"]
+}
+
+func writeKeychainCredentials(creds *OAuthCredentials) error {
+	if sandboxed() {
+		return nil
+	}
+	credsJSON, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	doc := map[string]json.RawMessage{"claudeAiOauth": credsJSON}
+	blob, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return credWrite(credentialTarget, blob)
+}
+
+// deleteKeychainCredentials removes Claude Code's entry; a missing entry is
+// not an error.
+func deleteKeychainCredentials() error {
+	if sandboxed() {
+		return nil
+	}
+	return credDelete(credentialTarget)
+}
+
+// Profile secrets are kept under targets named "claude-switch:<item>".
+
+func readKeychainSecret(item string) ([]byte, error) {
+	blob, err := credRead(profileKeychainService + ":" + item)
+	if errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return nil, errKeychainItemNotFound
+	}
+	if err != nil {
+		return nil, errors.New("failed to read from Windows Credential Manager: " + err.Error())
+	}
+	return blob, nil
+}
+
+func writeKeychainSecret(item string, data []byte) error {
+	return credWrite(profileKeychainService+":"+item, data)
+}
+
+func deleteKeychainSecret(item string) error {
+	return credDelete(profileKeychainService + ":" + item)
+}
+
+// keychainStatus reports whether Credential Manager can be queried for
+// Claude Code's entry, for doctor.
+func keychainStatus() (string, error) {
+	target, err := windows.UTF16PtrFromString(credentialTarget)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	ret, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret != 0 {
+		procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+		return "Claude Code entry found in Credential Manager", nil
+	}
+	if errors.Is(callErr, windows.ERROR_NOT_FOUND) {
+		return "no Claude Code entry in Credential Manager", nil
+	}
+	return "", errors.New("failed to read from Windows Credential Manager: " + callErr.Error())
+}
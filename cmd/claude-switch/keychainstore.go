@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// --- Keychain store (--store keychain, store = "keychain") ---
+
+// A profile stored as "keychain:<item>" keeps its secrets in the system
+// keychain (macOS Keychain, the Secret Service on Linux, Windows Credential
+// Manager) under the service claude-switch, and only the non-secret fields
+// on disk, like a 1Password reference. The item is named after the profile
+// when it is first saved and keeps that name through renames.
+
+const (
+	keychainStore          = "keychain"
+	profileKeychainService = "claude-switch"
+)
+
+var errKeychainItemNotFound = errors.New("the keychain item doesn't exist")
+
+// keychainItem returns the item of a keychain store reference.
+func keychainItem(store string) (string, bool) {
+	item, ok := strings.CutPrefix(store, keychainStore+":")
+	return item, ok && item != ""
+}
+
+// resolveStore checks a --store value, keychain, keychain:<item>, file or a
+// 1Password reference, and returns what goes in the profile. Without one,
+// the store setting applies.
+func resolveStore(store string) (string, error) {
+	switch store {
+	case "":
+		return defaults.newProfileStore(), nil
+	case "file":
+		return "", nil
+	case keychainStore:
+		return store, nil
+	}
+	if item, ok := strings.CutPrefix(store, keychainStore+":"); ok {
+		if validateProfileName(item) != nil {
+			return "", usageError("invalid keychain item '%s'", item)
+		}
+		return store, nil
+	}
+	if !strings.HasPrefix(store, "op://") {
+		return "", usageError("invalid store '%s' (expected keychain, file or op://vault/item)", store)
+	}
+	_, err := parseOpRef(store)
+	return store, err
+}
+
+// mergeStore is the store of a profile kept in current that is saved again
+// with store, such as on import --force: asking for the keychain keeps the
+// item it already has.
+func mergeStore(current, store string) string {
+	if _, ok := keychainItem(current); ok && store == keychainStore {
+		return current
+	}
+	return store
+}
+
+// newKeychainItem names the keychain item for a profile being moved into
+// the keychain. An item left behind by a removed profile of the same name,
+// which `remove --undo` would restore, is not reused.
+func newKeychainItem(name string) (string, error) {
+	for n := 1; ; n++ {
+		item := name
+		if n > 1 {
+			item += "-" + strconv.Itoa(n)
+		}
+		_, err := readSecretItem(item)
+		if errors.Is(err, errKeychainItemNotFound) {
+			return item, nil
+		}
+		if err != nil {
+			return "", keychainError(name, err)
+		}
+	}
+}
+
+func keychainError(name string, err error) error {
+	return &cliError{
+		Code:    errNoCredentials,
+		Message: fmt.Sprintf("failed to access the keychain for '%s': %v", name, err),
+		Profile: name,
+		Hint:    "unlock the keychain, or set store = \"file\" in config.toml to keep secrets on disk",
+		Err:     err,
+	}
+}
+
+func loadKeychainSecrets(name, item string, profile *Profile) error {
+	data, err := readSecretItem(item)
+	if errors.Is(err, errKeychainItemNotFound) {
+		return &cliError{Code: errNoCredentials, Message: fmt.Sprintf("the keychain item %s for '%s' is missing", item, name), Profile: name, Err: err}
+	}
+	if err != nil {
+		return keychainError(name, err)
+	}
+	var secrets profileSecrets
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return fmt.Errorf("keychain item %s doesn't hold claude-switch profile secrets: %w", item, err)
+	}
+	setSecrets(profile, secrets)
+	return nil
+}
+
+func saveKeychainSecrets(name, item string, profile *Profile) error {
+	data, err := json.Marshal(secretsOf(profile))
+	if err != nil {
+		return err
+	}
+	if err := writeSecretItem(item, data); err != nil {
+		return keychainError(name, err)
+	}
+	return nil
+}
+
+// deleteKeychainStore removes the keychain item of a profile stored there,
+// once the profile is gone for good or keeps its secrets elsewhere.
+func deleteKeychainStore(store string) error {
+	item, ok := keychainItem(store)
+	if !ok {
+		return nil
+	}
+	if sandboxed() {
+		err := os.Remove(sandboxKeychainPath(item))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return deleteKeychainSecret(item)
+}
+
+// readSecretItem and writeSecretItem are the platform's keychain, or files
+// in sandbox mode.
+func readSecretItem(item string) ([]byte, error) {
+	if sandboxed() {
+		data, err := readFile(sandboxKeychainPath(item))
+		if os.IsNotExist(err) {
+			return nil, errKeychainItemNotFound
+		}
+		return data, err
+	}
+	return readKeychainSecret(item)
+}
+
+func writeSecretItem(item string, data []byte) error {
+	if sandboxed() {
+		return writeSecure(sandboxKeychainPath(item), data)
+	}
+	return writeKeychainSecret(item, data)
+}
+
+// sandboxKeychainPath stands in for a keychain item in sandbox mode.
+func sandboxKeychainPath(item string) string {
+	return filepath.Join(sandboxDir(), "keychain", item+".json")
+}
+
+// moveProfileSecrets moves the secrets of every profile kept on disk into
+// the keychain, or with toKeychain false, those in the keychain back to
+// disk. Profiles in 1Password stay there. It returns how many moved.
+func moveProfileSecrets(toKeychain bool) (int, error) {
+	names, err := listProfiles()
+	if err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, name := range names {
+		profile, err := loadProfile(name)
+		if err != nil {
+			return moved, err
+		}
+		_, inKeychain := keychainItem(profile.Store)
+		old := profile.Store
+		switch {
+		case toKeychain && profile.Store == "":
+			profile.Store = keychainStore
+		case !toKeychain && inKeychain:
+			profile.Store = ""
+		default:
+			continue
+		}
+		if err := saveProfile(name, profile); err != nil {
+			return moved, err
+		}
+		if inKeychain {
+			if err := deleteKeychainStore(old); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: couldn't delete the keychain item of '%s': %v\n", name, err)
+			}
+		}
+		moved++
+	}
+	// Saving backed up the versions with the secrets in them.
+	if toKeychain {
+		if _, err := purgeSecretBackups(); err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
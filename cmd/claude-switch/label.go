@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// --- Labels and notes ---
+
+// A label is a short description shown next to the profile in list, e.g.
+// the client an account belongs to; notes are free-form text for show.
+
+// LabelInfo is the JSON shape of label's output.
+type LabelInfo struct {
+	Label string `json:"label,omitempty"`
+	Notes string `json:"notes,omitempty"`
+}
+
+func cmdLabel(args []string) error {
+	var name string
+	var label, notes *string
+	clear := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--notes" || strings.HasPrefix(a, "--notes="):
+			value, ok := strings.CutPrefix(a, "--notes=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("--notes requires text (or - to read it from stdin)")
+				}
+				i++
+				value = args[i]
+			}
+			if value == "-" {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return err
+				}
+				value = string(data)
+			}
+			notes = &value
+		case a == "--clear":
+			clear = true
+		case name == "":
+			name = a
+		case label == nil:
+			label = &a
+		default:
+			return usageError("unexpected argument: %s (quote a label with spaces)", a)
+		}
+	}
+	if name == "" {
+		return usageError("label requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	if clear && (label != nil || notes != nil) {
+		return usageError("--clear can't be combined with a label or --notes")
+	}
+
+	if clear || label != nil || notes != nil {
+		unlock, err := lockProfileUpdate(name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+	if !clear && label == nil && notes == nil {
+		info := labelInfo(profile)
+		if jsonOutput() {
+			return printJSON(info)
+		}
+		if info.Label == "" && info.Notes == "" {
+			infof("No label or notes for '%s'\n", name)
+			return nil
+		}
+		if info.Label != "" {
+			fmt.Println(info.Label)
+		}
+		if info.Notes != "" {
+			if info.Label != "" {
+				fmt.Println()
+			}
+			fmt.Println(info.Notes)
+		}
+		return nil
+	}
+
+	if clear {
+		profile.Label, profile.Notes = nil, nil
+	}
+	if label != nil {
+		profile.Label = optionalText(*label)
+	}
+	if notes != nil {
+		profile.Notes = optionalText(*notes)
+	}
+	if err := saveProfile(name, profile); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(labelInfo(profile))
+	}
+	infof("Updated label and notes for '%s'\n", name)
+	return nil
+}
+
+// optionalText trims s and returns nil for an empty string, which removes
+// the field.
+func optionalText(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func labelInfo(p *Profile) LabelInfo {
+	var info LabelInfo
+	if p.Label != nil {
+		info.Label = *p.Label
+	}
+	if p.Notes != nil {
+		info.Notes = *p.Notes
+	}
+	return info
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- Cross-process file locks ---
+
+// Each file that is read, modified and written back gets its own advisory
+// lock (flock, or LockFileEx on Windows) in the config directory, so
+// concurrent exec/use runs can't interleave their updates. Locks are never
+// nested on the same name: a second lock from the same process would wait
+// for itself. A profile's refresh lock is the only one held around others,
+// its profile lock among them, for as long as a refresh or an update
+// (lockProfileUpdate) has the profile loaded; nothing under it may refresh.
+
+const lockTimeout = claudeswitch.LockTimeout
+
+// refreshLockTimeout is how long to wait for another process's refresh of
+// the same profile, longer than a refresh with all its retries can take.
+const refreshLockTimeout = 3 * time.Minute
+
+// Fixed lock names; profiles each have their own (profileLock). Those of
+// the files pkg/claudeswitch also writes come from there.
+const (
+	claudeCredentialsLock = claudeswitch.ClaudeCredentialsLock
+	claudeJSONLock        = claudeswitch.ClaudeJSONLock
+	stateLock             = "state"
+	indexLock             = claudeswitch.IndexLock
+	configLock            = "config"
+	claudeSettingsLock    = "claude-settings"
+	launchesLock          = "launches"
+)
+
+func profileLock(name string) string {
+	return claudeswitch.ProfileLock(name)
+}
+
+// refreshLock is held for a profile's whole token refresh, so concurrent
+// processes refresh it once rather than invalidate each other's rotated
+// refresh tokens.
+func refreshLock(name string) string {
+	return "refresh-" + name
+}
+
+// lockProfileUpdate holds a profile's refresh lock from loading the profile
+// to saving it back, so a token another process rotates meanwhile isn't
+// overwritten with the stale one. Call the returned function to unlock.
+func lockProfileUpdate(name string) (func(), error) {
+	return lockFileWithin(refreshLock(name), refreshLockTimeout)
+}
+
+func locksDir() string {
+	return filepath.Join(configDir(), "locks")
+}
+
+// lockFile takes the exclusive lock called name, waiting up to lockTimeout
+// for other processes to release it. Call the returned function to unlock.
+func lockFile(name string) (func(), error) {
+	return lockFileWithin(name, lockTimeout)
+}
+
+// lockFileWithin is lockFile with another timeout.
+func lockFileWithin(name string, timeout time.Duration) (func(), error) {
+	start := time.Now()
+	unlock, err := claudeswitch.LockFile(locksDir(), name, timeout)
+	if err != nil {
+		var timedOut *claudeswitch.LockTimeoutError
+		if errors.As(err, &timedOut) {
+			return nil, lockedError(timedOut)
+		}
+		return nil, err
+	}
+	if waited := time.Since(start); waited >= 100*time.Millisecond {
+		debugf("waited %s for lock %s", waited.Round(time.Millisecond), name)
+	}
+	return unlock, nil
+}
+
+func lockedError(err *claudeswitch.LockTimeoutError) *cliError {
+	return &cliError{
+		Code:    errLocked,
+		Message: err.Error(),
+		Hint:    "another claude-switch process is still running; retry once it finishes",
+		Err:     err,
+	}
+}
+
+// withLock runs fn while holding the lock called name.
+func withLock(name string, fn func() error) error {
+	unlock, err := lockFile(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
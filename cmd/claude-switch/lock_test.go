@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLockProfileUpdateExcludesRefresh(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_SANDBOX", "1")
+	t.Setenv("CLAUDE_SWITCH_SANDBOX_DIR", t.TempDir())
+
+	unlock, err := lockProfileUpdate("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A refresh of the same profile has to wait for the update.
+	_, err = lockFileWithin(refreshLock("work"), 0)
+	var ce *cliError
+	if !errors.As(err, &ce) || ce.Code != errLocked {
+		t.Errorf("refresh lock while updating: %v, want %s", err, errLocked)
+	}
+	// Other profiles aren't held up.
+	other, err := lockFileWithin(refreshLock("personal"), 0)
+	if err != nil {
+		t.Errorf("refresh lock of another profile: %v", err)
+	} else {
+		other()
+	}
+	unlock()
+
+	again, err := lockFileWithin(refreshLock("work"), 0)
+	if err != nil {
+		t.Fatalf("refresh lock after the update: %v", err)
+	}
+	again()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// --- logout: leave Claude signed out ---
+
+// cmdLogout removes Claude's login from its config files and the system
+// keychain and clears the active profile. Saved profiles are untouched, so
+// `use` signs back in.
+func cmdLogout(args []string) error {
+	for _, a := range args {
+		return usageError("unexpected argument: %s", a)
+	}
+	if sessions := claudeSessions(); len(sessions) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s running. Claude may write its credentials back after the logout.\n", describeSessions(sessions))
+	}
+
+	// Keep any tokens Claude refreshed since the last switch, which would
+	// otherwise be lost along with the live login.
+	if loadState().ActiveProfile != nil {
+		if _, err := syncActiveProfile(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't save Claude's current tokens to the active profile: %v\n", err)
+		}
+	}
+	if err := clearAuth(); err != nil {
+		return err
+	}
+	if _, err := setAPIKeyHelper(nil); err != nil {
+		return err
+	}
+	if defaults.keychainEnabled() {
+		if err := deleteKeychainCredentials(); err != nil {
+			return fmt.Errorf("failed to remove Claude's keychain item: %w", err)
+		}
+	}
+	var previous string
+	if err := updateState(func(state *State) {
+		if state.ActiveProfile != nil {
+			previous = *state.ActiveProfile
+		}
+		state.ActiveProfile = nil
+	}); err != nil {
+		return err
+	}
+
+	if jsonOutput() {
+		result := map[string]any{"logged_out": true}
+		if previous != "" {
+			result["previous_profile"] = previous
+		}
+		return printJSON(result)
+	}
+	if previous != "" {
+		infof("Logged out of Claude. Profile '%s' is kept; 'claude-switch use %s' signs back in.\n", previous, previous)
+	} else {
+		infof("Logged out of Claude.\n")
+	}
+	return nil
+}
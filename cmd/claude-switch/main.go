@@ -0,0 +1,1802 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+const usage = `Manage multiple Claude Code accounts
+
+Usage: claude-switch [-o text|json] [--backend name] <command> [arguments]
+
+Commands:
+  add <name>              Add a new profile by logging in through the browser
+  add <name> --headless   Same, for machines without a browser: open the printed URL
+                          elsewhere and paste back the code
+  add <name> --claude [--claude-bin <path>]
+                          Add a profile via claude /login instead (logs out the current
+                          session; needed for Console/API key accounts)
+  add <name> --manual [--no-verify]
+                          Add an OAuth profile from pasted tokens (no browser or claude needed)
+  add <name> --api-key-stdin | --api-key <key>
+                          Add an API key profile directly, without logging in
+  import <name>           Import currently active Claude Code credentials as a named profile
+  import <name> --from-file <file|-> [--force]
+                          Import a profile written by export (--force: replace it)
+  import <name> --token-stdin [--force]
+                          Import a long-lived token from 'claude setup-token', which
+                          exec/env inject as is, without refreshing (or an API key)
+  import <name> --from-env[=VAR] [--force]
+                          Import CLAUDE_CODE_OAUTH_TOKEN or ANTHROPIC_API_KEY (or VAR)
+                          from the environment
+  import|add <name> --store keychain|op://vault/item|file
+                          Keep the profile's secrets in the system keychain or 1Password
+                          instead of on disk (file: on disk, whatever the store setting)
+  use <name> [--wait|-k|--kill|--force] [--refresh|--no-refresh]
+                          Switch to a named profile. If Claude is running on the login
+                          being replaced, asks first; --wait waits for it to exit, --kill
+                          terminates it, --force switches anyway. --refresh renews the
+                          token even if it isn't expiring; --no-refresh never does
+  use                     Use the profile named in ./.claude-profile (searched upwards),
+                          or pick one from a menu (on a terminal)
+  use --fzf [query]       Pick the profile with fzf, previewing each one's details
+  logout                  Sign Claude out (config files and keychain) and clear the
+                          active profile; saved profiles are kept
+  list [--names] [--group g] [--usage] [--check] [--wide | --columns c,...]
+       [--utc | --local] [--sort name|last-used]
+                          List all profiles (--names: just the names, one per line;
+                          --group: only that group's; --usage: with each account's
+                          5-hour and weekly usage; --check: verify each profile's
+                          credentials with the API, as verify does; --wide: with account UUID,
+                          scopes and last use; --columns: just these columns; --utc/--local:
+                          expiry as a timestamp instead of "in 3h12m"; --sort
+                          last-used: most recently used first)
+  group add <group> <name>... | remove <group> [name...] | list [group]
+                          Manage named groups of profiles (remove without names
+                          deletes the group)
+  current [--format f]    Print the active profile's name (or email, plan, org, type,
+                          expires, or a template such as '{{.Name}} ({{.Plan}})')
+  prompt [--style ansi|bash|zsh|tmux|plain] [--prefix p] [--no-expiry]
+                          Print a short segment such as 'claude:work ⏳45m' for shell
+                          prompts and status bars, coloured by how soon it expires
+  sessions [-p <name>] [--utc|--local]
+                          List running Claude sessions with the profile each is signed
+                          in as, when it started and its working directory
+  status                  Check that Claude still holds the active profile's credentials
+  refresh <name>... | --all | --group g [--within 24h]
+                          Refresh OAuth tokens now (--within: only those expiring soon)
+  service install [--interval 6h] [--within 24h] | uninstall | status
+                          Run 'refresh --all' on a schedule (launchd on macOS,
+                          systemd user timer on Linux)
+  sync                    Save tokens Claude refreshed on its own back into the profile
+  watch [--interval 10s] [--daemon] [--stop]
+                          Keep running and sync whenever Claude rotates its tokens
+                          (--daemon: in the background, logging to watch.log)
+  agent [--daemon] [--socket path] [--lifetime 8h] [--stop]
+                          Unlock profiles once and serve credentials to exec, env and
+                          token over a unix socket (ssh-agent style)
+  credential-helper [get]
+                          Read key=value lines (profile=<name>) on stdin and print the
+                          refreshed token as key=value lines, git-credential style
+  api-key-helper          Print the active profile's API key, for Claude's apiKeyHelper
+                          (see api_key_helper in config.toml)
+  serve [--listen 127.0.0.1:0]
+                          Serve list, use, token and refresh as a local REST API,
+                          authenticated by the token written to serve.json
+  api [-X POST] <path>    Call the API of a running serve, e.g. /v1/profiles
+  export <name> [--redact]
+                          Print a profile, secrets included, as JSON for import --from-file
+  push <name> <[user@]host> [--as name] [--use] [--force] [--remote-cmd path]
+                          Copy a profile to another machine's claude-switch over SSH
+                          (--use: switch to it there)
+  show <name> [--reveal] [--no-usage] [--local]
+                          Show a profile's details and usage (secrets masked unless
+                          --reveal; --no-usage: skip the usage lookup; --local: times
+                          in the local time zone)
+  verify <name> [--no-refresh]
+                          Check with the API that a profile's credentials still work
+                          (refreshes an expired token unless --no-refresh)
+  ui                      Full-screen dashboard: switch, refresh, remove, rename, search
+  remove <name> [--yes] [--revoke]
+                          Remove a profile, keeping it in the trash for 7 days (asks
+                          first on a terminal; --revoke: revoke its tokens and delete it)
+  remove --undo [name]    Restore a removed profile, or list those that can be restored
+  exec <name> [--refresh] -- <cmd>
+                          Run a command with a profile's credentials injected
+                          (--refresh: renew the token first even if it isn't expiring)
+  exec -- <cmd>           Same, under the profile run would use (the default profile
+                          unless $CLAUDE_SWITCH_PROFILE or .claude-profile says otherwise)
+  exec --chain <chain> -- <cmd>
+                          Same, using the first usable profile of a configured chain
+  exec --group <group> -- <cmd>
+                          Same, using the first usable profile of a group
+  exec --pool <group> -- <cmd>
+                          Same, rotating through the group: uses the usable profile
+                          that the pool handed out least recently
+  exec --failover <group> -- <cmd>
+                          Same, starting with the active profile, and re-running the
+                          command under the group's next profile when it fails on a
+                          usage or rate limit
+  exec --isolated <name> -- <cmd>
+                          Same, with a Claude config directory of the profile's own
+                          ($CLAUDE_CONFIG_DIR), so sessions under different profiles
+                          don't share ~/.claude
+  run -- <cmd>            Run a command like exec, under $CLAUDE_SWITCH_PROFILE, the
+                          profile named in .claude-profile, or the default profile
+  shell <name> [--isolated]
+                          Start $SHELL with a profile's credentials set and its name
+                          in the prompt (--isolated: as with exec)
+  exec-all [--group g] [-j N] -- <cmd>
+                          Run a command once per profile (-j: N at a time) and
+                          report each one's exit status
+  token <name>            Print a profile's current access token (or API key)
+  env <name> [--shell sh] Print eval-able lines exporting a profile's credentials
+                          (bash, zsh, sh, fish, nu, powershell, cmd)
+  docker-env <name> [--file path]
+                          Print (or write, mode 0600) a profile's variables as a
+                          docker --env-file
+  docker run <name> -- <docker run arguments>
+                          Run a container with a profile's variables passed in by name
+  devcontainer <name> [--dir .devcontainer] [--write]
+                          Print devcontainer.json properties that pass a profile into
+                          the container, refreshed on every start (--write: what they
+                          run on the host)
+  fallback-key <name> [key|-] [--clear]
+                          Attach a backup API key to an OAuth profile for exec/env
+  proxy <name> [--http url] [--https url] [--no-proxy hosts] [--clear]
+                          Show or set proxy variables that exec/env inject for a profile
+  vars <name> [KEY=VALUE...] [--unset KEY] [--clear]
+                          Show or set extra variables that exec/env inject for a
+                          profile (e.g. ANTHROPIC_BASE_URL)
+  settings <name> [--capture | --clear]
+                          Show, capture or drop a profile's own copy of Claude's
+                          settings.json and CLAUDE.md, which use puts in place
+  mcp <name> [--capture | --from-file <file|-> | --clear]
+                          Show or set a profile's own MCP servers, which use puts in
+                          Claude's config (--capture: the ones configured now)
+  label <name> [text] [--notes text|-] [--clear]
+                          Show or set a profile's label and notes
+  org list <name> | use <name> <org>
+                          List the organizations of a profile's account, or move the
+                          profile to another one (by name or UUID) without a new login
+  backup <file> [--encrypt|--plaintext]
+                          Write all profiles and state to one file (--encrypt: under
+                          a passphrase; --plaintext: unencrypted even if the profiles
+                          are encrypted)
+  restore <file> [--force]
+                          Load profiles from a backup (--force: overwrite existing ones)
+  sync-remote push|pull [--provider s3] [--keep-local]
+                          Share the profile store, encrypted, through an S3 bucket
+  gc [--dry-run]          Remove orphaned index entries and stale state
+  prune [--dry-run] [--yes]
+                          Remove profiles whose refresh token was revoked or whose
+                          file can't be parsed (asks first)
+  doctor                  Check config, profiles, Claude's files, keychain and network
+  history [--profile name] [--since 24h] [-n N] [--utc | --local]
+                          Show the audit log of profile switches, launches, adds and removes
+                          (needs audit = true in config.toml)
+  default [name | --clear]
+                          Show or set the default profile, which run, exec -- and use
+                          fall back to, and prompt shows while none is active
+  config get|set|unset <key> [value] | path
+                          Read or change a [defaults] setting in config.toml ('config set
+                          store keychain' moves every profile's secrets to the keychain)
+  encrypt enable|disable|status
+                          Encrypt stored profiles with a passphrase (or turn it off)
+  completion bash|zsh|fish
+                          Print a shell completion script
+  init bash|zsh|fish [--env]
+                          Print a shell hook that applies .claude-profile files on cd
+                          (switches the active profile, or with --env exports it)
+
+Global flags (before the command):
+  -o, --output <format>   Output format: text (default) or json. In json mode results
+                          are printed to stdout and failures as a JSON error on stderr
+  --json                  Shorthand for --output json
+  --backend <name>        Storage backend for this run (file, vault), overriding config.toml
+  --color <when>          Colour output: auto (default: only on a terminal, and not if
+                          NO_COLOR is set), always or never
+  --config-dir <dir>      claude-switch's directory for this run (default ~/.config/claude-switch,
+                          or $CLAUDE_SWITCH_CONFIG_DIR)
+  --claude-dir <dir>      Claude Code's config directory for this run (default ~/.claude, or
+                          $CLAUDE_CONFIG_DIR)
+  --exact                 Take profile names literally instead of completing a unique
+                          prefix (e.g. use wo for work-eu)
+  --verbose               Log files read and written, keychain calls and HTTP requests to
+                          stderr, with timings (or set CLAUDE_SWITCH_DEBUG=1)
+  -q, --quiet             Print only warnings and errors, leaving out progress and
+                          confirmation messages
+`
+
+func main() {
+	if err := parseGlobalFlags(); err != nil {
+		writeErrorText(os.Stderr, err)
+		os.Exit(exitCode(err))
+	}
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+	// Arguments may hold secrets, so only the command is logged.
+	debugf("%s: config in %s, Claude's credentials in %s and %s", os.Args[1], configDir(), credentialsPath(), claudeJSONPath())
+	if sandboxed() {
+		if err := seedSandbox(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: failed to set up sandbox: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	reconcile()
+
+	var err error
+	switch os.Args[1] {
+	case "add":
+		err = cmdAdd(os.Args[2:])
+	case "import":
+		err = cmdImport(os.Args[2:])
+	case "use":
+		var sessions, refresh string
+		var fzf bool
+		var filtered []string
+		if sessions, refresh, fzf, filtered, err = parseUseFlags(os.Args[2:]); err != nil {
+			break
+		}
+		var name string
+		if fzf {
+			if len(filtered) > 0 {
+				name = filtered[0]
+			}
+			if name, err = pickProfileFzf(name); err == nil {
+				err = cmdUse(name, sessions, refresh)
+			}
+			break
+		}
+		if len(filtered) > 0 {
+			name = filtered[0]
+		} else if name, _, err = cwdProfile(); err != nil {
+			break
+		} else if name == "" {
+			name = defaults.Profile
+		}
+		switch {
+		case name != "":
+			err = cmdUse(name, sessions, refresh)
+		case pickerAvailable() && !jsonOutput():
+			if name, err = pickProfile(); err == nil {
+				err = cmdUse(name, sessions, refresh)
+			}
+		default:
+			err = usageError("use requires a profile name")
+		}
+	case "logout":
+		err = cmdLogout(os.Args[2:])
+	case "list":
+		err = cmdList(os.Args[2:])
+	case "current":
+		err = cmdCurrent(os.Args[2:])
+	case "prompt":
+		err = cmdPrompt(os.Args[2:])
+	case "sessions":
+		err = cmdSessions(os.Args[2:])
+	case "show":
+		err = cmdShow(os.Args[2:])
+	case "verify":
+		err = cmdVerify(os.Args[2:])
+	case "remove":
+		err = cmdRemove(os.Args[2:])
+	case "exec":
+		err = cmdExec(os.Args[2:])
+	case "env":
+		err = cmdEnv(os.Args[2:])
+	case "docker-env":
+		err = cmdDockerEnv(os.Args[2:])
+	case "docker":
+		err = cmdDocker(os.Args[2:])
+	case "devcontainer":
+		err = cmdDevcontainer(os.Args[2:])
+	case "fallback-key":
+		err = cmdFallbackKey(os.Args[2:])
+	case "proxy":
+		err = cmdProxy(os.Args[2:])
+	case "exec-all":
+		err = cmdExecAll(os.Args[2:])
+	case "run":
+		err = cmdRun(os.Args[2:])
+	case "shell":
+		err = cmdShell(os.Args[2:])
+	case "settings":
+		err = cmdSettings(os.Args[2:])
+	case "mcp":
+		err = cmdMCP(os.Args[2:])
+	case "vars":
+		err = cmdVars(os.Args[2:])
+	case "label":
+		err = cmdLabel(os.Args[2:])
+	case "group":
+		err = cmdGroup(os.Args[2:])
+	case "org":
+		err = cmdOrg(os.Args[2:])
+	case "sync-remote":
+		err = cmdSyncRemote(os.Args[2:])
+	case "backup":
+		err = cmdBackup(os.Args[2:])
+	case "restore":
+		err = cmdRestore(os.Args[2:])
+	case "push":
+		err = cmdPush(os.Args[2:])
+	case "export":
+		err = cmdExport(os.Args[2:])
+	case "gc":
+		err = cmdGC(os.Args[2:])
+	case "prune":
+		err = cmdPrune(os.Args[2:])
+	case "encrypt":
+		err = cmdEncrypt(os.Args[2:])
+	case "completion":
+		err = cmdCompletion(os.Args[2:])
+	case "ui":
+		err = cmdUI(os.Args[2:])
+	case "status":
+		err = cmdStatus(os.Args[2:])
+	case "sync":
+		err = cmdSync(os.Args[2:])
+	case "agent":
+		err = cmdAgent(os.Args[2:])
+	case "token":
+		err = cmdToken(os.Args[2:])
+	case "api-key-helper":
+		err = cmdAPIKeyHelper(os.Args[2:])
+	case "credential-helper":
+		err = cmdCredentialHelper(os.Args[2:])
+	case "serve":
+		err = cmdServe(os.Args[2:])
+	case "api":
+		err = cmdAPI(os.Args[2:])
+	case "refresh":
+		err = cmdRefresh(os.Args[2:])
+	case "service":
+		err = cmdService(os.Args[2:])
+	case "watch":
+		err = cmdWatch(os.Args[2:])
+	case "doctor":
+		err = cmdDoctor(os.Args[2:])
+	case "init":
+		err = cmdInit(os.Args[2:])
+	case "hook":
+		err = cmdHook(os.Args[2:])
+	case "history":
+		err = cmdHistory(os.Args[2:])
+	case "default":
+		err = cmdDefault(os.Args[2:])
+	case "config":
+		err = cmdConfig(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(0)
+	default:
+		if jsonOutput() {
+			err = usageError("unknown command: %s", os.Args[1])
+			break
+		}
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", os.Args[1], usage)
+		os.Exit(exitUsage)
+	}
+
+	if err != nil && auditProfile != "" && !audited {
+		recordAudit(auditProfile, err)
+	}
+	debugf("done")
+	if err != nil {
+		if jsonOutput() {
+			writeErrorJSON(os.Stderr, err)
+		} else {
+			writeErrorText(os.Stderr, err)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// parseGlobalFlags removes flags that apply to every command from os.Args.
+// They come before the command: everything from the command on belongs to
+// it, including the arguments of a command that exec runs.
+func parseGlobalFlags() error {
+	args := []string{os.Args[0]}
+	rest := os.Args[1:]
+	for i := 0; i < len(rest); i++ {
+		a := rest[i]
+		if a == "--" || !strings.HasPrefix(a, "-") {
+			args = append(args, rest[i:]...)
+			break
+		}
+		switch {
+		case a == "--json":
+			outputFormat = "json"
+		case a == "--output" || a == "-o":
+			if i+1 >= len(rest) {
+				return usageError("%s requires a format (text or json)", a)
+			}
+			i++
+			outputFormat = rest[i]
+		case strings.HasPrefix(a, "--output="):
+			outputFormat = strings.TrimPrefix(a, "--output=")
+		case a == "--backend":
+			if i+1 >= len(rest) {
+				return usageError("%s requires a storage backend name", a)
+			}
+			i++
+			backendOverride = rest[i]
+		case strings.HasPrefix(a, "--backend="):
+			backendOverride = strings.TrimPrefix(a, "--backend=")
+		case a == "--color":
+			if i+1 >= len(rest) {
+				return usageError("%s requires auto, always or never", a)
+			}
+			i++
+			colorMode = rest[i]
+		case strings.HasPrefix(a, "--color="):
+			colorMode = strings.TrimPrefix(a, "--color=")
+		case a == "--exact":
+			exactNames = true
+		case a == "--verbose":
+			verbose = true
+		case a == "-q" || a == "--quiet":
+			quiet = true
+		case a == "--config-dir" || a == "--claude-dir" || strings.HasPrefix(a, "--config-dir=") || strings.HasPrefix(a, "--claude-dir="):
+			flag, dir, ok := strings.Cut(a, "=")
+			if !ok {
+				if i+1 >= len(rest) {
+					return usageError("%s requires a directory", a)
+				}
+				i++
+				dir = rest[i]
+			}
+			if err := setDirOverride(flag, dir); err != nil {
+				return err
+			}
+		default:
+			args = append(args, a)
+		}
+	}
+	if err := loadDefaults(); err != nil {
+		return err
+	}
+	if outputFormat == "" {
+		outputFormat = cmp.Or(defaults.Output, "text")
+	}
+	if outputFormat != "text" && outputFormat != "json" {
+		return usageError("unknown output format '%s' (expected text or json)", outputFormat)
+	}
+	if colorMode != "auto" && colorMode != "always" && colorMode != "never" {
+		return usageError("unknown color mode '%s' (expected auto, always or never)", colorMode)
+	}
+	os.Args = args
+	return nil
+}
+
+// setDirOverride applies --config-dir or --claude-dir by setting the
+// matching environment variable, so commands started from here (exec, the
+// agent) see the same directories.
+func setDirOverride(flag, dir string) error {
+	if dir == "" {
+		return usageError("%s requires a directory", flag)
+	}
+	abs, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		return err
+	}
+	key := "CLAUDE_SWITCH_CONFIG_DIR"
+	if flag == "--claude-dir" {
+		key = "CLAUDE_CONFIG_DIR"
+	}
+	return os.Setenv(key, abs)
+}
+
+func requireName(cmd string, fn func(string) error) error {
+	if len(os.Args) < 3 {
+		return usageError("%s requires a profile name", cmd)
+	}
+	return fn(os.Args[2])
+}
+
+// parseStoreArgs parses "<name> [--store keychain|file|op://vault/item]".
+func parseStoreArgs(cmd string, args []string) (name, store string, err error) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--store":
+			if i+1 >= len(args) {
+				return "", "", usageError("--store requires keychain, file or a 1Password reference")
+			}
+			i++
+			store = args[i]
+		case strings.HasPrefix(a, "--store="):
+			store = strings.TrimPrefix(a, "--store=")
+		case name == "":
+			name = a
+		default:
+			return "", "", usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return "", "", usageError("%s requires a profile name", cmd)
+	}
+	if store, err = resolveStore(store); err != nil {
+		return "", "", err
+	}
+	return name, store, nil
+}
+
+func cmdAdd(args []string) error {
+	var name, store string
+	var apiKey, claudeBin string
+	manual, viaClaude, headless, apiKeyStdin, verify := false, false, false, false, true
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--store":
+			if i+1 >= len(args) {
+				return usageError("--store requires keychain, file or a 1Password reference")
+			}
+			i++
+			store = args[i]
+		case strings.HasPrefix(a, "--store="):
+			store = strings.TrimPrefix(a, "--store=")
+		case a == "--manual":
+			manual = true
+		case a == "--headless":
+			headless = true
+		case a == "--claude":
+			viaClaude = true
+		case a == "--claude-bin":
+			if i+1 >= len(args) {
+				return usageError("--claude-bin requires a path")
+			}
+			i++
+			claudeBin = args[i]
+		case strings.HasPrefix(a, "--claude-bin="):
+			claudeBin = strings.TrimPrefix(a, "--claude-bin=")
+		case a == "--no-verify":
+			verify = false
+		case a == "--api-key":
+			if i+1 >= len(args) {
+				return usageError("--api-key requires a key")
+			}
+			i++
+			apiKey = args[i]
+		case strings.HasPrefix(a, "--api-key="):
+			apiKey = strings.TrimPrefix(a, "--api-key=")
+		case a == "--api-key-stdin":
+			apiKeyStdin = true
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("add requires a profile name")
+	}
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	store, err := resolveStore(store)
+	if err != nil {
+		return err
+	}
+	if profileExists(name) {
+		return existsError(name)
+	}
+	modes := 0
+	for _, set := range []bool{manual, viaClaude, headless, apiKey != "" || apiKeyStdin} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 || (apiKey != "" && apiKeyStdin) {
+		return usageError("only one of --manual, --headless, --claude, --api-key and --api-key-stdin can be given")
+	}
+	if claudeBin != "" && !viaClaude {
+		return usageError("--claude-bin only applies to --claude")
+	}
+	if apiKey != "" || apiKeyStdin {
+		return cmdAddAPIKey(name, store, apiKey)
+	}
+	if manual {
+		return cmdAddManual(name, store, verify)
+	}
+	if viaClaude {
+		return cmdAddViaClaude(name, store, claudeBin)
+	}
+
+	login := oauthLogin
+	if headless {
+		login = oauthLoginHeadless
+	}
+	profile, err := login(nil)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	profile.Store = store
+	saved, profile, err := saveNewProfile(name, profile)
+	if err != nil {
+		return err
+	}
+	if err := reportSaved("Saved", name, saved, profile); err != nil {
+		return err
+	}
+	if !jsonOutput() {
+		infof("Run 'claude-switch use %s' to switch to it.\n", saved)
+	}
+	return nil
+}
+
+// cmdAddViaClaude logs in through Claude Code itself and imports whatever it
+// saved. It replaces the live session, so the new profile becomes active. If
+// the login fails or is abandoned, the previous session is put back.
+func cmdAddViaClaude(name, store, bin string) error {
+	login, err := claudeLogin(name, bin)
+	if err != nil {
+		return err
+	}
+
+	snapshot := snapshotAuth()
+	// Clear Claude's auth so the CLI triggers its first-run login flow
+	if err := clearAuth(); err != nil {
+		return err
+	}
+
+	// Ctrl-C is meant for claude; staying alive lets us restore afterwards.
+	signal.Ignore(os.Interrupt)
+	err = login()
+	signal.Reset(os.Interrupt)
+	var profile *Profile
+	var failed *cliError
+	if err != nil {
+		failed = &cliError{Code: errClaudeFailed, Message: fmt.Sprintf("claude exited with error: %v", err), Profile: name, Err: err}
+	} else if profile, err = importCurrentCredentials(); err != nil {
+		failed = &cliError{Code: errNoCredentials, Message: "claude exited without logging in", Profile: name, Err: err}
+	}
+	if failed != nil {
+		if err := restoreAuth(snapshot); err != nil {
+			failed.Hint = fmt.Sprintf("restoring your previous session also failed (%v); use 'claude-switch use <profile>' to switch back", err)
+			return failed
+		}
+		if !jsonOutput() {
+			infof("Restored your previous Claude session.\n")
+		}
+		return failed
+	}
+	profile.Store = store
+
+	saved, profile, err := saveNewProfile(name, profile)
+	if err != nil {
+		return err
+	}
+
+	if err := updateState(func(state *State) { state.ActiveProfile = &saved }); err != nil {
+		return err
+	}
+
+	return reportSaved("Saved", name, saved, profile)
+}
+
+func cmdImport(args []string) error {
+	var fromFile, fromEnv string
+	var rest []string
+	force, tokenStdin := false, false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--force" || a == "-f":
+			force = true
+		case a == "--token-stdin":
+			tokenStdin = true
+		case a == "--from-env":
+			fromEnv = "auto"
+		case strings.HasPrefix(a, "--from-env="):
+			fromEnv = strings.TrimPrefix(a, "--from-env=")
+		case a == "--from-file":
+			if i+1 >= len(args) {
+				return usageError("--from-file requires a file name (or - for stdin)")
+			}
+			i++
+			fromFile = args[i]
+		case strings.HasPrefix(a, "--from-file="):
+			fromFile = strings.TrimPrefix(a, "--from-file=")
+		default:
+			rest = append(rest, a)
+		}
+	}
+	name, store, err := parseStoreArgs("import", rest)
+	if err != nil {
+		return err
+	}
+	sources := 0
+	for _, set := range []bool{fromFile != "", fromEnv != "", tokenStdin} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return usageError("--from-file, --from-env and --token-stdin can't be combined")
+	}
+	if force && sources == 0 {
+		return usageError("--force only applies to --from-file, --from-env and --token-stdin")
+	}
+	if profileExists(name) && !force {
+		return existsError(name)
+	}
+	switch {
+	case fromFile != "":
+		return importFromFile(name, store, fromFile)
+	case fromEnv != "":
+		key, secret, err := secretFromEnv(fromEnv)
+		if err != nil {
+			return err
+		}
+		return importSecret(name, store, secret, envSecretType(key, secret))
+	case tokenStdin:
+		secret, err := readSecret("Token from 'claude setup-token' (or an API key): ")
+		if err != nil {
+			return err
+		}
+		if secret == "" || strings.ContainsAny(secret, " \t\n") {
+			return usageError("expected a single token on stdin")
+		}
+		return importSecret(name, store, secret, envSecretType("", secret))
+	}
+
+	profile, err := importCurrentCredentials()
+	if err != nil {
+		return &cliError{Code: errNoCredentials, Message: "no credentials found — is Claude Code logged in?", Profile: name, Err: err}
+	}
+	profile.Store = store
+
+	saved, profile, err := saveNewProfile(name, profile)
+	if err != nil {
+		return err
+	}
+
+	if err := updateState(func(state *State) { state.ActiveProfile = &saved }); err != nil {
+		return err
+	}
+
+	if saved != name {
+		return reportSaved("Imported", name, saved, profile)
+	}
+	if jsonOutput() {
+		return printJSON(summarizeProfile(name, profile))
+	}
+	if profile.Type == "oauth" {
+		email := profile.DisplayEmail()
+		sub := profile.DisplaySub()
+		infof("Imported current session as '%s' (%s, %s)\n", name, email, sub)
+	} else {
+		infof("Imported current session as '%s' (API key)\n", name)
+	}
+	return nil
+}
+
+// secretFromEnv reads the credential for import --from-env. "auto" takes
+// whichever of the variables Claude Code reads is set.
+func secretFromEnv(key string) (string, string, error) {
+	if key != "auto" {
+		value := strings.TrimSpace(os.Getenv(key))
+		if value == "" {
+			return "", "", &cliError{Code: errNoCredentials, Message: fmt.Sprintf("%s is not set", key)}
+		}
+		return key, value, nil
+	}
+	oauth, apiKey := strings.TrimSpace(os.Getenv("CLAUDE_CODE_OAUTH_TOKEN")), strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY"))
+	switch {
+	case oauth != "" && apiKey != "":
+		return "", "", usageError("both CLAUDE_CODE_OAUTH_TOKEN and ANTHROPIC_API_KEY are set; pick one with --from-env=<variable>")
+	case oauth != "":
+		return "CLAUDE_CODE_OAUTH_TOKEN", oauth, nil
+	case apiKey != "":
+		return "ANTHROPIC_API_KEY", apiKey, nil
+	}
+	return "", "", &cliError{
+		Code:    errNoCredentials,
+		Message: "neither CLAUDE_CODE_OAUTH_TOKEN nor ANTHROPIC_API_KEY is set",
+		Hint:    "name another variable with --from-env=<variable>",
+	}
+}
+
+// envSecretType decides what kind of profile a bare secret makes: the
+// variable it came from says, otherwise the key's prefix does.
+func envSecretType(key, secret string) string {
+	switch {
+	case key == "ANTHROPIC_API_KEY":
+		return "api_key"
+	case key == "CLAUDE_CODE_OAUTH_TOKEN":
+		return "oauth_token"
+	case strings.HasPrefix(secret, "sk-ant-api"):
+		return "api_key"
+	}
+	if !strings.HasPrefix(secret, "sk-ant-oat") {
+		fmt.Fprintln(os.Stderr, "Warning: this doesn't look like a token from 'claude setup-token' (sk-ant-oat...); saving it as one.")
+	}
+	return "oauth_token"
+}
+
+// importSecret saves a bare token or API key as an oauth_token or api_key
+// profile. Like importFromFile, it leaves the active profile alone.
+func importSecret(name, store, secret, profileType string) error {
+	profile := &Profile{Type: profileType, Store: store}
+	if profileType == "api_key" {
+		profile.ApiKey = secret
+	} else {
+		profile.OAuthToken = secret
+	}
+	existing, err := loadProfile(name)
+	if err != nil {
+		saved, profile, err := saveNewProfile(name, profile)
+		if err != nil {
+			return err
+		}
+		return reportSaved("Imported", name, saved, profile)
+	}
+	// --force replaces the token but keeps proxies, variables and the like,
+	// so rotating a token is a one-liner.
+	existing.ReplaceCredentials(profile)
+	previous := existing.Store
+	existing.Store = mergeStore(previous, store)
+	if err := saveProfile(name, existing); err != nil {
+		return err
+	}
+	if existing.Store != previous {
+		if err := deleteKeychainStore(previous); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't delete the old keychain item of '%s': %v\n", name, err)
+		}
+	}
+	return reportProfileSaved("Imported", name, existing)
+}
+
+// importFromFile saves an exported profile under name, replacing any profile
+// already there. Unlike importing the live session, it leaves the active
+// profile alone.
+func importFromFile(name, store, path string) error {
+	profile, err := readProfileFile(path)
+	if err != nil {
+		return err
+	}
+	profile.Store = store
+	saved, profile, err := saveNewProfile(name, profile)
+	if err != nil {
+		return err
+	}
+	return reportSaved("Imported", name, saved, profile)
+}
+
+// Refresh modes of use and exec: by default a token is refreshed when it
+// is close to expiry. --refresh also replaces one that isn't, e.g. after it
+// was revoked on the server, and --no-refresh switches without the network.
+const (
+	refreshWhenExpired = ""
+	refreshAlways      = "always"
+	refreshNever       = "never"
+)
+
+// parseUseFlags takes use's flags out of args, returning what is left.
+func parseUseFlags(args []string) (sessions, refresh string, fzf bool, rest []string, err error) {
+	sessions, refresh = sessionsAsk, refreshWhenExpired
+	for _, a := range args {
+		switch a {
+		case "--fzf":
+			fzf = true
+		case "--refresh", "--no-refresh":
+			if err := setRefreshMode(&refresh, a); err != nil {
+				return "", "", false, nil, err
+			}
+		case "--kill", "-k":
+			sessions = sessionsKill
+		case "--wait":
+			sessions = sessionsWait
+		case "--force":
+			sessions = sessionsForce
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return sessions, refresh, fzf, rest, nil
+}
+
+// setRefreshMode applies a --refresh or --no-refresh flag to mode. Repeating
+// a flag is fine; giving both is not.
+func setRefreshMode(mode *string, flag string) error {
+	next := refreshAlways
+	if flag == "--no-refresh" {
+		next = refreshNever
+	}
+	if *mode != refreshWhenExpired && *mode != next {
+		return usageError("--refresh and --no-refresh can't be combined")
+	}
+	*mode = next
+	return nil
+}
+
+func cmdUse(name, sessions, refresh string) error {
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	auditProfile = name
+	if err := settleSessions(sessions); err != nil {
+		return err
+	}
+
+	var profile *Profile
+	switch refresh {
+	case refreshNever:
+		if profile, err = loadProfile(name); err != nil {
+			return err
+		}
+		if profile.Type == "oauth" && isExpired(profile.Credentials) {
+			fmt.Fprintf(os.Stderr, "Warning: the token of '%s' has expired; Claude refreshes it once it's online.\n", name)
+		}
+		err = switchProfile(name, profile)
+	case refreshAlways:
+		if err = forceRefresh(name, true); err == nil {
+			profile, err = activateProfile(name, true)
+		}
+	default:
+		profile, err = activateProfile(name, true)
+	}
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(summarizeProfile(name, profile))
+	}
+
+	switch {
+	case profile.Type == "oauth":
+		infof("Switched to '%s'\n", name)
+	case profile.Type == "api_key" && defaults.APIKeyHelper:
+		infof("Switched to '%s'; Claude gets its API key from 'claude-switch %s'\n", name, apiKeyHelperCommand)
+	default:
+		kind := profile.DescribeType()
+		fmt.Fprintf(os.Stderr, "%s profiles can't be written to Claude's config files.\n", strings.ToUpper(kind[:1])+kind[1:])
+		fmt.Fprintln(os.Stderr, "Use one of these instead:")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintf(os.Stderr, "  %s\n", evalCommand(detectShell(), name))
+		fmt.Fprintf(os.Stderr, "  claude-switch exec %s -- claude\n", name)
+		if profile.Type == "api_key" {
+			fmt.Fprintln(os.Stderr)
+			fmt.Fprintln(os.Stderr, "Or have use set Claude's apiKeyHelper: claude-switch config set api_key_helper true")
+		}
+	}
+	return nil
+}
+
+// activateProfile makes name the active profile, refreshing it first if
+// needed. OAuth credentials are written into Claude's config; API key
+// profiles are only marked active, since Claude reads those from the
+// environment or, with api_key_helper on, from api-key-helper. Either way
+// the profile's Claude settings and MCP servers are put in place.
+func activateProfile(name string, reauth bool) (*Profile, error) {
+	profile, err := loadFreshProfile(name, reauth)
+	if err != nil {
+		recordAudit(name, err)
+		return nil, err
+	}
+	return profile, switchProfile(name, profile)
+}
+
+// switchProfile is activateProfile for a profile that is already loaded,
+// as it is.
+func switchProfile(name string, profile *Profile) (err error) {
+	defer func() { recordAudit(name, err) }()
+	if profile.Type == "oauth" {
+		if err := installCredentials(profile); err != nil {
+			return err
+		}
+	}
+	if err := swapClaudeSettings(name, profile); err != nil {
+		return err
+	}
+	if err := swapMCPServers(name, profile); err != nil {
+		return err
+	}
+	// After the settings swap, which may have replaced settings.json.
+	if _, err := setAPIKeyHelper(profile); err != nil {
+		return err
+	}
+
+	return updateState(func(state *State) {
+		state.ActiveProfile = &name
+		stampUsed(state, name)
+	})
+}
+
+// installCredentials writes an OAuth profile's credentials and account
+// where Claude Code reads them.
+func installCredentials(profile *Profile) error {
+	live := toLocalClock(profile.Credentials)
+	if err := writeCredentials(live); err != nil {
+		return err
+	}
+	if defaults.keychainEnabled() {
+		if err := writeKeychainCredentials(live); err != nil {
+			return err
+		}
+	}
+	return writeOAuthAccount(profile.Account)
+}
+
+func cmdList(args []string) error {
+	namesOnly, withUsage, withCheck, wide := false, false, false, false
+	var group, columnSpec string
+	timeMode, sortBy := timeRelative, "name"
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--names":
+			namesOnly = true
+		case a == "--usage" || a == "-u":
+			withUsage = true
+		case a == "--check":
+			withCheck = true
+		case a == "--wide" || a == "-w":
+			wide = true
+		case parseTimeFlag(a, &timeMode):
+		case a == "--sort" || strings.HasPrefix(a, "--sort="):
+			value, ok := strings.CutPrefix(a, "--sort=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires name or last-used", a)
+				}
+				i++
+				value = args[i]
+			}
+			if value != "name" && value != "last-used" {
+				return usageError("unknown sort order '%s' (expected name or last-used)", value)
+			}
+			sortBy = value
+		case a == "--columns" || a == "-c" || strings.HasPrefix(a, "--columns="):
+			value, ok := strings.CutPrefix(a, "--columns=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a list of columns", a)
+				}
+				i++
+				value = args[i]
+			}
+			columnSpec = value
+		case a == "--group" || a == "-g" || strings.HasPrefix(a, "--group="):
+			value, ok := strings.CutPrefix(a, "--group=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a group name", a)
+				}
+				i++
+				value = args[i]
+			}
+			group = value
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if wide && columnSpec != "" {
+		return usageError("--columns and --wide can't be combined")
+	}
+	keys := defaultListColumns
+	switch {
+	case wide:
+		keys = wideListColumns
+	case columnSpec != "":
+		keys = strings.Split(columnSpec, ",")
+	}
+	if withUsage {
+		for _, key := range usageListColumns {
+			if !slices.Contains(keys, key) {
+				keys = append(slices.Clip(keys), key)
+			}
+		}
+	}
+	if withCheck && !slices.Contains(keys, "check") {
+		keys = append(slices.Clip(keys), "check")
+	}
+	columns, err := selectListColumns(keys)
+	if err != nil {
+		return err
+	}
+	withUsage = slices.ContainsFunc(columns, func(c listColumn) bool { return c.usage })
+	withCheck = slices.ContainsFunc(columns, func(c listColumn) bool { return c.check })
+
+	names, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	if group != "" {
+		members, err := groupMembers(group)
+		if err != nil {
+			return err
+		}
+		names = slices.DeleteFunc(names, func(name string) bool { return !slices.Contains(members, name) })
+	}
+	state := loadState()
+	if sortBy == "last-used" {
+		// Most recent first; never-used profiles stay in name order at the end.
+		slices.SortStableFunc(names, func(a, b string) int {
+			return cmp.Compare(state.LastUsed[b], state.LastUsed[a])
+		})
+	}
+	if namesOnly && !jsonOutput() {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	// Checks go first: they refresh expired tokens, which the usage
+	// lookups can then use.
+	var checks map[string]VerifyResult
+	if withCheck {
+		checks = verifyProfiles(names, true)
+	}
+	var usages map[string]usageResult
+	if withUsage {
+		usages = fetchUsages(names)
+	}
+	if jsonOutput() {
+		return listJSON(names, usages, checks)
+	}
+	if len(names) == 0 {
+		infof("No profiles. Use 'claude-switch add <name>' or 'claude-switch import <name>' to create one.\n")
+		return nil
+	}
+
+	index := loadIndex()
+	metas := make(map[string]*ProfileMeta, len(names))
+	for _, name := range names {
+		if meta, err := loadProfileMeta(&index, name); err == nil {
+			metas[name] = meta
+		}
+	}
+	// Labels only take up room by default once some profile has one.
+	if !wide && columnSpec == "" && slices.ContainsFunc(names, func(name string) bool {
+		return metas[name] != nil && metas[name].Label != ""
+	}) {
+		label, _ := selectListColumns([]string{"label"})
+		columns = slices.Insert(columns, 1, label...)
+	}
+
+	r := newRenderer(os.Stdout)
+	t := &table{}
+	headers := []string{r.header(" ")}
+	t.shrink = []bool{false}
+	for _, c := range columns {
+		headers = append(headers, r.header(c.header))
+		t.shrink = append(t.shrink, c.shrink)
+	}
+	t.add(headers...)
+	for _, name := range names {
+		row := listRow{name: name, meta: metas[name], usage: usages[name], timeMode: timeMode, lastUsed: state.LastUsed[name]}
+		if check, ok := checks[name]; ok {
+			row.check = &check
+		}
+		row.active = state.ActiveProfile != nil && *state.ActiveProfile == name
+		cells := []string{" "}
+		if row.active {
+			cells[0] = r.active("*")
+		}
+		for _, c := range columns {
+			cells = append(cells, c.cell(r, row))
+		}
+		t.add(cells...)
+	}
+	if err := t.write(os.Stdout); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if check := checks[name]; check.Error != "" {
+			fmt.Fprintf(os.Stderr, "'%s' is %s: %s\n", name, check.Status, check.Error)
+		}
+	}
+	for _, name := range names {
+		if err := usages[name].Err; err != nil {
+			fmt.Fprintf(os.Stderr, "Usage of '%s' unavailable: %v\n", name, err)
+		}
+	}
+
+	// Only the account is compared here, from .claude.json and the index, so
+	// list never has to open credential files.
+	if state.ActiveProfile != nil {
+		if meta, err := loadProfileMeta(&index, *state.ActiveProfile); err == nil && meta.AccountUUID != "" {
+			live := readLiveAuth()
+			if uuid := claudeswitch.AccountField(live.Account, "accountUuid"); uuid != "" && uuid != meta.AccountUUID {
+				fmt.Fprintf(os.Stderr, "Note: Claude is logged in as %s, not '%s'. Run 'claude-switch status' for details.\n", claudeswitch.AccountField(live.Account, "emailAddress"), *state.ActiveProfile)
+			}
+		}
+	}
+	return nil
+}
+
+// listJSON prints the profiles as summaries, with their usage and check
+// results if usages and checks are given.
+func listJSON(names []string, usages map[string]usageResult, checks map[string]VerifyResult) error {
+	return printJSON(listSummaries(names, usages, checks))
+}
+
+// listSummaries is what list --json prints for names.
+func listSummaries(names []string, usages map[string]usageResult, checks map[string]VerifyResult) []ProfileSummary {
+	index := loadIndex()
+	summaries := []ProfileSummary{}
+	for _, name := range names {
+		meta, err := loadProfileMeta(&index, name)
+		if err != nil {
+			summaries = append(summaries, ProfileSummary{Name: name, Error: err.Error()})
+			continue
+		}
+		summary := summarize(name, meta)
+		if result, ok := usages[name]; ok {
+			summary.Usage = result.Usage
+			if result.Err != nil {
+				summary.UsageError = result.Err.Error()
+			}
+		}
+		if check, ok := checks[name]; ok {
+			summary.Status, summary.StatusError = check.Status, check.Error
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func cmdFallbackKey(args []string) error {
+	var name, key string
+	clear := false
+	for _, a := range args {
+		switch {
+		case a == "--clear":
+			clear = true
+		case name == "":
+			name = a
+		case key == "":
+			key = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("fallback-key requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	if !clear {
+		if key == "" || key == "-" {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			key = strings.TrimSpace(string(data))
+		}
+		if key == "" {
+			return usageError("no API key given")
+		}
+	}
+	unlock, err := lockProfileUpdate(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+	if profile.Type != "oauth" {
+		return &cliError{Code: errUsage, Message: fmt.Sprintf("profile '%s' is not an OAuth profile", name), Profile: name}
+	}
+
+	if clear {
+		profile.FallbackApiKey = ""
+		if err := saveProfile(name, profile); err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(summarizeProfile(name, profile))
+		}
+		infof("Removed fallback API key from '%s'\n", name)
+		return nil
+	}
+
+	profile.FallbackApiKey = key
+	if err := saveProfile(name, profile); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(summarizeProfile(name, profile))
+	}
+	infof("Set fallback API key for '%s'\n", name)
+	return nil
+}
+
+// execOptions are the arguments of exec. All of the profile sources are
+// empty for exec -- <cmd>, which runs under the profile run would pick.
+type execOptions struct {
+	name, chain, group, pool, failover string
+	isolated                           bool
+	refresh                            string
+	command                            []string
+}
+
+func parseExecArgs(args []string) (*execOptions, error) {
+	opts := &execOptions{refresh: refreshWhenExpired}
+	// Both may come before or after the profile.
+	takeFlags := func() error {
+		for len(args) > 0 && (args[0] == "--isolated" || args[0] == "--refresh") {
+			if args[0] == "--isolated" {
+				opts.isolated = true
+			} else if err := setRefreshMode(&opts.refresh, args[0]); err != nil {
+				return err
+			}
+			args = args[1:]
+		}
+		return nil
+	}
+	if err := takeFlags(); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, usageError("exec requires a profile name, --chain <chain>, --group <group>, --pool <group> or --failover <group>")
+	}
+	flag, value, hasValue := strings.Cut(args[0], "=")
+	switch flag {
+	case "--chain", "--group", "-g", "--pool", "--failover":
+		if hasValue {
+			args = args[1:]
+		} else {
+			if len(args) < 2 {
+				return nil, usageError("%s requires a name", flag)
+			}
+			value, args = args[1], args[2:]
+		}
+		switch flag {
+		case "--chain":
+			opts.chain = value
+		case "--group", "-g":
+			opts.group = value
+		case "--pool":
+			opts.pool = value
+		case "--failover":
+			opts.failover = value
+		}
+	case "--":
+	default:
+		opts.name, args = args[0], args[1:]
+	}
+	if err := takeFlags(); err != nil {
+		return nil, err
+	}
+
+	// Find the command args (everything after --)
+	opts.command = args
+	// Strip leading "--" if present
+	if len(opts.command) > 0 && opts.command[0] == "--" {
+		opts.command = opts.command[1:]
+	}
+	if len(opts.command) == 0 {
+		return nil, usageError("no command specified")
+	}
+
+	if opts.failover != "" && opts.isolated {
+		return nil, usageError("--isolated can't be combined with --failover")
+	}
+	if opts.refresh != refreshWhenExpired && (opts.chain != "" || opts.group != "" || opts.pool != "" || opts.failover != "") {
+		return nil, usageError("--refresh needs a profile name")
+	}
+	return opts, nil
+}
+
+func cmdExec(args []string) error {
+	opts, err := parseExecArgs(args)
+	if err != nil {
+		return err
+	}
+	name, chain, group, pool := opts.name, opts.chain, opts.group, opts.pool
+	isolated, refresh, cmdArgs := opts.isolated, opts.refresh, opts.command
+	if opts.failover != "" {
+		return execFailover(opts.failover, cmdArgs)
+	}
+	if name == "" && chain == "" && group == "" && pool == "" {
+		// No profile named: the one run would pick, ending with the default.
+		if name, err = runProfile(); err != nil {
+			return err
+		}
+	}
+
+	var vars []envVar
+	if name != "" {
+		if name, err = resolveProfileName(name, true); err != nil {
+			return err
+		}
+		auditProfile = name
+		if refresh == refreshAlways {
+			if err := forceRefresh(name, true); err != nil {
+				return err
+			}
+		}
+	}
+	switch {
+	case chain != "":
+		name, vars, err = resolveChain(chain)
+	case group != "":
+		name, vars, err = resolveGroup(group)
+	case pool != "":
+		name, vars, err = resolvePool(pool)
+	default:
+		vars, err = credentialEnvVars(name, true)
+	}
+	if err != nil {
+		return err
+	}
+	if isolated {
+		dir, err := prepareIsolated(name)
+		if err != nil {
+			return err
+		}
+		// The flag wins over a CLAUDE_CONFIG_DIR among the profile's variables.
+		vars = append(slices.DeleteFunc(vars, func(v envVar) bool { return v.Key == dir.Key }), dir)
+	}
+	if cmdArgs[0] == "claude" {
+		if cmdArgs[0], err = resolveClaude(name); err != nil {
+			return err
+		}
+	}
+	markUsed(name)
+	return execWithEnv(cmdArgs, append(vars, envVar{"CLAUDE_SWITCH_PROFILE", name}))
+}
+
+// resolveChain walks a chain from config.toml in order and returns the
+// first profile whose credentials are usable without user interaction.
+func resolveChain(chain string) (string, []envVar, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	names := cfg.Chains[chain]
+	if len(names) == 0 {
+		return "", nil, &cliError{
+			Code:    errConfig,
+			Message: fmt.Sprintf("chain '%s' is not defined", chain),
+			Hint:    fmt.Sprintf("add it under [chains] in %s", configPath()),
+		}
+	}
+
+	return firstUsable(names, fmt.Sprintf("chain '%s'", chain))
+}
+
+// resolveGroup is resolveChain for a group, whose members are tried in
+// name order.
+func resolveGroup(group string) (string, []envVar, error) {
+	names, err := groupMembers(group)
+	if err != nil {
+		return "", nil, err
+	}
+	return firstUsable(names, fmt.Sprintf("group '%s'", group))
+}
+
+// firstUsable returns the first of names whose credentials are usable
+// without user interaction. from describes where the names came from.
+func firstUsable(names []string, from string) (string, []envVar, error) {
+	for _, name := range names {
+		vars, err := credentialEnvVars(name, false)
+		if err != nil {
+			infof("Skipping '%s': %v\n", name, err)
+			continue
+		}
+		infof("Using profile '%s' from %s\n", name, from)
+		return name, vars, nil
+	}
+	return "", nil, &cliError{
+		Code:    errChainExhausted,
+		Message: fmt.Sprintf("no profile in %s has usable credentials", from),
+		Hint:    "run 'claude-switch list' to check its profiles",
+	}
+}
+
+type envVar struct {
+	Key   string
+	Value string
+}
+
+// profileEnvVars resolves the variables to inject for a profile: its
+// credential first, then per-profile settings: proxies and the profile's own
+// variables, which win over a proxy variable of the same name. OAuth is
+// preferred; if it can't be refreshed and the profile carries a fallback
+// API key, that key is used instead of prompting for a new login.
+func profileEnvVars(name string, reauth bool) ([]envVar, error) {
+	profile, err := loadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var credential envVar
+	if profile.FallbackApiKey == "" {
+		profile, err = loadFreshProfile(name, reauth)
+		if err != nil {
+			return nil, err
+		}
+		credential.Key, credential.Value = profileEnv(profile)
+	} else if fresh, err := loadFreshProfile(name, false); err != nil {
+		fmt.Fprintf(os.Stderr, "OAuth credentials for '%s' are unusable (%v); using its fallback API key.\n", name, err)
+		credential = envVar{"ANTHROPIC_API_KEY", profile.FallbackApiKey}
+	} else {
+		profile = fresh
+		credential.Key, credential.Value = profileEnv(profile)
+	}
+
+	vars := []envVar{credential}
+	extra := extraEnvVars(profile)
+	for _, v := range proxyEnvVars(profile.Proxy) {
+		// Either spelling of a proxy variable is replaced by the profile's own.
+		if !slices.ContainsFunc(extra, func(e envVar) bool { return strings.EqualFold(e.Key, v.Key) }) {
+			vars = append(vars, v)
+		}
+	}
+	return append(vars, extra...), nil
+}
+
+// loadFreshProfile loads a profile and, for OAuth profiles close to expiry,
+// refreshes and persists the token first. When the refresh token itself is
+// dead the user is walked through re-authentication if reauth is set.
+func loadFreshProfile(name string, reauth bool) (*Profile, error) {
+	profile, err := loadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Type != "oauth" || !isExpired(profile.Credentials) {
+		return profile, nil
+	}
+
+	infof("Token expired, refreshing...\n")
+	return refreshProfile(name, profile, reauth)
+}
+
+// refreshProfile exchanges the profile's refresh token for new credentials
+// and saves them, whether or not the current ones have expired. Only one
+// process refreshes a profile at a time; the others wait and use the tokens
+// it saved.
+func refreshProfile(name string, profile *Profile, reauth bool) (*Profile, error) {
+	unlock, err := lockFileWithin(refreshLock(name), refreshLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	// Another process may have refreshed or changed the profile meanwhile;
+	// only the saved copy has its latest refresh token and other changes.
+	if current, err := loadProfile(name); err == nil && current.Credentials != nil {
+		if current.Credentials.RefreshToken != profile.Credentials.RefreshToken && !isExpired(current.Credentials) {
+			debugf("refresh: '%s' was refreshed by another process meanwhile", name)
+			return current, nil
+		}
+		profile = current
+	}
+
+	refreshed, err := refreshToken(profile.Credentials, profile.Proxy)
+	if err != nil {
+		if re, ok := err.(*RefreshError); ok && re.Kind == refreshRateLimited && profile.Credentials.ExpiresAt > nowMs() {
+			// Still inside the expiry buffer, so the old token keeps working
+			fmt.Fprintf(os.Stderr, "Warning: %v; using the current token for now.\n", err)
+			return profile, nil
+		}
+		if re, ok := err.(*RefreshError); ok && re.Kind == refreshInvalidGrant {
+			if !reauth {
+				return nil, &cliError{
+					Code:    errReauthRequired,
+					Message: fmt.Sprintf("refresh token for '%s' is no longer valid", name),
+					Profile: name,
+					Hint:    fmt.Sprintf("run 'claude-switch use %s' to re-authenticate", name),
+					Err:     err,
+				}
+			}
+			newProfile, err := reauthenticateProfile(name)
+			if err != nil {
+				return nil, err
+			}
+			if newProfile.Type != "oauth" {
+				return nil, fmt.Errorf("re-authentication resulted in non-OAuth profile")
+			}
+			return newProfile, nil
+		}
+		return nil, err
+	}
+	profile.Credentials = refreshed
+	if err := saveProfile(name, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// profileEnv returns the environment variable Claude Code reads a profile's
+// credential from, along with its value.
+func profileEnv(profile *Profile) (string, string) {
+	switch profile.Type {
+	case "oauth":
+		return "CLAUDE_CODE_OAUTH_TOKEN", profile.Credentials.AccessToken
+	case "oauth_token":
+		return "CLAUDE_CODE_OAUTH_TOKEN", profile.OAuthToken
+	}
+	return "ANTHROPIC_API_KEY", profile.ApiKey
+}
+
+// mergeEnv overrides entries of env with vars. Existing entries are dropped
+// rather than shadowed, since getenv implementations return the first match.
+func mergeEnv(env []string, vars []envVar) []string {
+	override := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		override[envKey(v.Key)] = true
+	}
+	merged := make([]string, 0, len(env)+len(vars))
+	for _, kv := range env {
+		if key, _, _ := strings.Cut(kv, "="); !override[envKey(key)] {
+			merged = append(merged, kv)
+		}
+	}
+	for _, v := range vars {
+		merged = append(merged, v.Key+"="+v.Value)
+	}
+	return merged
+}
+
+// --- Helpers ---
+
+func profileExists(name string) bool {
+	if validateProfileName(name) != nil {
+		return false
+	}
+	store, err := storage()
+	return err == nil && store.Exists(name)
+}
+
+func importCurrentCredentials() (*Profile, error) {
+	claudePath := claudeJSONPath()
+
+	oauthRaw := readOAuthCredentials()
+
+	// Try API key
+	var apiKey string
+	data, err := readFile(claudePath)
+	if err == nil {
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(data, &doc) == nil {
+			if raw, ok := doc["primaryApiKey"]; ok {
+				var key string
+				if json.Unmarshal(raw, &key) == nil {
+					apiKey = key
+				}
+			}
+		}
+	}
+
+	if oauthRaw != nil {
+		var creds OAuthCredentials
+		if err := json.Unmarshal(oauthRaw, &creds); err != nil {
+			return nil, fmt.Errorf("failed to parse OAuth credentials: %w", err)
+		}
+		creds.ExpiresAt = fromLocalClock(creds.ExpiresAt)
+
+		var account json.RawMessage
+		data, err := readFile(claudePath)
+		if err == nil {
+			var doc map[string]json.RawMessage
+			if json.Unmarshal(data, &doc) == nil {
+				account = doc["oauthAccount"]
+			}
+		}
+
+		return &Profile{
+			Type:        "oauth",
+			Credentials: &creds,
+			Account:     account,
+		}, nil
+	}
+
+	if apiKey != "" {
+		return &Profile{
+			Type:   "api_key",
+			ApiKey: apiKey,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no credentials found")
+}
+
+func reauthenticateProfile(name string) (*Profile, error) {
+	fmt.Fprintf(os.Stderr, "Refresh token expired for profile '%s'. Please re-authenticate...\n", name)
+
+	profile, err := loadProfile(name)
+	if err != nil {
+		profile = &Profile{}
+	}
+
+	imported, err := oauthLogin(profile.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("re-authentication failed: %w", err)
+	}
+
+	before, after := profile.AccountInfo("accountUuid"), imported.AccountInfo("accountUuid")
+	if before != "" && after != "" && before != after {
+		fmt.Fprintf(os.Stderr, "Warning: logged in as %s, which is a different account than profile '%s' had.\n", imported.DisplayEmail(), name)
+	}
+	profile.ReplaceCredentials(imported)
+	if err := saveProfile(name, profile); err != nil {
+		return nil, err
+	}
+
+	printProfileSaved("re-authenticated", name, profile)
+	return profile, nil
+}
+
+// claudeLogin prepares Claude Code's interactive login, which writes fresh
+// credentials into Claude's config for importCurrentCredentials to pick up.
+// The binary is resolved up front so callers can fail before clearing the
+// live session.
+// bin, from --claude-bin, overrides the configured binary; the arguments
+// come from login_args in config.toml.
+func claudeLogin(name, bin string) (func() error, error) {
+	if sandboxed() {
+		return sandboxLogin, nil
+	}
+	var claude string
+	var err error
+	if bin != "" {
+		if claude, err = exec.LookPath(expandHome(bin)); err != nil {
+			return nil, &cliError{Code: errClaudeFailed, Message: fmt.Sprintf("--claude-bin is not executable: %v", err), Profile: name, Err: err}
+		}
+	} else if claude, err = resolveClaude(name); err != nil {
+		return nil, err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	args := cfg.Profiles[name].LoginArgs
+	if args == nil {
+		args = cfg.Defaults.LoginArgs
+	}
+	if args == nil {
+		args = []string{"/login"}
+	}
+	return func() error {
+		cmd := exec.Command(claude, args...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}, nil
+}
+
+// reportProfileSaved reports the outcome of a command that created or
+// replaced a profile.
+func reportProfileSaved(action, name string, profile *Profile) error {
+	if jsonOutput() {
+		return printJSON(summarizeProfile(name, profile))
+	}
+	printProfileSaved(action, name, profile)
+	return nil
+}
+
+func printProfileSaved(action, name string, profile *Profile) {
+	label := strings.ToUpper(action[:1]) + action[1:]
+	if profile.Type == "oauth" {
+		email := profile.DisplayEmail()
+		infof("%s profile '%s' (%s)\n", label, name, email)
+	} else {
+		infof("%s profile '%s' (%s)\n", label, name, profile.DescribeType())
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func isUsageError(err error) bool {
+	var ce *cliError
+	return errors.As(err, &ce) && ce.Code == errUsage
+}
+
+func TestParseUseFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		sessions string
+		refresh  string
+		fzf      bool
+		rest     []string
+	}{
+		{[]string{"work"}, sessionsAsk, refreshWhenExpired, false, []string{"work"}},
+		{[]string{"--refresh", "work"}, sessionsAsk, refreshAlways, false, []string{"work"}},
+		{[]string{"work", "--no-refresh"}, sessionsAsk, refreshNever, false, []string{"work"}},
+		{[]string{"--refresh", "work", "--refresh"}, sessionsAsk, refreshAlways, false, []string{"work"}},
+		{[]string{"--no-refresh", "--no-refresh", "work"}, sessionsAsk, refreshNever, false, []string{"work"}},
+		{[]string{"-k", "work"}, sessionsKill, refreshWhenExpired, false, []string{"work"}},
+		{[]string{"--wait", "work"}, sessionsWait, refreshWhenExpired, false, []string{"work"}},
+		{[]string{"--force", "--fzf", "wo"}, sessionsForce, refreshWhenExpired, true, []string{"wo"}},
+		{nil, sessionsAsk, refreshWhenExpired, false, nil},
+	}
+	for _, tt := range tests {
+		sessions, refresh, fzf, rest, err := parseUseFlags(tt.args)
+		if err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if sessions != tt.sessions || refresh != tt.refresh || fzf != tt.fzf || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("%v: got %q %q %v %q, want %q %q %v %q", tt.args, sessions, refresh, fzf, rest, tt.sessions, tt.refresh, tt.fzf, tt.rest)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--refresh", "--no-refresh", "work"},
+		{"--no-refresh", "work", "--refresh"},
+	} {
+		if _, _, _, _, err := parseUseFlags(args); !isUsageError(err) {
+			t.Errorf("%v: %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestParseExecArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want execOptions
+	}{
+		{[]string{"work", "--", "claude", "-p", "hi"}, execOptions{name: "work", command: []string{"claude", "-p", "hi"}}},
+		{[]string{"work", "claude"}, execOptions{name: "work", command: []string{"claude"}}},
+		{[]string{"--refresh", "work", "--isolated", "--", "env"}, execOptions{name: "work", refresh: refreshAlways, isolated: true, command: []string{"env"}}},
+		{[]string{"--refresh", "work", "--refresh", "--", "env"}, execOptions{name: "work", refresh: refreshAlways, command: []string{"env"}}},
+		{[]string{"--", "env", "--refresh"}, execOptions{command: []string{"env", "--refresh"}}},
+		{[]string{"--refresh", "--", "env"}, execOptions{refresh: refreshAlways, command: []string{"env"}}},
+		{[]string{"--chain", "main", "--", "env"}, execOptions{chain: "main", command: []string{"env"}}},
+		{[]string{"--group=team", "--", "env"}, execOptions{group: "team", command: []string{"env"}}},
+		{[]string{"-g", "team", "--", "env"}, execOptions{group: "team", command: []string{"env"}}},
+		{[]string{"--pool", "team", "--isolated", "--", "env"}, execOptions{pool: "team", isolated: true, command: []string{"env"}}},
+		{[]string{"--failover", "team", "--", "env"}, execOptions{failover: "team", command: []string{"env"}}},
+	}
+	for _, tt := range tests {
+		got, err := parseExecArgs(tt.args)
+		if err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%v: got %+v, want %+v", tt.args, *got, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"--isolated"},
+		{"work"},
+		{"work", "--"},
+		{"--chain"},
+		{"--refresh", "--group", "team", "--", "env"},
+		{"--pool", "team", "--refresh", "--", "env"},
+		{"--refresh", "--failover", "team", "--", "env"},
+		{"--isolated", "--failover", "team", "--", "env"},
+	} {
+		if _, err := parseExecArgs(args); !isUsageError(err) {
+			t.Errorf("%v: %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_SANDBOX", "1")
+	t.Setenv("CLAUDE_SWITCH_SANDBOX_DIR", t.TempDir())
+	saved := os.Args
+	t.Cleanup(func() {
+		os.Args = saved
+		outputFormat, colorMode, quiet, verbose, exactNames = "", "auto", false, false, false
+	})
+
+	tests := []struct {
+		args  []string
+		want  []string
+		json  bool
+		quiet bool
+	}{
+		{[]string{"list"}, []string{"list"}, false, false},
+		{[]string{"--json", "-q", "list"}, []string{"list"}, true, true},
+		{[]string{"-o", "json", "--verbose", "use", "work"}, []string{"use", "work"}, true, false},
+		{[]string{"use", "work", "--json"}, []string{"use", "work", "--json"}, false, false},
+		{[]string{"exec", "work", "sh", "-c", "echo", "x", "-q", "--verbose", "a"}, []string{"exec", "work", "sh", "-c", "echo", "x", "-q", "--verbose", "a"}, false, false},
+		{[]string{"-q", "exec", "work", "--", "tool", "--json"}, []string{"exec", "work", "--", "tool", "--json"}, false, true},
+	}
+	for _, tt := range tests {
+		outputFormat, quiet = "", false
+		os.Args = append([]string{"claude-switch"}, tt.args...)
+		if err := parseGlobalFlags(); err != nil {
+			t.Errorf("%v: %v", tt.args, err)
+			continue
+		}
+		if got := os.Args[1:]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: args = %q, want %q", tt.args, got, tt.want)
+		}
+		if jsonOutput() != tt.json || quiet != tt.quiet {
+			t.Errorf("%v: json = %v, quiet = %v; want %v, %v", tt.args, jsonOutput(), quiet, tt.json, tt.quiet)
+		}
+	}
+}
+
+func TestRefreshProfileUsesSavedCopy(t *testing.T) {
+	t.Setenv("CLAUDE_SWITCH_SANDBOX", "1")
+	t.Setenv("CLAUDE_SWITCH_SANDBOX_DIR", t.TempDir())
+	if err := seedSandbox(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The caller's copy predates a rotation by another process, whose new
+	// token has expired too, and a label set since.
+	stale, err := loadProfile("personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staleCreds := *stale.Credentials
+	staleCreds.RefreshToken = sandboxRevokedToken
+	stale.Credentials = &staleCreds
+	saved, err := loadProfile("personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := "rotated elsewhere"
+	saved.Label = &label
+	if err := saveProfile("personal", saved); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := refreshProfile("personal", stale, false)
+	if err != nil {
+		t.Fatalf("refreshProfile: %v", err)
+	}
+	if refreshed.Label == nil || *refreshed.Label != label {
+		t.Errorf("label = %v, want %q", refreshed.Label, label)
+	}
+	if isExpired(refreshed.Credentials) {
+		t.Error("token wasn't refreshed")
+	}
+	after, err := loadProfile("personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Label == nil || *after.Label != label || after.Credentials.AccessToken != refreshed.Credentials.AccessToken {
+		t.Error("the refreshed profile wasn't saved over the latest copy")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Manual token entry (add --manual) ---
+
+// cmdAddManual builds an OAuth profile from tokens pasted from another
+// machine, for hosts where neither a browser nor the claude CLI is usable.
+// The live Claude session is left alone.
+func cmdAddManual(name, store string, verify bool) error {
+	access, err := readSecret("Access token: ")
+	if err != nil {
+		return err
+	}
+	if err := checkTokenShape("access token", access, "sk-ant-oat"); err != nil {
+		return err
+	}
+	refresh, err := readSecret("Refresh token: ")
+	if err != nil {
+		return err
+	}
+	if err := checkTokenShape("refresh token", refresh, "sk-ant-ort"); err != nil {
+		return err
+	}
+	expiry, err := readLine("Expires at (RFC 3339, Unix ms, or a duration such as 8h): ")
+	if err != nil {
+		return err
+	}
+	expiresAt, err := parseExpiry(expiry)
+	if err != nil {
+		return err
+	}
+
+	profile := &Profile{
+		Type:  "oauth",
+		Store: store,
+		Credentials: &OAuthCredentials{
+			AccessToken:  access,
+			RefreshToken: refresh,
+			ExpiresAt:    expiresAt,
+			Scopes:       strings.Fields(scopes),
+		},
+	}
+
+	if verify {
+		identity, err := fetchOAuthIdentity(access, nil)
+		switch {
+		case err == nil:
+			profile.Account = identity.Account
+			profile.Credentials.SubscriptionType = identity.SubscriptionType
+			profile.Credentials.RateLimitTier = identity.RateLimitTier
+		case classifyError(err).Code == errTokenRejected:
+			if expiresAt > nowMs() {
+				return err
+			}
+			// An expired access token is expected; the refresh token is
+			// what matters and gets exercised on first use.
+			fmt.Fprintln(os.Stderr, "Warning: access token has expired; it will be refreshed on first use.")
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: couldn't verify the token (%v); saving it unverified.\n", err)
+		}
+	}
+
+	saved, profile, err := saveNewProfile(name, profile)
+	if err != nil {
+		return err
+	}
+	return reportSaved("Saved", name, saved, profile)
+}
+
+// cmdAddAPIKey saves an API key as a profile. There is nothing to log in
+// to, so the live session is left alone; an empty key is read from stdin.
+func cmdAddAPIKey(name, store, key string) error {
+	if key == "" {
+		var err error
+		if key, err = readSecret("API key: "); err != nil {
+			return err
+		}
+	}
+	if key == "" || strings.ContainsAny(key, " \t\r\n") {
+		return usageError("expected a single API key")
+	}
+	if !strings.HasPrefix(key, "sk-ant-api") {
+		fmt.Fprintln(os.Stderr, "Warning: this doesn't look like an Anthropic API key (sk-ant-api...); saving it anyway.")
+	}
+	profile := &Profile{Type: "api_key", ApiKey: key, Store: store}
+	saved, profile, err := saveNewProfile(name, profile)
+	if err != nil {
+		return err
+	}
+	return reportSaved("Saved", name, saved, profile)
+}
+
+func checkTokenShape(what, token, prefix string) error {
+	if token == "" {
+		return usageError("no %s given", what)
+	}
+	if !strings.HasPrefix(token, prefix) || strings.ContainsAny(token, " \t\r\n") {
+		return usageError("that doesn't look like an OAuth %s (expected it to start with %s)", what, prefix)
+	}
+	return nil
+}
+
+// parseExpiry accepts an RFC 3339 timestamp, Unix time in ms (or seconds),
+// or a duration from now, and returns Unix ms.
+func parseExpiry(s string) (uint64, error) {
+	if s == "" {
+		return 0, usageError("no expiry given")
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if n < 1e12 {
+			n *= 1000
+		}
+		return n, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return uint64(t.UnixMilli()), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return nowMs() + uint64(d.Milliseconds()), nil
+	}
+	return 0, usageError("can't parse expiry '%s'", s)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// --- Profile name matching ---
+
+// A name given on the command line that isn't a profile is taken as the
+// start of one when exactly one profile begins with it, so `use wo` picks
+// work-eu. Otherwise the error suggests profiles with a similar name.
+
+// exactNames turns prefix matching off; set by the global --exact flag.
+var exactNames bool
+
+// resolveProfileName returns the profile name refers to. allowPrefix is
+// false for commands that destroy a profile, which only get suggestions.
+func resolveProfileName(name string, allowPrefix bool) (string, error) {
+	if validateProfileName(name) != nil {
+		// Left for loading the profile to report.
+		return name, nil
+	}
+	names, err := listProfiles()
+	if err != nil || slices.Contains(names, name) {
+		return name, nil
+	}
+	if exactNames {
+		return "", notFoundError(name)
+	}
+
+	var matches []string
+	for _, n := range names {
+		if strings.HasPrefix(strings.ToLower(n), strings.ToLower(name)) {
+			matches = append(matches, n)
+		}
+	}
+	switch {
+	case len(matches) == 1 && allowPrefix:
+		infof("Using profile '%s' for '%s'\n", matches[0], name)
+		return matches[0], nil
+	case len(matches) > 1:
+		return "", &cliError{
+			Code:    errAmbiguousName,
+			Message: fmt.Sprintf("'%s' matches several profiles: %s", name, strings.Join(matches, ", ")),
+			Profile: name,
+			Hint:    "give more of the name",
+		}
+	case len(matches) == 0:
+		matches = similarNames(name, names)
+	}
+	if len(matches) == 0 {
+		return "", notFoundError(name)
+	}
+	return "", &cliError{
+		Code:    errProfileNotFound,
+		Message: fmt.Sprintf("profile '%s' not found", name),
+		Profile: name,
+		Hint:    "did you mean " + quoteNames(matches) + "?",
+	}
+}
+
+// similarNames returns the names within a few typos of name, closest first.
+func similarNames(name string, names []string) []string {
+	// Allow one edit for short names and about one per four characters
+	// beyond that, so "wrok" finds work but "a" doesn't find everything.
+	limit := max(1, len(name)/4)
+	var similar []string
+	distances := map[string]int{}
+	for _, n := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(n)); d <= limit {
+			similar = append(similar, n)
+			distances[n] = d
+		}
+	}
+	slices.SortStableFunc(similar, func(a, b string) int { return distances[a] - distances[b] })
+	return similar
+}
+
+// editDistance is the Damerau-Levenshtein (optimal string alignment)
+// distance, so a swapped pair of letters counts as one edit.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// quoteNames renders names as "'a'", "'a' or 'b'" or "'a', 'b' or 'c'".
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = "'" + n + "'"
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
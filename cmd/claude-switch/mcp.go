@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// --- Per-profile MCP servers (mcp <name>) ---
+
+// A profile can carry its own mcpServers block, the user-scope MCP servers
+// in Claude's .claude.json, which `use` swaps in like the profile's
+// settings files (see claudesettings.go): changes Claude makes while the
+// profile is active are saved back to it on the next switch, and the
+// servers of profiles without their own are set aside in shared-mcp.json
+// meanwhile. `exec --isolated` writes the profile's servers into its own
+// .claude.json. The block is stored in the profile, so `encrypt enable`
+// covers any secrets among the servers' variables and headers.
+
+func sharedMCPPath() string {
+	return filepath.Join(configDir(), "shared-mcp.json")
+}
+
+// sharedMCP is the form of shared-mcp.json; a nil Servers means Claude's
+// config had no mcpServers block.
+type sharedMCP struct {
+	Servers json.RawMessage `json:"mcpServers,omitempty"`
+}
+
+// parseMCPServers checks an mcpServers block, given either as the block
+// itself or as a file holding one under "mcpServers", such as .mcp.json.
+func parseMCPServers(data []byte) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("not a JSON object: %w", err)
+	}
+	if servers, ok := doc["mcpServers"]; ok {
+		doc = nil
+		if err := json.Unmarshal(servers, &doc); err != nil {
+			return nil, fmt.Errorf("mcpServers is not an object: %w", err)
+		}
+	}
+	for name, server := range doc {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(server, &fields) != nil {
+			return nil, fmt.Errorf("server '%s' is not an object", name)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// readMCPServers returns the mcpServers block of Claude's config, or nil.
+func readMCPServers() json.RawMessage {
+	return readJSONDoc(claudeJSONPath())["mcpServers"]
+}
+
+func writeMCPServers(servers json.RawMessage) error {
+	return claudeSwitcher().SetKeys(claudeJSONPath(),
+		map[string]json.RawMessage{"mcpServers": servers})
+}
+
+// swapMCPServers puts the MCP servers of profile, about to become active as
+// name, in place of those of the active profile, saving the block in place
+// to wherever it came from first.
+func swapMCPServers(name string, profile *Profile) error {
+	from := ""
+	if state := loadState(); state.ActiveProfile != nil {
+		from = *state.ActiveProfile
+	}
+	if from == name {
+		return nil
+	}
+	outgoing, err := loadProfileIf(from, func(p *Profile) bool { return p.MCPServers != nil })
+	if err != nil {
+		return err
+	}
+	if outgoing == nil && profile.MCPServers == nil {
+		return nil
+	}
+
+	current := readMCPServers()
+	if outgoing != nil {
+		outgoing.MCPServers = orEmptyServers(current)
+		if err := saveProfile(from, outgoing); err != nil {
+			return fmt.Errorf("failed to save the MCP servers to '%s': %w", from, err)
+		}
+	} else if err := saveSharedMCP(current); err != nil {
+		return err
+	}
+
+	incoming := profile.MCPServers
+	if incoming == nil {
+		shared, err := loadSharedMCP()
+		if err != nil || shared == nil {
+			return err
+		}
+		incoming = shared.Servers
+	}
+	if err := writeMCPServers(incoming); err != nil {
+		return err
+	}
+	if profile.MCPServers == nil {
+		os.Remove(sharedMCPPath())
+	}
+	debugf("mcp: switched MCP servers from '%s' to '%s'", from, name)
+	return nil
+}
+
+// orEmptyServers stands in an empty block for a missing one, since a
+// profile's nil block means it shares the servers.
+func orEmptyServers(servers json.RawMessage) json.RawMessage {
+	if servers == nil {
+		return json.RawMessage("{}")
+	}
+	return servers
+}
+
+func loadSharedMCP() (*sharedMCP, error) {
+	data, err := readFile(sharedMCPPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var shared sharedMCP
+	if err := json.Unmarshal(data, &shared); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", sharedMCPPath(), err)
+	}
+	return &shared, nil
+}
+
+func saveSharedMCP(servers json.RawMessage) error {
+	data, err := json.MarshalIndent(sharedMCP{servers}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSecure(sharedMCPPath(), data)
+}
+
+func cmdMCP(args []string) error {
+	var name, fromFile string
+	capture, clear := false, false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--capture":
+			capture = true
+		case a == "--clear":
+			clear = true
+		case a == "--from-file":
+			if i+1 >= len(args) {
+				return usageError("%s requires a file (or - for stdin)", a)
+			}
+			i++
+			fromFile = args[i]
+		case strings.HasPrefix(a, "--from-file="):
+			fromFile = strings.TrimPrefix(a, "--from-file=")
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("mcp requires a profile name")
+	}
+	if (capture && clear) || (fromFile != "" && (capture || clear)) {
+		return usageError("--capture, --from-file and --clear can't be combined")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	if capture || clear || fromFile != "" {
+		unlock, err := lockProfileUpdate(name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+
+	state := loadState()
+	active := state.ActiveProfile != nil && *state.ActiveProfile == name
+	switch {
+	case capture:
+		current := readMCPServers()
+		// The block in place was the shared one; it comes back for
+		// profiles without servers of their own.
+		if active && profile.MCPServers == nil {
+			if err := saveSharedMCP(current); err != nil {
+				return err
+			}
+		}
+		profile.MCPServers = orEmptyServers(current)
+	case fromFile != "":
+		var data []byte
+		if fromFile == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = readFile(expandHome(fromFile))
+		}
+		if err != nil {
+			return err
+		}
+		servers, err := parseMCPServers(data)
+		if err != nil {
+			return usageError("invalid MCP server config: %v", err)
+		}
+		if active && profile.MCPServers == nil {
+			if err := saveSharedMCP(readMCPServers()); err != nil {
+				return err
+			}
+		}
+		profile.MCPServers = servers
+	case clear:
+		if profile.MCPServers == nil {
+			break
+		}
+		profile.MCPServers = nil
+		if active {
+			os.Remove(sharedMCPPath())
+		}
+	default:
+		return printMCPServers(name, profile.MCPServers)
+	}
+	if err := saveProfile(name, profile); err != nil {
+		return err
+	}
+	// The active profile's servers take effect right away.
+	if active && fromFile != "" {
+		if err := writeMCPServers(profile.MCPServers); err != nil {
+			return err
+		}
+	}
+	if jsonOutput() {
+		return printMCPServers(name, profile.MCPServers)
+	}
+	switch {
+	case clear:
+		infof("Cleared the MCP servers of '%s'; it uses the shared ones from now on.\n", name)
+	case active:
+		infof("Saved %d MCP server(s) for '%s'.\n", len(mcpServerNames(profile.MCPServers)), name)
+	default:
+		infof("Saved %d MCP server(s) for '%s'; 'claude-switch use %s' puts them in place.\n", len(mcpServerNames(profile.MCPServers)), name, name)
+	}
+	return nil
+}
+
+// mcpServerNames returns the names of the servers in a block, sorted.
+func mcpServerNames(servers json.RawMessage) []string {
+	var doc map[string]json.RawMessage
+	json.Unmarshal(servers, &doc)
+	return slices.Sorted(maps.Keys(doc))
+}
+
+// printMCPServers lists a profile's servers with what they run or connect
+// to; their variables and headers may be secrets and aren't shown.
+func printMCPServers(name string, servers json.RawMessage) error {
+	names := mcpServerNames(servers)
+	if jsonOutput() {
+		return printJSON(map[string]any{"profile": name, "own": servers != nil, "servers": append([]string{}, names...)})
+	}
+	if servers == nil {
+		infof("'%s' uses the shared MCP servers; 'claude-switch mcp %s --capture' gives it its own.\n", name, name)
+		return nil
+	}
+	if len(names) == 0 {
+		infof("'%s' has no MCP servers.\n", name)
+		return nil
+	}
+	var doc map[string]struct {
+		Command string   `json:"command"`
+		Args    []string `json:"args"`
+		URL     string   `json:"url"`
+	}
+	json.Unmarshal(servers, &doc)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, server := range names {
+		target := doc[server].URL
+		if target == "" {
+			target = strings.Join(append([]string{doc[server].Command}, doc[server].Args...), " ")
+		}
+		fmt.Fprintf(w, "%s\t%s\n", server, target)
+	}
+	return w.Flush()
+}
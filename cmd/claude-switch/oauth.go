@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// The OAuth client. config.toml's [oauth] table and CLAUDE_SWITCH_OAUTH_*
+// variables can point it elsewhere; see applyOAuthConfig.
+var (
+	clientID = claudeswitch.DefaultClientID
+	tokenURL = claudeswitch.DefaultTokenURL
+	// authorizeURL is the Claude.ai (subscription) login; console accounts
+	// still go through `add --claude`.
+	authorizeURL = "https://claude.ai/oauth/authorize"
+	scopes       = claudeswitch.DefaultScopes
+)
+
+const (
+	// manualRedirectURL shows the authorization code on a page for the user
+	// to paste back, for machines the browser can't reach.
+	manualRedirectURL = "https://platform.claude.com/oauth/code/callback"
+
+	profileURL = "https://api.anthropic.com/api/oauth/profile"
+
+	// Rate limits asking for at most this long are waited out in-process;
+	// longer ones are recorded as a cooldown in state.json.
+	maxInlineRetryWait = 10 * time.Second
+
+	// Offsets below this are indistinguishable from request latency and the
+	// one-second resolution of the Date header.
+	skewNoiseFloor = 2 * time.Second
+	// Offsets above this are worth telling the user about.
+	significantSkew = 2 * time.Minute
+
+	// How long to wait for the browser to come back to the callback listener.
+	loginTimeout = 5 * time.Minute
+
+	// A refresh gives up after refreshTimeout, so a hung proxy can't hang
+	// `use`. Transient failures are retried refreshRetries times, waiting
+	// refreshBackoff and then twice as long each time.
+	refreshTimeout = 30 * time.Second
+	refreshRetries = 3
+	refreshBackoff = time.Second
+)
+
+// Refresh failures are claudeswitch.RefreshError; the CLI adds retries and
+// the cooldown after a 429 around claudeswitch.Refresher.
+type RefreshError = claudeswitch.RefreshError
+
+const (
+	refreshInvalidGrant = claudeswitch.RefreshInvalidGrant
+	refreshRateLimited  = claudeswitch.RefreshRateLimited
+	refreshOther        = claudeswitch.RefreshOther
+)
+
+var rateLimitedError = claudeswitch.RateLimitedError
+
+// refreshToken exchanges a refresh token for fresh credentials, honouring
+// any cooldown recorded from an earlier 429 so we don't hammer the endpoint.
+func refreshToken(creds *OAuthCredentials, proxy *ProxySettings) (refreshed *OAuthCredentials, err error) {
+	debugf("refresh: using refresh token %s", maskSecret(creds.RefreshToken))
+	defer func() {
+		if err != nil {
+			debugf("refresh: failed: %v", err)
+		} else {
+			debugf("refresh: got access token %s, expires %s", maskSecret(refreshed.AccessToken), time.UnixMilli(int64(refreshed.ExpiresAt)).UTC().Format(time.RFC3339))
+		}
+	}()
+	if sandboxed() {
+		return sandboxRefresh(creds)
+	}
+	state := loadState()
+	if now := nowMs(); state.RefreshCooldownUntil > now {
+		return nil, rateLimitedError(time.Duration(state.RefreshCooldownUntil-now) * time.Millisecond)
+	}
+
+	retries, waitedOut := 0, false
+	for {
+		refreshed, err = requestRefresh(creds, proxy)
+		if retries < refreshRetries && retryableRefresh(err) {
+			wait := refreshBackoff << retries
+			retries++
+			infof("Token refresh failed (%v), retrying in %s...\n", err, wait)
+			time.Sleep(wait)
+			continue
+		}
+		re, ok := err.(*RefreshError)
+		if !ok || re.Kind != refreshRateLimited {
+			return refreshed, err
+		}
+		if !waitedOut && re.RetryAfter <= maxInlineRetryWait {
+			infof("Token endpoint rate limited, retrying in %s...\n", re.RetryAfter)
+			time.Sleep(re.RetryAfter)
+			waitedOut = true
+			continue
+		}
+		until := nowMs() + uint64(re.RetryAfter.Milliseconds())
+		if err := updateState(func(state *State) { state.RefreshCooldownUntil = until }); err != nil {
+			return nil, err
+		}
+		return nil, re
+	}
+}
+
+// retryableRefresh reports whether a failed refresh is worth retrying: the
+// token endpoint answered with a server error, or it or the proxy couldn't be
+// connected to. Timeouts aren't retried. The server may have rotated the
+// refresh token before the response was lost, and the retry would then fail
+// with invalid_grant.
+func retryableRefresh(err error) bool {
+	var re *RefreshError
+	if errors.As(err, &re) {
+		return re.Kind == refreshOther && re.Status >= 500
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return false
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+func requestRefresh(creds *OAuthCredentials, proxy *ProxySettings) (*OAuthCredentials, error) {
+	refresher := &claudeswitch.Refresher{
+		ClientID:   clientID,
+		TokenURL:   tokenURL,
+		Scopes:     scopes,
+		Client:     &http.Client{Transport: proxyTransport(proxy), Timeout: refreshTimeout},
+		Now:        func() time.Time { return time.UnixMilli(int64(nowMs())) },
+		ServerDate: recordClockSkew,
+	}
+	return refresher.Refresh(context.Background(), creds)
+}
+
+// --- Built-in login (authorization code + PKCE) ---
+
+// oauthLogin runs the OAuth authorization-code flow with PKCE against a
+// one-shot callback listener on localhost and returns the resulting profile.
+// Unlike `claude /login` it never touches the live Claude session.
+func oauthLogin(proxy *ProxySettings) (*Profile, error) {
+	if sandboxed() {
+		return sandboxOAuthLogin()
+	}
+
+	pkce := newPKCE()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback listener: %w", err)
+	}
+	defer listener.Close()
+	redirectURI := fmt.Sprintf("http://localhost:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+
+	type callback struct {
+		code string
+		err  error
+	}
+	done := make(chan callback, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var result callback
+		switch {
+		case q.Get("state") != pkce.state:
+			result.err = errors.New("login callback had an unexpected state parameter")
+		case q.Get("error") != "":
+			result.err = fmt.Errorf("authorization was denied: %s", strings.TrimSpace(q.Get("error")+" "+q.Get("error_description")))
+		case q.Get("code") == "":
+			result.err = errors.New("login callback had no authorization code")
+		default:
+			result.code = q.Get("code")
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "<p>Login failed. Check the terminal for details.</p>")
+		} else {
+			fmt.Fprint(w, "<p>Login complete. You can close this tab and return to the terminal.</p>")
+		}
+		select {
+		case done <- result:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL := pkce.authorizationURL(redirectURI)
+	fmt.Fprintf(os.Stderr, "Opening your browser to log in. If it doesn't open, visit:\n\n  %s\n\n", authURL)
+	if err := openBrowser(authURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't open a browser: %v\n", err)
+	}
+
+	var result callback
+	select {
+	case result = <-done:
+	case <-time.After(loginTimeout):
+		return nil, fmt.Errorf("timed out after %s waiting for the browser login", loginTimeout)
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	return exchangeCode(result.code, pkce, redirectURI, proxy)
+}
+
+// oauthLoginHeadless is the same flow for machines without a browser: the
+// URL is opened on any other device, and the code shown after approving is
+// pasted back here. The code page is the only redirect the OAuth client
+// accepts besides localhost, so there is nothing to poll.
+func oauthLoginHeadless(proxy *ProxySettings) (*Profile, error) {
+	if sandboxed() {
+		return sandboxOAuthLogin()
+	}
+
+	pkce := newPKCE()
+	fmt.Fprintf(os.Stderr, "Open this URL in a browser on any device and log in:\n\n  %s\n\n", pkce.authorizationURL(manualRedirectURL))
+	pasted, err := readLine("Paste the code shown after login: ")
+	if err != nil {
+		return nil, err
+	}
+	if pasted == "" {
+		return nil, usageError("no code given")
+	}
+	// The page shows "<code>#<state>"; a bare code is accepted too.
+	code, state, hasState := strings.Cut(pasted, "#")
+	if hasState && state != pkce.state {
+		return nil, errors.New("the pasted code belongs to a different login attempt")
+	}
+	return exchangeCode(code, pkce, manualRedirectURL, proxy)
+}
+
+// pkceParams holds one login attempt's PKCE verifier and state.
+type pkceParams struct {
+	verifier  string
+	challenge string
+	state     string
+}
+
+func newPKCE() pkceParams {
+	verifier := randomURLToken(32)
+	sum := sha256.Sum256([]byte(verifier))
+	return pkceParams{
+		verifier:  verifier,
+		challenge: base64.RawURLEncoding.EncodeToString(sum[:]),
+		state:     randomURLToken(32),
+	}
+}
+
+func (p pkceParams) authorizationURL(redirectURI string) string {
+	return authorizeURL + "?" + url.Values{
+		"code":                  {"true"},
+		"client_id":             {clientID},
+		"response_type":         {"code"},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {scopes},
+		"code_challenge":        {p.challenge},
+		"code_challenge_method": {"S256"},
+		"state":                 {p.state},
+	}.Encode()
+}
+
+// exchangeCode trades an authorization code for tokens and builds a profile
+// from them, filling in the plan from the profile endpoint when it answers.
+func exchangeCode(code string, pkce pkceParams, redirectURI string, proxy *ProxySettings) (*Profile, error) {
+	reqBody, err := json.Marshal(map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"redirect_uri":  redirectURI,
+		"client_id":     clientID,
+		"code_verifier": pkce.verifier,
+		"state":         pkce.state,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", tokenURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+
+	sent := time.Now()
+	client := &http.Client{Transport: proxyTransport(proxy), Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	recordClockSkew(resp.Header.Get("Date"), sent, time.Now())
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, rateLimitedError(claudeswitch.ParseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("token exchange failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    uint64 `json:"expires_in"`
+		Scope        string `json:"scope"`
+		Account      struct {
+			UUID  string `json:"uuid"`
+			Email string `json:"email_address"`
+		} `json:"account"`
+		Organization struct {
+			UUID string `json:"uuid"`
+			Name string `json:"name"`
+		} `json:"organization"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("missing access_token in token response")
+	}
+	if result.ExpiresIn == 0 {
+		result.ExpiresIn = 3600
+	}
+	granted := strings.Fields(result.Scope)
+	if len(granted) == 0 {
+		granted = strings.Fields(scopes)
+	}
+
+	profile := &Profile{
+		Type: "oauth",
+		Credentials: &OAuthCredentials{
+			AccessToken:  result.AccessToken,
+			RefreshToken: result.RefreshToken,
+			ExpiresAt:    nowMs() + result.ExpiresIn*1000,
+			Scopes:       granted,
+		},
+	}
+	profile.Account, err = json.Marshal(map[string]string{
+		"accountUuid":      result.Account.UUID,
+		"emailAddress":     result.Account.Email,
+		"organizationUuid": result.Organization.UUID,
+		"organizationName": result.Organization.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := fetchOAuthIdentity(result.AccessToken, proxy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't look up the account's plan: %v\n", err)
+		return profile, nil
+	}
+	profile.Account = identity.Account
+	profile.Credentials.SubscriptionType = identity.SubscriptionType
+	profile.Credentials.RateLimitTier = identity.RateLimitTier
+	return profile, nil
+}
+
+func randomURLToken(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// OAuthIdentity is what the profile endpoint reports about an access token,
+// with the account already in the shape of Claude's oauthAccount block.
+type OAuthIdentity struct {
+	Account          json.RawMessage
+	SubscriptionType *string
+	RateLimitTier    *string
+}
+
+// fetchOAuthIdentity asks the API who an access token belongs to. A token
+// the API rejects is reported as a token_rejected cliError, so callers can
+// tell it apart from the API simply being unreachable.
+func fetchOAuthIdentity(accessToken string, proxy *ProxySettings) (*OAuthIdentity, error) {
+	if sandboxed() {
+		return sandboxIdentity(accessToken)
+	}
+	req, err := http.NewRequest("GET", profileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+
+	client := &http.Client{Transport: proxyTransport(proxy), Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, &cliError{Code: errTokenRejected, Message: fmt.Sprintf("access token was rejected (%d)", resp.StatusCode)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("profile lookup failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Account struct {
+			UUID        string `json:"uuid"`
+			Email       string `json:"email"`
+			DisplayName string `json:"display_name"`
+		} `json:"account"`
+		Organization struct {
+			UUID             string `json:"uuid"`
+			Name             string `json:"name"`
+			OrganizationType string `json:"organization_type"`
+			RateLimitTier    string `json:"rate_limit_tier"`
+		} `json:"organization"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	account, err := json.Marshal(map[string]string{
+		"accountUuid":      result.Account.UUID,
+		"emailAddress":     result.Account.Email,
+		"displayName":      result.Account.DisplayName,
+		"organizationUuid": result.Organization.UUID,
+		"organizationName": result.Organization.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	identity := &OAuthIdentity{Account: account}
+	if sub, ok := strings.CutPrefix(result.Organization.OrganizationType, "claude_"); ok {
+		identity.SubscriptionType = &sub
+	}
+	if tier := result.Organization.RateLimitTier; tier != "" {
+		identity.RateLimitTier = &tier
+	}
+	return identity, nil
+}
+
+func isExpired(creds *OAuthCredentials) bool {
+	// Consider expired if within the refresh buffer (5 minutes by default)
+	bufferMs := uint64(defaults.refreshBuffer.Milliseconds())
+	return nowMs()+bufferMs >= creds.ExpiresAt
+}
+
+// nowMs returns the current time in Unix ms on the token server's clock,
+// i.e. the local clock corrected by the last measured skew.
+func nowMs() uint64 {
+	return uint64(time.Now().UnixMilli() + clockSkewMs())
+}
+
+// --- Clock skew ---
+
+var cachedSkewMs *int64
+
+func clockSkewMs() int64 {
+	if cachedSkewMs == nil {
+		skew := loadState().ClockSkewMs
+		cachedSkewMs = &skew
+	}
+	return *cachedSkewMs
+}
+
+// recordClockSkew compares a response's Date header with the midpoint of the
+// request's round trip and stores the offset for later expiry checks.
+func recordClockSkew(dateHeader string, sent, received time.Time) {
+	serverTime, err := http.ParseTime(dateHeader)
+	if err != nil {
+		return
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	skew := serverTime.Sub(local)
+	if skew.Abs() < skewNoiseFloor {
+		skew = 0
+	}
+	skewMs := skew.Milliseconds()
+	if skewMs == clockSkewMs() {
+		return
+	}
+	if skew.Abs() >= significantSkew {
+		fmt.Fprintf(os.Stderr, "Warning: local clock differs from the token server by %s; adjusting expiry checks.\n", skew.Round(time.Second))
+	}
+	cachedSkewMs = &skewMs
+	updateState(func(state *State) { state.ClockSkewMs = skewMs })
+}
+
+// Claude Code computes expiresAt from the local clock, while profiles keep
+// it on the server's clock so they stay correct if the local clock drifts or
+// the profile is moved to another machine. These convert at the boundary.
+
+func fromLocalClock(ms uint64) uint64 {
+	return uint64(int64(ms) + clockSkewMs())
+}
+
+func toLocalClock(creds *OAuthCredentials) *OAuthCredentials {
+	local := *creds
+	local.ExpiresAt = uint64(int64(creds.ExpiresAt) - clockSkewMs())
+	return &local
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryableRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &RefreshError{Kind: refreshOther, Status: 502}, true},
+		{"wrapped server error", fmt.Errorf("refresh: %w", &RefreshError{Kind: refreshOther, Status: 500}), true},
+		{"client error", &RefreshError{Kind: refreshOther, Status: 400}, false},
+		{"invalid grant", &RefreshError{Kind: refreshInvalidGrant, Status: 400}, false},
+		{"rate limited", &RefreshError{Kind: refreshRateLimited, Status: 429}, false},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"proxy unreachable", &net.OpError{Op: "proxyconnect", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"dial timeout", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, false},
+		{"read reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, false},
+		{"deadline", context.DeadlineExceeded, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := retryableRefresh(tt.err); got != tt.want {
+			t.Errorf("%s: retryableRefresh = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRecordClockSkew(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Cleanup(func() { cachedSkewMs = nil })
+
+	local := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header string
+		want   int64
+	}{
+		{"server ahead", local.Add(30 * time.Second).Format(http.TimeFormat), 30_000},
+		{"server behind", local.Add(-5 * time.Minute).Format(http.TimeFormat), -300_000},
+		{"within noise", local.Add(skewNoiseFloor - time.Second).Format(http.TimeFormat), 0},
+		{"at noise floor", local.Add(skewNoiseFloor).Format(http.TimeFormat), skewNoiseFloor.Milliseconds()},
+		{"unparsable keeps the last", "yesterday", skewNoiseFloor.Milliseconds()},
+	}
+	for _, tt := range tests {
+		// The round trip's midpoint is local.
+		recordClockSkew(tt.header, local.Add(-time.Second), local.Add(time.Second))
+		if got := clockSkewMs(); got != tt.want {
+			t.Errorf("%s: skew = %d, want %d", tt.name, got, tt.want)
+		}
+		cachedSkewMs = nil
+		if got := loadState().ClockSkewMs; got != tt.want {
+			t.Errorf("%s: saved skew = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
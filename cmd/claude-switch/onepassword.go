@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// --- 1Password references (--store op://vault/item) ---
+
+// A profile with a store reference keeps its secrets in a 1Password item
+// and only the non-secret fields on disk. The secrets are read through the
+// 1Password CLI whenever the profile is loaded and written back when it is
+// saved, e.g. after a token refresh.
+
+const opDefaultField = "credential"
+
+// profileSecrets is what gets stored in the 1Password item's field.
+type profileSecrets struct {
+	Credentials    *OAuthCredentials `json:"credentials,omitempty"`
+	ApiKey         string            `json:"api_key,omitempty"`
+	OAuthToken     string            `json:"oauth_token,omitempty"`
+	FallbackApiKey string            `json:"fallback_api_key,omitempty"`
+}
+
+func secretsOf(p *Profile) profileSecrets {
+	return profileSecrets{
+		Credentials:    p.Credentials,
+		ApiKey:         p.ApiKey,
+		OAuthToken:     p.OAuthToken,
+		FallbackApiKey: p.FallbackApiKey,
+	}
+}
+
+func hasSecrets(p *Profile) bool {
+	return secretsOf(p) != profileSecrets{}
+}
+
+func setSecrets(p *Profile, secrets profileSecrets) {
+	p.Credentials = secrets.Credentials
+	p.ApiKey = secrets.ApiKey
+	p.OAuthToken = secrets.OAuthToken
+	p.FallbackApiKey = secrets.FallbackApiKey
+}
+
+type opRef struct {
+	vault, item, field string
+}
+
+// parseOpRef accepts op://vault/item, storing into the item's "credential"
+// field, or op://vault/item/field.
+func parseOpRef(ref string) (opRef, error) {
+	rest, ok := strings.CutPrefix(ref, "op://")
+	parts := strings.Split(rest, "/")
+	if !ok || len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return opRef{}, usageError("invalid 1Password reference '%s' (expected op://vault/item or op://vault/item/field)", ref)
+	}
+	r := opRef{vault: parts[0], item: parts[1], field: opDefaultField}
+	if len(parts) == 3 && parts[2] != "" {
+		r.field = parts[2]
+	}
+	return r, nil
+}
+
+func (r opRef) String() string {
+	return fmt.Sprintf("op://%s/%s/%s", r.vault, r.item, r.field)
+}
+
+func opCommand(args ...string) (*exec.Cmd, error) {
+	op, err := exec.LookPath("op")
+	if err != nil {
+		return nil, &cliError{
+			Code:    errConfig,
+			Message: "the 1Password CLI (op) was not found on PATH",
+			Hint:    "install it from https://developer.1password.com/docs/cli/ and sign in with 'op signin'",
+			Err:     err,
+		}
+	}
+	return exec.Command(op, args...), nil
+}
+
+func runOp(args ...string) ([]byte, error) {
+	cmd, err := opCommand(args...)
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("op %s failed: %s", args[0], msg)
+	}
+	return out, nil
+}
+
+// loadExternalSecrets fills in the secrets of a profile read from storage.
+func loadExternalSecrets(name string, profile *Profile) error {
+	if item, ok := keychainItem(profile.Store); ok {
+		return loadKeychainSecrets(name, item, profile)
+	}
+	ref, err := parseOpRef(profile.Store)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if sandboxed() {
+		data, err = readFile(sandboxOpPath(ref))
+	} else {
+		data, err = runOp("read", "--no-newline", ref.String())
+	}
+	if err != nil {
+		return &cliError{Code: errNoCredentials, Message: fmt.Sprintf("failed to read secrets for '%s' from %s: %v", name, ref, err), Profile: name, Err: err}
+	}
+	var secrets profileSecrets
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return fmt.Errorf("%s doesn't hold claude-switch profile secrets: %w", ref, err)
+	}
+	setSecrets(profile, secrets)
+	return nil
+}
+
+// saveExternalSecrets writes a profile's secrets to its 1Password item or
+// keychain item, creating the item if it doesn't exist yet. A bare
+// "keychain" store is first given an item of its own.
+func saveExternalSecrets(name string, profile *Profile) error {
+	if profile.Store == keychainStore {
+		item, err := newKeychainItem(name)
+		if err != nil {
+			return err
+		}
+		profile.Store = keychainStore + ":" + item
+	}
+	if item, ok := keychainItem(profile.Store); ok {
+		return saveKeychainSecrets(name, item, profile)
+	}
+	ref, err := parseOpRef(profile.Store)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(secretsOf(profile))
+	if err != nil {
+		return err
+	}
+	if sandboxed() {
+		return writeSecure(sandboxOpPath(ref), data)
+	}
+
+	assignment := fmt.Sprintf("%s[password]=%s", ref.field, data)
+	if ref.field == opDefaultField {
+		assignment = fmt.Sprintf("%s=%s", ref.field, data)
+	}
+	if _, err := runOp("item", "get", ref.item, "--vault", ref.vault, "--format", "json"); err == nil {
+		_, err = runOp("item", "edit", ref.item, "--vault", ref.vault, assignment)
+		return err
+	}
+	_, err = runOp("item", "create", "--category", "API Credential", "--vault", ref.vault, "--title", ref.item, assignment)
+	return err
+}
+
+// sandboxOpPath stands in for a 1Password item field in sandbox mode.
+func sandboxOpPath(ref opRef) string {
+	return filepath.Join(sandboxDir(), "1password", ref.vault, ref.item, ref.field+".json")
+}
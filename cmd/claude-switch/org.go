@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- org: the organization of a multi-organization account ---
+
+// An account can belong to several organizations. A profile records the one
+// it works in, with the user's role there, in its oauthAccount block, and
+// the plan in its credentials. org lists the account's organizations and
+// moves a profile to another one, so one login can be pivoted between them
+// without a profile per organization.
+
+const organizationsURL = "https://api.anthropic.com/api/oauth/organizations"
+
+// Organization is one organization an account belongs to.
+type Organization struct {
+	UUID          string `json:"uuid"`
+	Name          string `json:"name"`
+	Plan          string `json:"plan,omitempty"`
+	RateLimitTier string `json:"rate_limit_tier,omitempty"`
+	Role          string `json:"role,omitempty"`
+	Current       bool   `json:"current"`
+}
+
+func cmdOrg(args []string) error {
+	if len(args) == 0 {
+		return usageError("org requires an action: list or use")
+	}
+	action, args := args[0], args[1:]
+	switch action {
+	case "list":
+		if len(args) != 1 {
+			return usageError("org list requires a profile name")
+		}
+		return listOrganizations(args[0])
+	case "use":
+		if len(args) != 2 {
+			return usageError("org use requires a profile name and an organization")
+		}
+		return useOrganization(args[0], args[1])
+	}
+	return usageError("unknown org action '%s' (expected list or use)", action)
+}
+
+// profileOrganizations returns the organizations of an OAuth profile's
+// account, refreshing its token first if needed.
+func profileOrganizations(name string) (string, *Profile, []Organization, error) {
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if profile.Type != "oauth" {
+		return "", nil, nil, usageError("'%s' isn't an OAuth profile; only those belong to organizations", name)
+	}
+	if profile, err = loadFreshProfile(name, false); err != nil {
+		return "", nil, nil, err
+	}
+	orgs, err := fetchOrganizations(profile)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	current := profile.AccountInfo("organizationUuid")
+	for i := range orgs {
+		orgs[i].Current = orgs[i].UUID == current
+	}
+	return name, profile, orgs, nil
+}
+
+func listOrganizations(name string) error {
+	_, _, orgs, err := profileOrganizations(name)
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(orgs)
+	}
+	if len(orgs) == 0 {
+		infof("The account belongs to no organizations.\n")
+		return nil
+	}
+	r := newRenderer(os.Stdout)
+	t := &table{}
+	t.add("", r.header("NAME"), r.header("UUID"), r.header("PLAN"), r.header("ROLE"))
+	for _, org := range orgs {
+		marker := ""
+		if org.Current {
+			marker = "*"
+		}
+		t.add(marker, org.Name, org.UUID, orDash(org.Plan), orDash(org.Role))
+	}
+	return t.write(os.Stdout)
+}
+
+// useOrganization moves a profile to another organization of its account.
+// The list is fetched fresh, so the role recorded is the current one. An
+// active profile's new organization is written to Claude's config as well.
+func useOrganization(name, query string) error {
+	name, profile, orgs, err := profileOrganizations(name)
+	if err != nil {
+		return err
+	}
+	org, err := matchOrganization(name, orgs, query)
+	if err != nil {
+		return err
+	}
+	// The refresh is done; the profile is read again under the lock in case
+	// another process has rotated its tokens since.
+	unlock, err := lockProfileUpdate(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if profile, err = loadProfile(name); err != nil {
+		return err
+	}
+
+	account := map[string]any{}
+	if len(profile.Account) > 0 {
+		if err := json.Unmarshal(profile.Account, &account); err != nil {
+			return fmt.Errorf("failed to read the account of '%s': %w", name, err)
+		}
+	}
+	account["organizationUuid"] = org.UUID
+	account["organizationName"] = org.Name
+	if org.Role != "" {
+		account["organizationRole"] = org.Role
+	} else {
+		delete(account, "organizationRole")
+	}
+	// Workspaces belong to an organization, so the old one's role is void.
+	delete(account, "workspaceRole")
+	if profile.Account, err = json.Marshal(account); err != nil {
+		return err
+	}
+	creds := *profile.Credentials
+	if org.Plan != "" {
+		creds.SubscriptionType = &org.Plan
+	}
+	if org.RateLimitTier != "" {
+		creds.RateLimitTier = &org.RateLimitTier
+	}
+	profile.Credentials = &creds
+	if err := saveProfile(name, profile); err != nil {
+		return err
+	}
+
+	state := loadState()
+	active := state.ActiveProfile != nil && *state.ActiveProfile == name
+	if active {
+		if err := installCredentials(profile); err != nil {
+			return err
+		}
+	}
+	org.Current = true
+	if jsonOutput() {
+		return printJSON(org)
+	}
+	infof("Switched '%s' to organization '%s'\n", name, org.Name)
+	if active {
+		infof("Claude's config was updated too; restart running Claude sessions to pick it up.\n")
+	}
+	return nil
+}
+
+// matchOrganization finds query among orgs by UUID, by name ignoring case,
+// or by the start of a single name.
+func matchOrganization(name string, orgs []Organization, query string) (Organization, error) {
+	var exact, prefix []Organization
+	for _, org := range orgs {
+		switch {
+		case org.UUID == query:
+			return org, nil
+		case strings.EqualFold(org.Name, query):
+			exact = append(exact, org)
+		case strings.HasPrefix(strings.ToLower(org.Name), strings.ToLower(query)):
+			prefix = append(prefix, org)
+		}
+	}
+	matches := exact
+	if len(matches) == 0 {
+		matches = prefix
+	}
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return Organization{}, &cliError{
+			Code:    errOrgNotFound,
+			Message: fmt.Sprintf("'%s' doesn't match an organization of '%s'", query, name),
+			Profile: name,
+			Hint:    fmt.Sprintf("run 'claude-switch org list %s' to see them", name),
+		}
+	}
+	names := make([]string, len(matches))
+	for i, org := range matches {
+		names[i] = fmt.Sprintf("%s (%s)", org.Name, org.UUID)
+	}
+	return Organization{}, &cliError{
+		Code:    errAmbiguousName,
+		Message: fmt.Sprintf("'%s' matches several organizations: %s", query, strings.Join(names, ", ")),
+		Profile: name,
+		Hint:    "give the organization's UUID",
+	}
+}
+
+func fetchOrganizations(profile *Profile) ([]Organization, error) {
+	if sandboxed() {
+		return sandboxOrganizations(profile.Credentials.AccessToken)
+	}
+	req, err := http.NewRequest("GET", organizationsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+profile.Credentials.AccessToken)
+	req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+
+	client := &http.Client{Transport: proxyTransport(profile.Proxy), Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, &cliError{Code: errTokenRejected, Message: fmt.Sprintf("access token was rejected (%d)", resp.StatusCode)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("organization lookup failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		UUID             string `json:"uuid"`
+		Name             string `json:"name"`
+		OrganizationType string `json:"organization_type"`
+		RateLimitTier    string `json:"rate_limit_tier"`
+		Role             string `json:"role"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	orgs := make([]Organization, 0, len(result))
+	for _, o := range result {
+		plan, _ := strings.CutPrefix(o.OrganizationType, "claude_")
+		orgs = append(orgs, Organization{UUID: o.UUID, Name: o.Name, Plan: plan, RateLimitTier: o.RateLimitTier, Role: o.Role})
+	}
+	return orgs, nil
+}
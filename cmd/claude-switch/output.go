@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// --- Structured output (--output json) ---
+
+// outputFormat is "text" or "json", set by the global --output/-o flag or
+// output under [defaults] in config.toml.
+var outputFormat string
+
+func jsonOutput() bool {
+	return outputFormat == "json"
+}
+
+// quiet is set by the global -q/--quiet flag. Progress and confirmation
+// messages ("Switched to ...") are then left out, so claude-switch can run
+// from prompts, hooks and scripts without cluttering their output. Warnings
+// and errors are still printed.
+var quiet bool
+
+// infof prints an informational message to stderr unless --quiet is set.
+func infof(format string, args ...any) {
+	if quiet {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// printJSON writes v to stdout as a single indented JSON document.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// ProfileSummary is the JSON shape of a profile in command output. It never
+// includes secrets.
+type ProfileSummary struct {
+	Name        string `json:"name"`
+	Active      bool   `json:"active"`
+	Type        string `json:"type,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Org         string `json:"org,omitempty"`
+	Plan        string `json:"plan,omitempty"`
+	AccountUUID string `json:"account_uuid,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+	FallbackKey bool   `json:"fallback_key,omitempty"`
+	Label       string `json:"label,omitempty"`
+	Notes       string `json:"notes,omitempty"`
+	LastUsed    string `json:"last_used,omitempty"`
+	Usage       *Usage `json:"usage,omitempty"`
+	UsageError  string `json:"usage_error,omitempty"`
+	Status      string `json:"status,omitempty"`
+	StatusError string `json:"status_error,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func summarize(name string, meta *ProfileMeta) ProfileSummary {
+	state := loadState()
+	summary := ProfileSummary{
+		Name:        name,
+		Active:      state.ActiveProfile != nil && *state.ActiveProfile == name,
+		Type:        meta.Type,
+		Email:       meta.Email,
+		Org:         meta.Org,
+		Plan:        meta.Plan,
+		AccountUUID: meta.AccountUUID,
+		FallbackKey: meta.FallbackKey,
+		Label:       meta.Label,
+		Notes:       meta.Notes,
+	}
+	if meta.ExpiresAt != nil {
+		summary.ExpiresAt = time.UnixMilli(int64(*meta.ExpiresAt)).UTC().Format(time.RFC3339)
+	}
+	if ms := state.LastUsed[name]; ms != 0 {
+		summary.LastUsed = time.UnixMilli(int64(ms)).UTC().Format(time.RFC3339)
+	}
+	return summary
+}
+
+func summarizeProfile(name string, profile *Profile) ProfileSummary {
+	meta := profileMeta(profile)
+	return summarize(name, &meta)
+}
+
+// --- Expiry display ---
+
+// How list and show print a token's expiry: relative to now by default,
+// or as a timestamp with --utc or --local.
+const (
+	timeRelative = "relative"
+	timeUTC      = "utc"
+	timeLocal    = "local"
+)
+
+// parseTimeFlag recognises --utc and --local, setting mode.
+func parseTimeFlag(a string, mode *string) bool {
+	switch a {
+	case "--utc":
+		*mode = timeUTC
+	case "--local":
+		*mode = timeLocal
+	default:
+		return false
+	}
+	return true
+}
+
+// formatExpiry renders an expiry in Unix ms, e.g. "in 3h12m", "expired 2d4h
+// ago" or "2026-10-17 13:46 UTC".
+func formatExpiry(ms uint64, mode string) string {
+	if mode != timeRelative {
+		return formatTimestamp(ms, mode)
+	}
+	remaining := time.Duration(int64(ms)-int64(nowMs())) * time.Millisecond
+	if remaining <= 0 {
+		return "expired " + humanDuration(-remaining) + " ago"
+	}
+	return "in " + humanDuration(remaining)
+}
+
+// formatLastUsed renders a last-use time in Unix ms, e.g. "3h12m ago", or
+// "never" for 0.
+func formatLastUsed(ms uint64, mode string) string {
+	if ms == 0 {
+		return "never"
+	}
+	if mode != timeRelative {
+		return formatTimestamp(ms, mode)
+	}
+	ago := time.Since(time.UnixMilli(int64(ms)))
+	if ago < time.Minute {
+		return "just now"
+	}
+	return humanDuration(ago) + " ago"
+}
+
+// formatTimestamp renders Unix ms in UTC or, for timeLocal, local time.
+func formatTimestamp(ms uint64, mode string) string {
+	t := time.UnixMilli(int64(ms))
+	if mode == timeLocal {
+		return t.Local().Format("2006-01-02 15:04 MST")
+	}
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// humanDuration formats d with its two most significant units, e.g. 3h12m.
+func humanDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	// A zero second unit is left off: 2d, not 2d0h.
+	pair := func(a time.Duration, ua string, b time.Duration, ub string) string {
+		if b == 0 {
+			return fmt.Sprintf("%d%s", a, ua)
+		}
+		return fmt.Sprintf("%d%s%d%s", a, ua, b, ub)
+	}
+	switch {
+	case days > 0:
+		return pair(days, "d", hours, "h")
+	case hours > 0:
+		return pair(hours, "h", minutes, "m")
+	case minutes > 0:
+		return pair(minutes, "m", seconds, "s")
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
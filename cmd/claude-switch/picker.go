@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+)
+
+// --- Interactive profile picker (use with no name) ---
+
+// pickerAvailable reports whether a menu can be shown: keys are read from
+// stdin and the menu is drawn on stderr.
+func pickerAvailable() bool {
+	return stdinIsTerminal() && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// pickProfile shows the profiles as an arrow-key menu on stderr and returns
+// the chosen name. The active profile is preselected.
+// With picker = "fzf" under [defaults], fzf is used instead when installed.
+func pickProfile() (string, error) {
+	if defaults.Picker == "fzf" {
+		if _, err := exec.LookPath("fzf"); err == nil {
+			return pickProfileFzf("")
+		}
+		debugf("picker: fzf isn't on PATH; showing the built-in menu")
+	}
+	names, err := pickerNames()
+	if err != nil {
+		return "", err
+	}
+	lines := pickerLines(names)
+	state := loadState()
+	selected := 0
+	for i, name := range names {
+		if state.ActiveProfile != nil && *state.ActiveProfile == name {
+			selected = i
+		}
+	}
+
+	fd := int(os.Stdin.Fd())
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from the terminal: %w", err)
+	}
+	defer term.Restore(fd, old)
+
+	fmt.Fprint(os.Stderr, "Switch to profile (↑/↓, enter to select, q to cancel):\r\n")
+	r := newRenderer(os.Stderr)
+	draw := func() {
+		for i, line := range lines {
+			if i == selected {
+				fmt.Fprintf(os.Stderr, "\033[2K%s\r\n", r.selected("> "+line))
+			} else {
+				fmt.Fprintf(os.Stderr, "\033[2K  %s\r\n", line)
+			}
+		}
+	}
+	draw()
+
+	buf := make([]byte, 8)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		key := string(buf[:n])
+		switch key {
+		case "\033[A", "\033OA", "k":
+			selected = (selected + len(names) - 1) % len(names)
+		case "\033[B", "\033OB", "j":
+			selected = (selected + 1) % len(names)
+		case "\r", "\n":
+			return names[selected], nil
+		case "q", "\033", "\x03":
+			return "", &cliError{Code: errUsage, Message: "cancelled"}
+		default:
+			continue
+		}
+		// Move back to the top of the menu and redraw it in place
+		fmt.Fprintf(os.Stderr, "\033[%dA", len(lines))
+		draw()
+	}
+}
+
+// pickerNames returns the profiles to pick from, failing when there are
+// none.
+func pickerNames() ([]string, error) {
+	names, err := listProfiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, &cliError{Code: errProfileNotFound, Message: "no profiles", Hint: "use 'claude-switch add <name>' or 'claude-switch import <name>' to create one"}
+	}
+	return names, nil
+}
+
+// pickerLines renders one aligned row per profile from the metadata index.
+func pickerLines(names []string) []string {
+	index := loadIndex()
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		meta, err := loadProfileMeta(&index, name)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\t\t\n", name, "error")
+			continue
+		}
+		expiry := "-"
+		if ts := meta.ExpiresAt; ts != nil {
+			expiry = formatExpiry(*ts, timeRelative)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, meta.DisplayEmail(), meta.DisplaySub(), expiry)
+	}
+	w.Flush()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// --- fzf picker (use --fzf) ---
+
+// pickProfileFzf lets fzf pick a profile, with show's details of the one
+// under the cursor in a preview pane. A query starts the search with it,
+// and a single match is taken without asking.
+func pickProfileFzf(query string) (string, error) {
+	fzf, err := exec.LookPath("fzf")
+	if err != nil {
+		return "", &cliError{
+			Code:    errGeneric,
+			Message: "fzf isn't installed",
+			Hint:    "install it (https://github.com/junegunn/fzf), or run 'claude-switch use' for the built-in menu",
+		}
+	}
+	names, err := pickerNames()
+	if err != nil {
+		return "", err
+	}
+	self, err := selfCommand()
+	if err != nil {
+		return "", err
+	}
+	// Each line is the name, a tab, then the row shown. fzf searches and
+	// shows only the row, and hands the name to the preview as {1}.
+	var input strings.Builder
+	for i, line := range pickerLines(names) {
+		fmt.Fprintf(&input, "%s\t%s\n", names[i], line)
+	}
+	args := []string{
+		"--delimiter", "\t", "--with-nth", "2..", "--no-multi",
+		"--height", "60%", "--reverse", "--prompt", "profile> ",
+		"--preview", self + " --color always show {1} --no-usage",
+		"--preview-window", "right,55%,wrap",
+	}
+	if state := loadState(); state.ActiveProfile != nil {
+		args = append(args, "--header", "active: "+*state.ActiveProfile)
+	}
+	if query != "" {
+		args = append(args, "--query", query, "--select-1", "--exit-0")
+	}
+
+	cmd := exec.Command(fzf, args...)
+	cmd.Stdin = strings.NewReader(input.String())
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// fzf exits 1 when nothing matched and 130 when cancelled.
+		switch exitErr.ExitCode() {
+		case 1:
+			return "", &cliError{Code: errProfileNotFound, Message: fmt.Sprintf("no profile matches '%s'", query)}
+		case 130:
+			return "", &cliError{Code: errUsage, Message: "cancelled"}
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("fzf failed: %w", err)
+	}
+	name, _, _ := strings.Cut(strings.TrimRight(string(out), "\r\n"), "\t")
+	return name, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// --- exec --pool: rotate across a group's profiles ---
+
+// Each pick is recorded in state.json under the state lock, so parallel
+// invocations see each other's choices and spread across the accounts
+// instead of all starting on the same one.
+
+// resolvePool picks the group member that was least recently handed out by
+// a pool and has usable credentials.
+func resolvePool(group string) (string, []envVar, error) {
+	members, err := groupMembers(group)
+	if err != nil {
+		return "", nil, err
+	}
+	var tried []string
+	for {
+		var name string
+		now := nowMs() // reads the state, so not under its lock
+		if err := updateState(func(state *State) {
+			name = leastRecentlyPooled(state, members, tried)
+			if name == "" {
+				return
+			}
+			if state.PoolLastUsed == nil {
+				state.PoolLastUsed = make(map[string]uint64)
+			}
+			// Stamped before the credentials are checked, so an unusable
+			// profile also moves to the back of the queue.
+			state.PoolLastUsed[name] = now
+		}); err != nil {
+			return "", nil, err
+		}
+		if name == "" {
+			return "", nil, &cliError{
+				Code:    errChainExhausted,
+				Message: fmt.Sprintf("no profile in pool '%s' has usable credentials", group),
+				Hint:    "run 'claude-switch list --group " + group + "' to check its profiles",
+			}
+		}
+		vars, err := credentialEnvVars(name, false)
+		if err != nil {
+			infof("Skipping '%s': %v\n", name, err)
+			tried = append(tried, name)
+			continue
+		}
+		infof("Using profile '%s' from pool '%s'\n", name, group)
+		return name, vars, nil
+	}
+}
+
+// leastRecentlyPooled returns the member not in skip with the oldest pool
+// pick, ties going to the first in name order, or "" if none is left.
+func leastRecentlyPooled(state *State, members, skip []string) string {
+	best := ""
+	for _, name := range members {
+		if slices.Contains(skip, name) {
+			continue
+		}
+		if best == "" || state.PoolLastUsed[name] < state.PoolLastUsed[best] {
+			best = name
+		}
+	}
+	return best
+}
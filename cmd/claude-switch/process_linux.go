@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processEnv reads the environment pid was started with, or returns nil
+// if it can't be read.
+func processEnv(pid int) map[string]string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/environ")
+	if err != nil {
+		return nil
+	}
+	env := make(map[string]string)
+	for entry := range bytes.SplitSeq(data, []byte{0}) {
+		if key, value, ok := bytes.Cut(entry, []byte{'='}); ok {
+			env[string(key)] = string(value)
+		}
+	}
+	return env
+}
+
+// clockTicks is USER_HZ, the unit of the start times in /proc, which Linux
+// fixes at 100 for userspace.
+const clockTicks = 100
+
+// inspectProcess reads what /proc shows of pid; fields it can't read are
+// left zero.
+func inspectProcess(pid int) processInfo {
+	var info processInfo
+	dir := "/proc/" + strconv.Itoa(pid)
+	info.Dir, _ = os.Readlink(dir + "/cwd")
+	data, err := os.ReadFile(dir + "/stat")
+	if err != nil {
+		return info
+	}
+	// The command name in parentheses may hold spaces; the fields after
+	// it start with the state, then the parent's pid.
+	i := bytes.LastIndex(data, []byte(") "))
+	if i < 0 {
+		return info
+	}
+	fields := strings.Fields(string(data[i+2:]))
+	if len(fields) > 19 {
+		info.Parent, _ = strconv.Atoi(fields[1])
+		if ticks, err := strconv.ParseInt(fields[19], 10, 64); err == nil {
+			if boot := bootTime(); !boot.IsZero() {
+				info.Started = boot.Add(time.Duration(ticks) * time.Second / clockTicks)
+			}
+		}
+	}
+	return info
+}
+
+func bootTime() time.Time {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}
+	}
+	for line := range strings.SplitSeq(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			if secs, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				return time.Unix(secs, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
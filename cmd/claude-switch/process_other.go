@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// processEnv returns nil: reading another process's environment needs
+// /proc, so sessions here are assumed to use the shared login.
+func processEnv(pid int) map[string]string {
+	return nil
+}
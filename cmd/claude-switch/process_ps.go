@@ -0,0 +1,33 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inspectProcess asks ps for pid's parent and start time, and lsof, where
+// installed, for its working directory; fields it can't find are left zero.
+func inspectProcess(pid int) processInfo {
+	var info processInfo
+	out, err := exec.Command("ps", "-o", "ppid=,lstart=", "-p", strconv.Itoa(pid)).Output()
+	if err == nil {
+		// e.g. "  412 Sat Oct 17 06:46:00 2026", in local time.
+		if fields := strings.Fields(string(out)); len(fields) == 6 {
+			info.Parent, _ = strconv.Atoi(fields[0])
+			info.Started, _ = time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(fields[1:], " "), time.Local)
+		}
+	}
+	out, err = exec.Command("lsof", "-a", "-p", strconv.Itoa(pid), "-d", "cwd", "-Fn").Output()
+	if err == nil {
+		for line := range strings.SplitSeq(string(out), "\n") {
+			if dir, ok := strings.CutPrefix(line, "n"); ok {
+				info.Dir = dir
+			}
+		}
+	}
+	return info
+}
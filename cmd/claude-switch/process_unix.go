@@ -0,0 +1,64 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+func claudePIDs() []int {
+	if sandboxed() {
+		return nil
+	}
+	out, err := exec.Command("pgrep", "-x", "claude|claude-code").Output()
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, s := range strings.Fields(string(out)) {
+		var pid int
+		if _, err := fmt.Sscanf(s, "%d", &pid); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+func execWithEnv(args []string, vars []envVar) error {
+	binary, err := exec.LookPath(args[0])
+	if err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	// Recorded now: on Unix the command replaces claude-switch.
+	recordAudit(auditProfile, nil)
+	// The command keeps our pid.
+	recordLaunch(os.Getpid(), args)
+	return syscall.Exec(binary, args, mergeEnv(os.Environ(), vars))
+}
+
+func envKey(key string) string {
+	return key
+}
+
+// detach starts cmd in its own session so it outlives the terminal.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	return err == nil && p.Signal(syscall.Signal(0)) == nil
+}
+
+// stopProcess asks pid to shut down cleanly.
+func stopProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,141 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is the exit code GetExitCodeProcess reports for a running
+// process (STILL_ACTIVE).
+const stillActive = 259
+
+func claudePIDs() []int {
+	if sandboxed() {
+		return nil
+	}
+	out, err := exec.Command("tasklist", "/FI", "IMAGENAME eq claude.exe", "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return nil
+	}
+	records, err := csv.NewReader(strings.NewReader(string(out))).ReadAll()
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, record := range records {
+		if len(record) < 2 || !strings.EqualFold(record[0], "claude.exe") {
+			continue
+		}
+		if pid, err := strconv.Atoi(record[1]); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// execWithEnv runs the command as a child, since Windows has no exec(2), and
+// exits with its status. Ctrl-C reaches every process on the console, so the
+// child handles it itself; we only have to survive it and wait.
+func execWithEnv(args []string, vars []envVar) error {
+	binary, err := exec.LookPath(args[0])
+	if err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	// Recorded now: on Unix the command replaces claude-switch.
+	recordAudit(auditProfile, nil)
+	cmd := exec.Command(binary, args[1:]...)
+	cmd.Env = mergeEnv(os.Environ(), vars)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	recordLaunch(cmd.Process.Pid, args)
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	os.Exit(0)
+	return nil
+}
+
+// envKey folds case, since Windows environment variable names are
+// case-insensitive.
+func envKey(key string) string {
+	return strings.ToUpper(key)
+}
+
+// detach starts cmd without a console so it outlives the terminal.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS}
+}
+
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	return windows.GetExitCodeProcess(h, &code) == nil && code == stillActive
+}
+
+// stopProcess terminates pid. Windows has no SIGTERM to deliver to a
+// process without a console.
+func stopProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// inspectProcess finds pid's parent in a process snapshot and its start
+// time from the process itself. Windows doesn't expose another process's
+// working directory, so Dir is left empty.
+func inspectProcess(pid int) processInfo {
+	var info processInfo
+	if snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0); err == nil {
+		defer windows.CloseHandle(snap)
+		entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+		for err := windows.Process32First(snap, &entry); err == nil; err = windows.Process32Next(snap, &entry) {
+			if entry.ProcessID == uint32(pid) {
+				info.Parent = int(entry.ParentProcessID)
+				break
+			}
+		}
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return info
+	}
+	defer windows.CloseHandle(h)
+	var created, exited, kernel, user windows.Filetime
+	if windows.GetProcessTimes(h, &created, &exited, &kernel, &user) == nil {
+		info.Started = time.Unix(0, created.Nanoseconds())
+	}
+	return info
+}
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- Profiles ---
+
+// Profiles and their credentials are defined in pkg/claudeswitch, which
+// reads and writes them in the same format.
+type (
+	Profile          = claudeswitch.Profile
+	OAuthCredentials = claudeswitch.OAuthCredentials
+)
+
+// --- Profile metadata (non-secret, kept in index.json) ---
+
+// ProfileMeta is the non-secret summary of a profile. It lives in a separate
+// index so list-style commands never have to open credential files.
+type ProfileMeta struct {
+	Type        string   `json:"type"`
+	Email       string   `json:"email,omitempty"`
+	Org         string   `json:"org,omitempty"`
+	Plan        string   `json:"plan,omitempty"`
+	AccountUUID string   `json:"account_uuid,omitempty"`
+	ExpiresAt   *uint64  `json:"expires_at,omitempty"`
+	FallbackKey bool     `json:"fallback_key,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	Label       string   `json:"label,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+	// Of the API key or long-lived token, to spot duplicates; see
+	// keyFingerprint.
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+}
+
+type Index struct {
+	Version  int                    `json:"version,omitempty"`
+	Profiles map[string]ProfileMeta `json:"profiles"`
+}
+
+// indexVersion is bumped when ProfileMeta gains fields or they are derived
+// differently, so entries written before are rebuilt from the profiles.
+const indexVersion = 4
+
+func profileMeta(p *Profile) ProfileMeta {
+	info := labelInfo(p)
+	meta := ProfileMeta{Type: p.Type, ExpiresAt: p.ExpiresAt(), FallbackKey: p.FallbackApiKey != "", Label: info.Label, Notes: info.Notes}
+	if p.Type == "oauth" {
+		meta.Email = p.AccountInfo("emailAddress")
+		meta.Org = p.AccountInfo("organizationName")
+		meta.AccountUUID = p.AccountInfo("accountUuid")
+		if sub := p.DisplaySub(); sub != "-" {
+			meta.Plan = sub
+		}
+		if p.Credentials != nil {
+			meta.Scopes = p.Credentials.Scopes
+		}
+	}
+	switch p.Type {
+	case "api_key":
+		meta.KeyFingerprint = keyFingerprint(p.ApiKey)
+	case "oauth_token":
+		meta.KeyFingerprint = keyFingerprint(p.OAuthToken)
+	}
+	return meta
+}
+
+func (m *ProfileMeta) DisplayType() string {
+	if m.FallbackKey {
+		return m.Type + "+key"
+	}
+	return m.Type
+}
+
+func (m *ProfileMeta) DisplayEmail() string {
+	if m.Type != "oauth" {
+		return "-"
+	}
+	if m.Email == "" {
+		return "(unknown)"
+	}
+	return m.Email
+}
+
+func (m *ProfileMeta) DisplayOrg() string {
+	if m.Org == "" {
+		return "-"
+	}
+	return m.Org
+}
+
+func (m *ProfileMeta) DisplaySub() string {
+	if m.Plan == "" {
+		return "-"
+	}
+	return m.Plan
+}
+
+// --- State tracking ---
+
+type State struct {
+	ActiveProfile *string `json:"active_profile,omitempty"`
+	// Unix ms until which token refreshes are suppressed after a 429.
+	RefreshCooldownUntil uint64 `json:"refresh_cooldown_until,omitempty"`
+	// Server time minus local time in ms, measured on token refreshes.
+	ClockSkewMs int64 `json:"clock_skew_ms,omitempty"`
+	// Named sets of profiles, managed with `group`.
+	Groups map[string][]string `json:"groups,omitempty"`
+	// Unix ms of each profile's last pick by exec --pool.
+	PoolLastUsed map[string]uint64 `json:"pool_last_used,omitempty"`
+	// Unix ms, local clock, of each profile's last use or exec.
+	LastUsed map[string]uint64 `json:"last_used,omitempty"`
+}
+
+// stampUsed records that name was just used; see markUsed.
+func stampUsed(state *State, name string) {
+	if state.LastUsed == nil {
+		state.LastUsed = make(map[string]uint64)
+	}
+	state.LastUsed[name] = uint64(time.Now().UnixMilli())
+}
+
+// markUsed records a use of name for list and show. It's bookkeeping, so a
+// failure to record it doesn't fail the command.
+func markUsed(name string) {
+	auditProfile = name
+	if err := updateState(func(state *State) { stampUsed(state, name) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't record the use of '%s': %v\n", name, err)
+	}
+}
+
+// --- Directory/path helpers ---
+
+func configDir() string {
+	if sandboxed() {
+		return filepath.Join(sandboxDir(), "config")
+	}
+	return claudeswitch.DefaultConfigDir()
+}
+
+func profilesDir() string {
+	return filepath.Join(configDir(), "profiles")
+}
+
+func indexPath() string {
+	return filepath.Join(configDir(), "index.json")
+}
+
+func statePath() string {
+	return filepath.Join(configDir(), "state.json")
+}
+
+func claudeConfigDir() string {
+	if sandboxed() {
+		return filepath.Join(sandboxDir(), "claude")
+	}
+	return claudeswitch.DefaultClaudeConfigDir()
+}
+
+func credentialsPath() string {
+	return filepath.Join(claudeConfigDir(), ".credentials.json")
+}
+
+func claudeJSONPath() string {
+	if sandboxed() {
+		return filepath.Join(sandboxDir(), ".claude.json")
+	}
+	return claudeswitch.DefaultClaudeJSONPath()
+}
+
+// --- Credential reading (flat-file with system keychain fallback) ---
+
+func readOAuthCredentials() json.RawMessage {
+	data, err := readFile(credentialsPath())
+	if err == nil {
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(data, &doc) == nil {
+			if raw, ok := doc["claudeAiOauth"]; ok {
+				return raw
+			}
+		}
+	}
+
+	// Fallback: macOS keychain, Secret Service or Windows Credential Manager
+	if !defaults.keychainEnabled() {
+		return nil
+	}
+	return readKeychainCredentials()
+}
+
+// --- File I/O with 0600 permissions ---
+
+// writeSecure replaces path atomically, creating new files 0600; see
+// claudeswitch.WriteFileAtomic.
+func writeSecure(path string, data []byte) error {
+	if err := claudeswitch.WriteFileAtomic(path, data); err != nil {
+		return err
+	}
+	debugf("wrote %s (%d bytes)", path, len(data))
+	return nil
+}
+
+// tmpSuffix marks writeSecure's temporary files; the random part follows.
+const tmpSuffix = claudeswitch.TempSuffix
+
+// --- Profile name validation ---
+
+func validateProfileName(name string) error {
+	if err := claudeswitch.ValidateName(name); err != nil {
+		return &cliError{
+			Code:    errInvalidName,
+			Message: fmt.Sprintf("invalid profile name: '%s'", name),
+			Profile: name,
+			Hint:    "profile names must be a single path component without slashes",
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+func profilePath(name string) string {
+	return filepath.Join(profilesDir(), name+".json")
+}
+
+// --- Profile CRUD ---
+
+// These wrap the configured StorageBackend and keep the metadata index,
+// which is always local, in step with it.
+
+func saveProfile(name string, profile *Profile) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	store, err := storage()
+	if err != nil {
+		return err
+	}
+	err = withLock(profileLock(name), func() error {
+		stored := profile
+		if profile.Store != "" {
+			if err := saveExternalSecrets(name, profile); err != nil {
+				return err
+			}
+			stored = profile.WithoutSecrets()
+		}
+		return store.Save(name, stored)
+	})
+	if err != nil {
+		return err
+	}
+	meta := profileMeta(profile)
+	return updateIndex(name, &meta)
+}
+
+func loadProfile(name string) (*Profile, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+	store, err := storage()
+	if err != nil {
+		return nil, err
+	}
+	var profile *Profile
+	err = withLock(profileLock(name), func() error {
+		profile, err = store.Load(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if profile.Store != "" {
+		if err := loadExternalSecrets(name, profile); err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+func listProfiles() ([]string, error) {
+	store, err := storage()
+	if err != nil {
+		return nil, err
+	}
+	names, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func removeProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	store, err := storage()
+	if err != nil {
+		return err
+	}
+	if err := withLock(profileLock(name), func() error { return store.Remove(name) }); err != nil {
+		return err
+	}
+	if err := updateIndex(name, nil); err != nil {
+		return err
+	}
+
+	// Clear active state if this was the active profile
+	return updateState(func(state *State) {
+		if state.ActiveProfile != nil && *state.ActiveProfile == name {
+			state.ActiveProfile = nil
+		}
+		dropFromGroups(state, name)
+		delete(state.PoolLastUsed, name)
+		delete(state.LastUsed, name)
+	})
+}
+
+// renameProfile moves a profile to a new name, carrying the active marker,
+// group memberships, last use and isolated config directory along with it.
+func renameProfile(from, to string) error {
+	if err := validateProfileName(to); err != nil {
+		return err
+	}
+	if profileExists(to) {
+		return existsError(to)
+	}
+	profile, err := loadProfile(from)
+	if err != nil {
+		return err
+	}
+	if err := saveProfile(to, profile); err != nil {
+		return err
+	}
+	state := loadState()
+	wasActive := state.ActiveProfile != nil && *state.ActiveProfile == from
+	groups := profileGroups(state, from)
+	lastUsed, used := state.LastUsed[from]
+	if err := removeProfile(from); err != nil {
+		return err
+	}
+	if err := renameIsolated(from, to); err != nil {
+		debugf("rename: couldn't move the isolated config directory of '%s': %v", from, err)
+	}
+	if !wasActive && len(groups) == 0 && !used {
+		return nil
+	}
+	return updateState(func(state *State) {
+		if wasActive {
+			state.ActiveProfile = &to
+		}
+		for _, group := range groups {
+			joinGroup(state, group, to)
+		}
+		if used {
+			if state.LastUsed == nil {
+				state.LastUsed = make(map[string]uint64)
+			}
+			state.LastUsed[to] = lastUsed
+		}
+	})
+}
+
+// --- Index CRUD ---
+
+func loadIndex() Index {
+	index := Index{}
+	if data, err := readFile(indexPath()); err == nil {
+		json.Unmarshal(data, &index)
+	}
+	if index.Version < indexVersion {
+		index.Profiles = nil
+	}
+	if index.Profiles == nil {
+		index.Profiles = make(map[string]ProfileMeta)
+	}
+	return index
+}
+
+// saveIndex writes the index; callers hold indexLock.
+func saveIndex(index *Index) error {
+	index.Version = indexVersion
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSecure(indexPath(), data)
+}
+
+// updateIndex records (or, with a nil meta, drops) a profile's index entry.
+func updateIndex(name string, meta *ProfileMeta) error {
+	return withLock(indexLock, func() error {
+		index := loadIndex()
+		if meta == nil {
+			if _, ok := index.Profiles[name]; !ok {
+				return nil
+			}
+			delete(index.Profiles, name)
+		} else {
+			index.Profiles[name] = *meta
+		}
+		return saveIndex(&index)
+	})
+}
+
+// loadProfileMeta returns a profile's metadata from the index, falling back
+// to the profile file (and backfilling the index) for profiles saved before
+// the index existed.
+func loadProfileMeta(index *Index, name string) (*ProfileMeta, error) {
+	if meta, ok := index.Profiles[name]; ok {
+		return &meta, nil
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	meta := profileMeta(profile)
+	index.Profiles[name] = meta
+	return &meta, updateIndex(name, &meta)
+}
+
+// --- State CRUD ---
+
+func loadState() State {
+	if unlock, err := lockFile(stateLock); err == nil {
+		defer unlock()
+	}
+	return readState()
+}
+
+func saveState(state *State) error {
+	return withLock(stateLock, func() error { return writeState(state) })
+}
+
+// updateState applies fn to the saved state under the state lock, so a
+// concurrent update between the read and the write isn't lost.
+func updateState(fn func(*State)) error {
+	return withLock(stateLock, func() error {
+		state := readState()
+		fn(&state)
+		return writeState(&state)
+	})
+}
+
+func readState() State {
+	data, err := readFile(statePath())
+	if err != nil {
+		return State{}
+	}
+	var state State
+	if json.Unmarshal(data, &state) != nil {
+		return State{}
+	}
+	return state
+}
+
+func writeState(state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSecure(statePath(), data)
+}
+
+// --- Surgical config editing ---
+
+// claudeSwitcher edits the login keys of Claude's files, under the same
+// locks as the rest of the CLI and backing each file up before replacing it.
+func claudeSwitcher() *claudeswitch.Switcher {
+	return &claudeswitch.Switcher{
+		ConfigDir:  claudeConfigDir(),
+		ClaudeJSON: claudeJSONPath(),
+		LockDir:    locksDir(),
+		ReadFile:   readFile,
+		WriteFile: func(path string, data []byte) error {
+			backup := claudeJSONBackup
+			switch path {
+			case credentialsPath():
+				backup = claudeCredentialsBackup
+			case claudeSettingsPath():
+				backup = claudeSettingsBackup
+			}
+			return writeWithBackup(path, backup, data)
+		},
+	}
+}
+
+func writeCredentials(creds *OAuthCredentials) error {
+	return claudeSwitcher().WriteCredentials(creds)
+}
+
+func writeOAuthAccount(account json.RawMessage) error {
+	return claudeSwitcher().WriteAccount(account)
+}
+
+func clearAuth() error {
+	return restoreAuth(authSnapshot{})
+}
+
+// authSnapshot holds the parts of Claude's login that clearAuth removes.
+type authSnapshot struct {
+	Credentials json.RawMessage // claudeAiOauth in .credentials.json
+	Keychain    json.RawMessage
+	Account     json.RawMessage // oauthAccount in .claude.json
+	APIKey      json.RawMessage // primaryApiKey in .claude.json
+}
+
+// snapshotAuth records Claude's current login so a failed `add --claude`
+// can put it back.
+func snapshotAuth() authSnapshot {
+	var snap authSnapshot
+	if doc := readJSONDoc(credentialsPath()); doc != nil {
+		snap.Credentials = doc["claudeAiOauth"]
+	}
+	if doc := readJSONDoc(claudeJSONPath()); doc != nil {
+		snap.Account, snap.APIKey = doc["oauthAccount"], doc["primaryApiKey"]
+	}
+	if defaults.keychainEnabled() {
+		snap.Keychain = readKeychainCredentials()
+	}
+	return snap
+}
+
+// restoreAuth makes Claude's login keys match snap exactly; an empty
+// snapshot logs Claude out. The keychain item is only ever written back,
+// since clearing it isn't needed for Claude to start a new login.
+func restoreAuth(snap authSnapshot) error {
+	switcher := claudeSwitcher()
+	if err := switcher.SetKeys(credentialsPath(),
+		map[string]json.RawMessage{"claudeAiOauth": snap.Credentials}); err != nil {
+		return err
+	}
+	if err := switcher.SetKeys(claudeJSONPath(),
+		map[string]json.RawMessage{"oauthAccount": snap.Account, "primaryApiKey": snap.APIKey}); err != nil {
+		return err
+	}
+	if snap.Keychain != nil {
+		var creds OAuthCredentials
+		if err := json.Unmarshal(snap.Keychain, &creds); err != nil {
+			return err
+		}
+		return writeKeychainCredentials(&creds)
+	}
+	return nil
+}
+
+func readJSONDoc(path string) map[string]json.RawMessage {
+	data, err := readFile(path)
+	if err != nil {
+		return nil
+	}
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(data, &doc) != nil {
+		return nil
+	}
+	return doc
+}
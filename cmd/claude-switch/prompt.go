@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- Prompt segment (prompt) ---
+
+// cmdPrompt prints a short segment for shell prompts and status bars, e.g.
+// "claude:work ⏳45m". Like current, it only reads state.json and the
+// metadata index: no profile files, no keychain and no network, so it can
+// run on every render.
+
+// promptStyles wrap the segment's colour codes for where it is shown.
+var promptStyles = []string{"ansi", "bash", "zsh", "tmux", "plain"}
+
+// promptSoon is how close to expiry the segment turns from green to yellow.
+const promptSoon = time.Hour
+
+func cmdPrompt(args []string) error {
+	style, prefix := "", "claude:"
+	withExpiry := true
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--style" || a == "--prefix":
+			if i+1 >= len(args) {
+				return usageError("%s requires a value", a)
+			}
+			i++
+			if a == "--style" {
+				style = args[i]
+			} else {
+				prefix = args[i]
+			}
+		case strings.HasPrefix(a, "--style="):
+			style = strings.TrimPrefix(a, "--style=")
+		case strings.HasPrefix(a, "--prefix="):
+			prefix = strings.TrimPrefix(a, "--prefix=")
+		case a == "--no-expiry":
+			withExpiry = false
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	switch {
+	case style == "":
+		style = "plain"
+		if useColor(os.Stdout) {
+			style = "ansi"
+		}
+	case !slices.Contains(promptStyles, style):
+		return usageError("unknown style '%s' (expected %s)", style, strings.Join(promptStyles, ", "))
+	}
+	if colorMode == "never" || os.Getenv("NO_COLOR") != "" {
+		style = "plain"
+	}
+
+	// Without an active profile, the default one is what run and exec --
+	// would use.
+	var name string
+	active := false
+	if state := loadState(); state.ActiveProfile != nil {
+		name, active = *state.ActiveProfile, true
+	} else if defaults.Profile != "" {
+		name = defaults.Profile
+	} else {
+		if jsonOutput() {
+			return printJSON(nil)
+		}
+		// Like current: nothing printed, so the segment simply disappears.
+		os.Exit(1)
+	}
+	color, expiry := "green", ""
+	var remaining *time.Duration
+	if meta, ok := loadIndex().Profiles[name]; ok && meta.Type == "oauth" && meta.ExpiresAt != nil {
+		left := time.Duration(int64(*meta.ExpiresAt)-int64(nowMs())) * time.Millisecond
+		remaining = &left
+		switch {
+		case left <= 0:
+			color, expiry = "red", "⌛expired"
+		case left <= promptSoon:
+			color, expiry = "yellow", "⏳"+humanDuration(max(left.Truncate(time.Minute), time.Minute))
+		default:
+			expiry = "⏳" + humanDuration(left.Truncate(time.Minute))
+		}
+	}
+	if jsonOutput() {
+		result := map[string]any{"profile": name, "active": active, "expires_in_seconds": nil}
+		if remaining != nil {
+			result["expires_in_seconds"] = int64(remaining.Seconds())
+		}
+		return printJSON(result)
+	}
+
+	segment := prefix + name
+	if withExpiry && expiry != "" {
+		segment += " " + expiry
+	}
+	fmt.Println(promptColor(style, color, segment))
+	return nil
+}
+
+// promptColor colours s for style. The shells need the codes marked as
+// taking no width, or line editing goes wrong.
+func promptColor(style, color, s string) string {
+	code := map[string]string{"green": "32", "yellow": "33", "red": "31"}[color]
+	ansi := "\033[" + code + "m"
+	switch style {
+	case "ansi":
+		return ansi + s + ansiReset
+	case "bash":
+		return "\001" + ansi + "\002" + s + "\001" + ansiReset + "\002"
+	case "zsh":
+		return "%{" + ansi + "%}" + s + "%{" + ansiReset + "%}"
+	case "tmux":
+		return "#[fg=" + color + "]" + s + "#[default]"
+	}
+	return s
+}
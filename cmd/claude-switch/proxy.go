@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- Per-profile proxy settings ---
+
+type ProxySettings = claudeswitch.ProxySettings
+
+// proxyEnvVars returns the proxy variables in both the upper- and
+// lower-case spellings, since tools disagree on which one they read.
+func proxyEnvVars(ps *ProxySettings) []envVar {
+	if ps.Empty() {
+		return nil
+	}
+	var vars []envVar
+	for _, kv := range [][2]string{{"HTTP_PROXY", ps.HTTP}, {"HTTPS_PROXY", ps.HTTPS}, {"NO_PROXY", ps.NoProxy}} {
+		if kv[1] != "" {
+			vars = append(vars, envVar{kv[0], kv[1]}, envVar{strings.ToLower(kv[0]), kv[1]})
+		}
+	}
+	return vars
+}
+
+// proxyTransport returns an http.RoundTripper that routes through the
+// profile's proxy and trusts the configured ca_bundle, or nil to use the
+// default (environment-driven) transport. Either is wrapped for debug
+// logging when that is on.
+func proxyTransport(ps *ProxySettings) http.RoundTripper {
+	if ps.Empty() && defaults.rootCAs == nil {
+		return withDebug(nil)
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if defaults.rootCAs != nil {
+		t.TLSClientConfig = &tls.Config{RootCAs: defaults.rootCAs}
+	}
+	if ps.Empty() {
+		// Without a profile proxy, HTTPS_PROXY and friends still apply.
+		return withDebug(t)
+	}
+	t.Proxy = ps.Proxy
+	return withDebug(t)
+}
+
+func cmdProxy(args []string) error {
+	var name string
+	settings := ProxySettings{}
+	set := map[string]bool{}
+	clear := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch a {
+		case "--http", "--https", "--no-proxy":
+			if i+1 >= len(args) {
+				return usageError("%s requires a value", a)
+			}
+			i++
+			set[a] = true
+			switch a {
+			case "--http":
+				settings.HTTP = args[i]
+			case "--https":
+				settings.HTTPS = args[i]
+			case "--no-proxy":
+				settings.NoProxy = args[i]
+			}
+		case "--clear":
+			clear = true
+		default:
+			if name != "" {
+				return usageError("unexpected argument: %s", a)
+			}
+			name = a
+		}
+	}
+	if name == "" {
+		return usageError("proxy requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+
+	if clear || len(set) > 0 {
+		unlock, err := lockProfileUpdate(name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+
+	if !clear && len(set) == 0 {
+		if jsonOutput() {
+			return printJSON(proxyJSON(profile.Proxy))
+		}
+		if profile.Proxy.Empty() {
+			infof("No proxy configured for '%s'\n", name)
+			return nil
+		}
+		for _, v := range proxyEnvVars(profile.Proxy) {
+			if v.Key == strings.ToUpper(v.Key) {
+				fmt.Printf("%s=%s\n", v.Key, v.Value)
+			}
+		}
+		return nil
+	}
+
+	if clear {
+		profile.Proxy = nil
+	}
+	if len(set) > 0 {
+		if profile.Proxy == nil {
+			profile.Proxy = &ProxySettings{}
+		}
+		if set["--http"] {
+			profile.Proxy.HTTP = settings.HTTP
+		}
+		if set["--https"] {
+			profile.Proxy.HTTPS = settings.HTTPS
+		}
+		if set["--no-proxy"] {
+			profile.Proxy.NoProxy = settings.NoProxy
+		}
+		for _, raw := range []string{profile.Proxy.HTTP, profile.Proxy.HTTPS} {
+			if raw == "" {
+				continue
+			}
+			if u, err := url.Parse(raw); err != nil || u.Host == "" {
+				return usageError("invalid proxy URL: '%s'", raw)
+			}
+		}
+		if profile.Proxy.Empty() {
+			profile.Proxy = nil
+		}
+	}
+
+	if err := saveProfile(name, profile); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(proxyJSON(profile.Proxy))
+	}
+	if profile.Proxy == nil {
+		infof("Cleared proxy settings for '%s'\n", name)
+	} else {
+		infof("Updated proxy settings for '%s'\n", name)
+	}
+	return nil
+}
+
+func proxyJSON(ps *ProxySettings) *ProxySettings {
+	if ps == nil {
+		return &ProxySettings{}
+	}
+	return ps
+}
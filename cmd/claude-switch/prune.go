@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// --- prune: remove dead profiles ---
+
+// A profile is dead when its file can't be parsed, or when it is an OAuth
+// profile whose token has expired and whose refresh token is rejected with
+// invalid_grant. Tokens that are still valid aren't refreshed just to check,
+// so a revoked account is found once its access token runs out. Revoked
+// profiles go to the trash like `remove`; unparseable ones are deleted.
+
+// PruneResult is one dead profile in prune's JSON output.
+type PruneResult struct {
+	Profile string `json:"profile"`
+	Reason  string `json:"reason"`
+	Kind    string `json:"kind"` // "invalid_grant" or "unparseable"
+	Removed bool   `json:"removed"`
+	Error   string `json:"error,omitempty"`
+}
+
+func cmdPrune(args []string) error {
+	dryRun, yes := false, false
+	for _, a := range args {
+		switch a {
+		case "--dry-run", "-n":
+			dryRun = true
+		case "--yes", "-y":
+			yes = true
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+
+	names, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	dead := []PruneResult{}
+	for _, name := range names {
+		if r, ok := checkDead(name); ok {
+			dead = append(dead, r)
+		}
+	}
+	if len(dead) == 0 {
+		if jsonOutput() {
+			return printJSON(dead)
+		}
+		infof("No dead profiles.\n")
+		return nil
+	}
+	if !jsonOutput() {
+		for _, r := range dead {
+			infof("%s: %s\n", r.Profile, r.Reason)
+		}
+	}
+	if dryRun {
+		if jsonOutput() {
+			return printJSON(dead)
+		}
+		infof("%d dead profile(s). Re-run without --dry-run to remove them.\n", len(dead))
+		return nil
+	}
+	if !yes {
+		if !stdinIsTerminal() || jsonOutput() {
+			return usageError("prune needs --yes to remove profiles without asking")
+		}
+		answer, err := readLine(fmt.Sprintf("Remove %d profile(s)? [y/N] ", len(dead)))
+		if err != nil {
+			return err
+		}
+		if a := strings.ToLower(answer); a != "y" && a != "yes" {
+			return &cliError{Code: errGeneric, Message: "prune cancelled"}
+		}
+	}
+
+	failed := 0
+	for i := range dead {
+		r := &dead[i]
+		// There is nothing to restore from a file that can't be parsed.
+		remove := trashProfile
+		if r.Kind == "unparseable" {
+			remove = removeProfile
+		}
+		if err := remove(r.Profile); err != nil {
+			r.Error = err.Error()
+			failed++
+			if !jsonOutput() {
+				fmt.Fprintf(os.Stderr, "%s: failed to remove: %v\n", r.Profile, err)
+			}
+			continue
+		}
+		r.Removed = true
+	}
+	if jsonOutput() {
+		if err := printJSON(dead); err != nil {
+			return err
+		}
+	} else if removed := len(dead) - failed; removed > 0 {
+		infof("Removed %d profile(s).\n", removed)
+		if slices.ContainsFunc(dead, func(r PruneResult) bool { return r.Removed && r.Kind == "invalid_grant" }) {
+			infof("Run 'claude-switch remove --undo <name>' within 7 days to restore a revoked one.\n")
+		}
+	}
+	if failed > 0 {
+		return &cliError{Code: errGeneric, Message: fmt.Sprintf("%d of %d profile(s) couldn't be removed", failed, len(dead))}
+	}
+	return nil
+}
+
+// checkDead reports whether name is dead and why. Profiles that can't be
+// checked right now, e.g. offline or rate limited, count as alive.
+func checkDead(name string) (PruneResult, bool) {
+	result := PruneResult{Profile: name}
+	profile, err := loadProfile(name)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr) || errors.As(err, &typeErr):
+		result.Reason, result.Kind = "can't be parsed: "+err.Error(), "unparseable"
+		return result, true
+	case err != nil:
+		if !jsonOutput() {
+			fmt.Fprintf(os.Stderr, "%s: not checked: %v\n", name, err)
+		}
+		return result, false
+	case profile.Type != "oauth" || profile.Credentials == nil || !isExpired(profile.Credentials):
+		return result, false
+	}
+	if r := refreshOne(name, 0); r.Code == errReauthRequired {
+		result.Reason, result.Kind = r.Error, "invalid_grant"
+		return result, true
+	} else if r.Error != "" && !jsonOutput() {
+		fmt.Fprintf(os.Stderr, "%s: not checked: %s\n", name, r.Error)
+	}
+	return result, false
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// --- push <name> <host>: copy a profile to another machine over SSH ---
+
+// The profile is exported locally and piped into `claude-switch import
+// --from-file -` on the remote side, so the secrets travel over the SSH
+// channel's stdin and never appear in a command line.
+
+func cmdPush(args []string) error {
+	var name, host, remoteName string
+	remoteCmd := "claude-switch"
+	use, force := false, false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--use":
+			use = true
+		case a == "--force" || a == "-f":
+			force = true
+		case a == "--as" || a == "--remote-cmd" ||
+			strings.HasPrefix(a, "--as=") || strings.HasPrefix(a, "--remote-cmd="):
+			flag, value, ok := strings.Cut(a, "=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a value", a)
+				}
+				i++
+				value = args[i]
+			}
+			if flag == "--as" {
+				remoteName = value
+			} else {
+				remoteCmd = value
+			}
+		case strings.HasPrefix(a, "-"):
+			return usageError("unexpected argument: %s", a)
+		case name == "":
+			name = a
+		case host == "":
+			host = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" || host == "" {
+		return usageError("push requires a profile name and a host (e.g. push work user@devbox)")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	if remoteName == "" {
+		remoteName = name
+	}
+	if err := validateProfileName(remoteName); err != nil {
+		return err
+	}
+
+	file, err := exportProfile(name, false)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	steps := [][]string{{"import", remoteName, "--from-file", "-"}}
+	if force {
+		steps[0] = append(steps[0], "--force")
+	}
+	if use {
+		steps = append(steps, []string{"use", remoteName})
+	}
+	if err := runRemote(host, remoteCmd, steps, data); err != nil {
+		return err
+	}
+
+	if jsonOutput() {
+		return printJSON(map[string]any{"profile": name, "host": host, "remote_profile": remoteName, "used": use})
+	}
+	infof("Pushed '%s' to %s as '%s'.\n", name, host, remoteName)
+	return nil
+}
+
+// runRemote runs each step as `<remoteCmd> <step...>` on host, stopping at
+// the first failure, with stdin fed to the first step. The remote output is
+// passed through. In the sandbox, the "host" is another sandbox directory.
+func runRemote(host, remoteCmd string, steps [][]string, stdin []byte) error {
+	if sandboxed() {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(sandboxDir(), "remotes", strings.NewReplacer("/", "_", "\\", "_").Replace(host))
+		for i, step := range steps {
+			cmd := exec.Command(exe, step...)
+			cmd.Env = append(os.Environ(), "CLAUDE_SWITCH_SANDBOX_DIR="+dir)
+			if i == 0 {
+				cmd.Stdin = bytes.NewReader(stdin)
+			}
+			cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+			if err := cmd.Run(); err != nil {
+				return remoteError(host, err)
+			}
+		}
+		return nil
+	}
+
+	var script []string
+	for _, step := range steps {
+		words := []string{quotePosix(remoteCmd)}
+		for _, a := range step {
+			words = append(words, quotePosix(a))
+		}
+		script = append(script, strings.Join(words, " "))
+	}
+	cmd := exec.Command("ssh", host, strings.Join(script, " && "))
+	cmd.Stdin = bytes.NewReader(stdin)
+	// Remote stdout is informational here; keep our stdout for --json.
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return remoteError(host, err)
+	}
+	return nil
+}
+
+// remoteError adds a hint for failures that happen before the remote
+// claude-switch runs; its own errors have already been printed.
+func remoteError(host string, err error) error {
+	ce := &cliError{Code: errGeneric, Message: fmt.Sprintf("push to %s failed: %v", host, err), Err: err}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		switch exit.ExitCode() {
+		case 127:
+			ce.Hint = "claude-switch isn't on the remote PATH; install it there or pass --remote-cmd"
+		case 255:
+			ce.Hint = fmt.Sprintf("check that 'ssh %s' works on its own", host)
+		}
+	}
+	return ce
+}
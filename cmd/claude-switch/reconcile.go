@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// --- Reconciling tokens Claude rotated ---
+
+// Claude refreshes its login on its own, and each refresh rotates the
+// refresh token, so the copy `use` saved in the profile stops working. Every
+// invocation therefore starts by comparing Claude's live credentials with
+// the saved profile of the same account (by account UUID) and, when Claude's
+// expire later, saving them to the profile. It is quiet and best effort:
+// anything that would prompt, wait or fail is skipped, and `sync` and
+// `status` remain for looking at the drift directly.
+
+// reconcileSkipped are commands that check or sync Claude's credentials
+// themselves, or run too often to spend a keychain lookup on.
+var reconcileSkipped = []string{"sync", "watch", "status", "doctor", "logout", "hook", "init", "completion", "current", "prompt", "token", "api-key-helper", "credential-helper", "-h", "--help", "help"}
+
+// reconcile saves tokens Claude has rotated since the last switch to the
+// profile of the same account. Whether anything was saved is only logged.
+func reconcile() {
+	if !defaults.reconcileEnabled() || slices.Contains(reconcileSkipped, os.Args[1]) {
+		return
+	}
+	// Other stores can't be read without a prompt or a network round trip.
+	if !usingFileStore() || (encryptionEnabled() && profileKey == nil && os.Getenv("CLAUDE_SWITCH_PASSPHRASE") == "") {
+		return
+	}
+	live := readLiveAuth()
+	if live.Credentials == nil || live.Credentials.RefreshToken == "" {
+		return
+	}
+	uuid := (&Profile{Credentials: live.Credentials, Account: live.Account}).AccountInfo("accountUuid")
+	if uuid == "" {
+		debugf("reconcile: Claude's account is unknown")
+		return
+	}
+	// When two profiles share the account, the active one gets the tokens.
+	name := profileForAccount(uuid)
+	if state := loadState(); state.ActiveProfile != nil && *state.ActiveProfile != name {
+		index := loadIndex()
+		if meta, err := loadProfileMeta(&index, *state.ActiveProfile); err == nil && meta.AccountUUID == uuid {
+			name = *state.ActiveProfile
+		}
+	}
+	if name == "" {
+		debugf("reconcile: Claude's account isn't saved as a profile")
+		return
+	}
+	if err := reconcileProfile(name, live); err != nil {
+		debugf("reconcile: %s: %v", name, err)
+	}
+}
+
+// reconcileProfile saves live to the profile name if its tokens are newer.
+// A refresh of the profile in progress elsewhere is not waited for.
+func reconcileProfile(name string, live liveAuth) error {
+	unlock, err := lockFileWithin(refreshLock(name), 0)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	store, err := storage()
+	if err != nil {
+		return err
+	}
+	// Loaded from the store directly, so secrets kept in 1Password, which
+	// takes a round trip and may prompt, aren't fetched.
+	var profile *Profile
+	if err := withLock(profileLock(name), func() error {
+		profile, err = store.Load(name)
+		return err
+	}); err != nil {
+		return err
+	}
+	if _, ok := keychainItem(profile.Store); ok {
+		if err := loadExternalSecrets(name, profile); err != nil {
+			return err
+		}
+	}
+	switch creds := profile.Credentials; {
+	case profile.Type != "oauth" || strings.HasPrefix(profile.Store, "op://"):
+		return nil
+	case creds != nil && creds.RefreshToken == live.Credentials.RefreshToken:
+		debugf("reconcile: '%s' is in sync with Claude", name)
+		return nil
+	case creds != nil && creds.ExpiresAt >= fromLocalClock(live.Credentials.ExpiresAt):
+		debugf("reconcile: '%s' holds newer tokens than Claude", name)
+		return nil
+	}
+	if err := adoptLiveCredentials(name, profile, live); err != nil {
+		return err
+	}
+	debugf("reconcile: saved Claude's rotated tokens to '%s'", name)
+	return nil
+}
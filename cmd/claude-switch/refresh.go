@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- refresh: renew OAuth tokens ahead of time ---
+
+// RefreshResult is one profile's outcome in refresh's JSON output.
+type RefreshResult struct {
+	Profile   string  `json:"profile"`
+	Refreshed bool    `json:"refreshed"`
+	Skipped   string  `json:"skipped,omitempty"`
+	ExpiresAt *uint64 `json:"expires_at,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	Code      string  `json:"code,omitempty"`
+}
+
+func cmdRefresh(args []string) error {
+	var names []string
+	var group string
+	all := false
+	var within time.Duration
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--all" || a == "-a":
+			all = true
+		case a == "--within" || strings.HasPrefix(a, "--within="):
+			value, ok := strings.CutPrefix(a, "--within=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a duration (e.g. 24h)", a)
+				}
+				i++
+				value = args[i]
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return usageError("invalid duration '%s' (e.g. 24h)", value)
+			}
+			within = d
+		case a == "--group" || a == "-g" || strings.HasPrefix(a, "--group="):
+			value, ok := strings.CutPrefix(a, "--group=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a group name", a)
+				}
+				i++
+				value = args[i]
+			}
+			group = value
+		case strings.HasPrefix(a, "-"):
+			return usageError("unexpected argument: %s", a)
+		default:
+			names = append(names, a)
+		}
+	}
+	switch {
+	case (all || group != "") && len(names) > 0, all && group != "":
+		return usageError("refresh takes profile names, --all or --group, only one of them")
+	case all:
+		var err error
+		if names, err = listProfiles(); err != nil {
+			return err
+		}
+	case group != "":
+		var err error
+		if names, err = groupMembers(group); err != nil {
+			return err
+		}
+	case len(names) == 0:
+		return usageError("refresh requires a profile name, --all or --group")
+	default:
+		for i, name := range names {
+			var err error
+			if names[i], err = resolveProfileName(name, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	results := []RefreshResult{}
+	failed := 0
+	for _, name := range names {
+		result := refreshOne(name, within)
+		if result.Error != "" {
+			failed++
+		}
+		results = append(results, result)
+		if jsonOutput() {
+			continue
+		}
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(os.Stderr, "%s: failed: %s\n", name, result.Error)
+		case result.Skipped != "":
+			infof("%s: skipped (%s)\n", name, result.Skipped)
+		default:
+			expiry := time.UnixMilli(int64(*result.ExpiresAt)).UTC().Format("2006-01-02 15:04 UTC")
+			infof("%s: refreshed, expires %s\n", name, expiry)
+		}
+	}
+
+	if jsonOutput() {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		if len(names) == 1 {
+			return &cliError{Code: results[0].Code, Message: results[0].Error, Profile: names[0]}
+		}
+		return &cliError{Code: errRefreshFailed, Message: fmt.Sprintf("%d of %d profile(s) failed to refresh", failed, len(names))}
+	}
+	return nil
+}
+
+// refreshOne forces a refresh of one profile unless its token is good for
+// longer than within. If the profile is active, Claude's own newer tokens
+// are synced first, since refreshing the stale saved copy would fail, and
+// the new tokens are handed to Claude afterwards.
+func refreshOne(name string, within time.Duration) RefreshResult {
+	result := RefreshResult{Profile: name}
+	fail := func(err error) RefreshResult {
+		ce := classifyError(err)
+		result.Error, result.Code = err.Error(), ce.Code
+		return result
+	}
+
+	profile, err := loadProfile(name)
+	if err != nil {
+		return fail(err)
+	}
+	if profile.Type != "oauth" {
+		result.Skipped = profile.DescribeType() + " profile"
+		return result
+	}
+
+	state := loadState()
+	active := state.ActiveProfile != nil && *state.ActiveProfile == name
+	if active {
+		if synced, err := syncActiveProfile(); err == nil && synced.Updated && synced.Profile == name {
+			if profile, err = loadProfile(name); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	creds := profile.Credentials
+	if creds == nil {
+		return fail(&cliError{Code: errNoCredentials, Message: fmt.Sprintf("'%s' has no OAuth tokens", name), Profile: name})
+	}
+	if within > 0 && creds.ExpiresAt > nowMs()+uint64(within.Milliseconds()) {
+		result.Skipped = "not expiring within " + within.String()
+		result.ExpiresAt = &creds.ExpiresAt
+		return result
+	}
+
+	before := creds.AccessToken
+	refreshed, err := refreshProfile(name, profile, false)
+	if err != nil {
+		return fail(err)
+	}
+	if refreshed.Credentials.AccessToken == before {
+		// refreshProfile keeps a still-valid token when rate limited
+		return fail(&cliError{Code: errRateLimited, Message: "rate limited; the current token is still valid", Profile: name})
+	}
+	if active {
+		if err := installCredentials(refreshed); err != nil {
+			return fail(err)
+		}
+	}
+	result.Refreshed = true
+	result.ExpiresAt = &refreshed.Credentials.ExpiresAt
+	return result
+}
+
+// forceRefresh refreshes an OAuth profile's token even if it isn't close to
+// expiry, for use --refresh and exec --refresh. Other profiles are left as
+// they are. A dead refresh token means a new login if reauth is set.
+func forceRefresh(name string, reauth bool) error {
+	result := refreshOne(name, 0)
+	switch {
+	case result.Refreshed:
+		infof("Refreshed the token of '%s'\n", name)
+	case result.Code == errReauthRequired && reauth:
+		_, err := reauthenticateProfile(name)
+		return err
+	case result.Error != "":
+		return &cliError{Code: result.Code, Message: result.Error, Profile: name}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// --- sync-remote: share the profile store through remote object storage ---
+
+// The remote holds a single encrypted archive in the backup format (see
+// archive.go). push replaces it with the local profiles; pull restores it,
+// so a CI job can fetch a shared set of accounts at start.
+
+type RemoteConfig struct {
+	// Which remoteProviders entry to use; --provider overrides it.
+	Provider string   `toml:"provider"`
+	S3       S3Config `toml:"s3"`
+}
+
+type remoteStore interface {
+	// Get returns the archive, or nil if nothing has been pushed yet.
+	Get() ([]byte, error)
+	Put(data []byte) error
+	String() string
+}
+
+// remoteProviders maps the [remote] provider setting to a constructor.
+var remoteProviders = map[string]func(cfg *Config) (remoteStore, error){
+	"s3": newS3Remote,
+}
+
+func openRemote(provider string) (remoteStore, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if provider == "" {
+		provider = cfg.Remote.Provider
+	}
+	var known []string
+	for k := range remoteProviders {
+		known = append(known, k)
+	}
+	sort.Strings(known)
+	if provider == "" {
+		return nil, &cliError{
+			Code:    errConfig,
+			Message: "no remote configured",
+			Hint:    fmt.Sprintf("set [remote] provider in %s or pass --provider (%s)", configPath(), strings.Join(known, ", ")),
+		}
+	}
+	newRemote, ok := remoteProviders[provider]
+	if !ok {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("unknown remote provider '%s'", provider), Hint: "expected one of: " + strings.Join(known, ", ")}
+	}
+	return newRemote(cfg)
+}
+
+func cmdSyncRemote(args []string) error {
+	var action, provider string
+	keepLocal := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--provider" || strings.HasPrefix(a, "--provider="):
+			value, ok := strings.CutPrefix(a, "--provider=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a provider name", a)
+				}
+				i++
+				value = args[i]
+			}
+			provider = value
+		case a == "--keep-local":
+			keepLocal = true
+		case action == "" && !strings.HasPrefix(a, "-"):
+			action = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if action != "push" && action != "pull" {
+		return usageError("sync-remote requires an action: push or pull")
+	}
+	if keepLocal && action != "pull" {
+		return usageError("--keep-local only applies to pull")
+	}
+	remote, err := openRemote(provider)
+	if err != nil {
+		return err
+	}
+
+	if action == "push" {
+		data, count, err := buildArchive(true)
+		if err != nil {
+			return err
+		}
+		if err := remote.Put(data); err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(map[string]any{"remote": remote.String(), "pushed": count})
+		}
+		infof("Pushed %d profile(s) to %s.\n", count, remote)
+		return nil
+	}
+
+	data, err := remote.Get()
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return &cliError{Code: errProfileNotFound, Message: fmt.Sprintf("nothing has been pushed to %s yet", remote), Hint: "run 'claude-switch sync-remote push' first"}
+	}
+	payload, err := parseArchive(data, remote.String())
+	if err != nil {
+		return err
+	}
+	restored, skipped, err := restorePayload(payload, !keepLocal)
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"remote": remote.String(), "pulled": restored, "skipped": skipped})
+	}
+	infof("Pulled %d profile(s) from %s", len(restored), remote)
+	if len(skipped) > 0 {
+		infof("; kept %d local one(s): %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	infof(".\n")
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// --- Token revocation (remove --revoke) ---
+
+// Deleting a profile leaves its tokens valid until they expire, and a
+// refresh token doesn't expire. `remove --revoke` first asks the OAuth
+// server to revoke them, per RFC 7009, at the revocation endpoint next to
+// the token endpoint.
+var revokeURL = "https://platform.claude.com/v1/oauth/revoke"
+
+// revokeProfile revokes the profile's OAuth tokens. API keys can only be
+// deleted in the Console, so they are left alone with a warning.
+func revokeProfile(name string, profile *Profile) error {
+	var tokens [][2]string // token, token_type_hint
+	switch profile.Type {
+	case "oauth":
+		if creds := profile.Credentials; creds != nil {
+			// The refresh token first: it's the one that lasts.
+			if creds.RefreshToken != "" {
+				tokens = append(tokens, [2]string{creds.RefreshToken, "refresh_token"})
+			}
+			if creds.AccessToken != "" {
+				tokens = append(tokens, [2]string{creds.AccessToken, "access_token"})
+			}
+		}
+	case "oauth_token":
+		tokens = append(tokens, [2]string{profile.OAuthToken, "access_token"})
+	}
+	if profile.FallbackApiKey != "" || profile.Type == "api_key" {
+		fmt.Fprintf(os.Stderr, "Warning: API keys of '%s' can't be revoked from here; delete them in the Anthropic Console.\n", name)
+	}
+	for _, t := range tokens {
+		if err := revokeToken(t[0], t[1], profile.Proxy); err != nil {
+			return &cliError{
+				Code:    errRefreshFailed,
+				Message: fmt.Sprintf("failed to revoke the %s of '%s': %v", strings.ReplaceAll(t[1], "_", " "), name, err),
+				Profile: name,
+				Hint:    fmt.Sprintf("the profile was kept; run 'claude-switch remove %s' without --revoke to delete it anyway", name),
+				Err:     err,
+			}
+		}
+	}
+	return nil
+}
+
+func revokeToken(token, hint string, proxy *ProxySettings) error {
+	if sandboxed() {
+		infof("[sandbox] revoking %s\n", strings.ReplaceAll(hint, "_", " "))
+		return nil
+	}
+	if revokeURL == "" {
+		return fmt.Errorf("no revocation endpoint for token_url %s; set revoke_url under [oauth] in %s", tokenURL, configPath())
+	}
+	form := url.Values{"token": {token}, "token_type_hint": {hint}, "client_id": {clientID}}
+	req, err := http.NewRequest("POST", revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+
+	client := &http.Client{Transport: proxyTransport(proxy), Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	// An already invalid token also gets a 200, so any success will do.
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("revocation failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import "os"
+
+// --- run: exec with the profile picked from the environment ---
+
+// cmdRun is exec for wrappers and Makefiles that shouldn't name a profile:
+// it takes the first of CLAUDE_SWITCH_PROFILE, a .claude-profile file and
+// the default profile from config.toml.
+func cmdRun(args []string) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return usageError("no command specified")
+	}
+	name, err := runProfile()
+	if err != nil {
+		return err
+	}
+	auditProfile = name
+	vars, err := credentialEnvVars(name, true)
+	if err != nil {
+		return err
+	}
+	if args[0] == "claude" {
+		if args[0], err = resolveClaude(name); err != nil {
+			return err
+		}
+	}
+	markUsed(name)
+	return execWithEnv(args, append(vars, envVar{"CLAUDE_SWITCH_PROFILE", name}))
+}
+
+// runProfile returns the profile run uses.
+func runProfile() (string, error) {
+	if name := os.Getenv("CLAUDE_SWITCH_PROFILE"); name != "" {
+		return name, nil
+	}
+	name, _, err := cwdProfile()
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		return name, nil
+	}
+	if defaults.Profile != "" {
+		return defaults.Profile, nil
+	}
+	return "", &cliError{
+		Code:    errProfileNotFound,
+		Message: "no profile to run under",
+		Hint:    "set CLAUDE_SWITCH_PROFILE, add a .claude-profile file, or pick a default with 'claude-switch default <name>'",
+	}
+}
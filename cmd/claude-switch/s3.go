@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// --- S3-compatible remote (AWS S3, MinIO, R2, ...) ---
+
+// Requests are signed with AWS Signature Version 4 directly rather than
+// through an SDK; two object operations don't justify the dependency.
+// Credentials come from the standard AWS_* environment variables.
+
+type S3Config struct {
+	Bucket string `toml:"bucket"`
+	// Object key of the archive; defaults to claude-switch/profiles.json.
+	Key    string `toml:"key"`
+	Region string `toml:"region"`
+	// Endpoint for S3-compatible services, e.g. https://minio.internal:9000.
+	Endpoint string `toml:"endpoint"`
+	// PathStyle addresses the bucket as endpoint/bucket rather than as a
+	// bucket.endpoint host name, which most self-hosted services need.
+	PathStyle bool `toml:"path_style"`
+}
+
+type s3Remote struct {
+	bucket, key, region string
+	endpoint            *url.URL
+	pathStyle           bool
+	accessKey, secret   string
+	sessionToken        string
+	client              *http.Client
+}
+
+func newS3Remote(cfg *Config) (remoteStore, error) {
+	sc := cfg.Remote.S3
+	r := &s3Remote{
+		bucket:    sc.Bucket,
+		key:       strings.TrimPrefix(firstNonEmpty(sc.Key, "claude-switch/profiles.json"), "/"),
+		region:    firstNonEmpty(sc.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1"),
+		pathStyle: sc.PathStyle,
+		client:    &http.Client{Transport: withDebug(nil), Timeout: 30 * time.Second},
+	}
+	if r.bucket == "" {
+		return nil, &cliError{Code: errConfig, Message: "no S3 bucket configured", Hint: fmt.Sprintf("set [remote.s3] bucket in %s", configPath())}
+	}
+	if sandboxed() {
+		return r, nil
+	}
+	endpoint := firstNonEmpty(sc.Endpoint, os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL"), fmt.Sprintf("https://s3.%s.amazonaws.com", r.region))
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("invalid S3 endpoint '%s'", endpoint)}
+	}
+	r.endpoint = u
+	r.accessKey, r.secret = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	r.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	if r.accessKey == "" || r.secret == "" {
+		return nil, &cliError{Code: errConfig, Message: "no S3 credentials available", Hint: "set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY"}
+	}
+	return r, nil
+}
+
+func (r *s3Remote) String() string {
+	return fmt.Sprintf("s3://%s/%s", r.bucket, r.key)
+}
+
+// sandboxPath stands in for the object in sandbox mode, which makes no
+// network requests.
+func (r *s3Remote) sandboxPath() string {
+	return filepath.Join(sandboxDir(), "s3", r.bucket, filepath.FromSlash(r.key))
+}
+
+func (r *s3Remote) Get() ([]byte, error) {
+	if sandboxed() {
+		data, err := readFile(r.sandboxPath())
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+	return r.do(http.MethodGet, nil)
+}
+
+func (r *s3Remote) Put(data []byte) error {
+	if sandboxed() {
+		return writeSecure(r.sandboxPath(), data)
+	}
+	_, err := r.do(http.MethodPut, data)
+	return err
+}
+
+// objectURL returns the object's URL in virtual-hosted or path style.
+func (r *s3Remote) objectURL() *url.URL {
+	u := *r.endpoint
+	key := s3EscapePath(r.key)
+	if r.pathStyle {
+		u.Path = u.Path + "/" + s3EscapePath(r.bucket) + "/" + key
+	} else {
+		u.Host = r.bucket + "." + u.Host
+		u.Path = u.Path + "/" + key
+	}
+	u.RawPath = u.Path
+	return &u
+}
+
+// do sends one signed request. A missing object is a nil body and nil error.
+func (r *s3Remote) do(method string, body []byte) ([]byte, error) {
+	u := r.objectURL()
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.URL.RawPath = u.RawPath
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r.sign(req, body, time.Now().UTC())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read S3 response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound && method == http.MethodGet:
+		return nil, nil
+	case resp.StatusCode >= 300:
+		return nil, &cliError{Code: errGeneric, Message: fmt.Sprintf("S3 %s %s returned HTTP %d: %s", method, r, resp.StatusCode, s3ErrorMessage(data))}
+	}
+	return data, nil
+}
+
+// s3ErrorMessage pulls the <Code> and <Message> out of an S3 XML error.
+func s3ErrorMessage(data []byte) string {
+	field := func(tag string) string {
+		s := string(data)
+		start := strings.Index(s, "<"+tag+">")
+		end := strings.Index(s, "</"+tag+">")
+		if start < 0 || end < start {
+			return ""
+		}
+		return s[start+len(tag)+2 : end]
+	}
+	if code := field("Code"); code != "" {
+		return strings.TrimSpace(code + " " + field("Message"))
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// sign adds an AWS Signature Version 4 Authorization header, covering the
+// Host header and every x-amz-* header.
+func (r *s3Remote) sign(req *http.Request, body []byte, now time.Time) {
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if r.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", r.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{req.Method, path, req.URL.Query().Encode(), canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + r.region + "/s3/aws4_request"
+	canonicalSum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalSum[:])
+
+	key := []byte("AWS4" + r.secret)
+	for _, part := range []string{date, r.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", r.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EscapePath URI-encodes an object key the way SigV4 expects: every byte
+// except unreserved characters and the / separators.
+func s3EscapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- Sandbox mode (CLAUDE_SWITCH_SANDBOX=1) ---
+//
+// In sandbox mode every path resolves inside a fixture directory seeded with
+// fake profiles, the keychain and running-process checks are skipped, token
+// refreshes are answered locally and `claude /login` is replaced by a fake
+// login. Nothing outside the sandbox directory is read or written and no
+// network requests are made.
+
+// Refresh tokens with this value fail with invalid_grant, so wrappers can
+// exercise the re-authentication path.
+const sandboxRevokedToken = "sk-ant-REDACTED"
+
+func sandboxed() bool {
+	v := os.Getenv("CLAUDE_SWITCH_SANDBOX")
+	return v != "" && v != "0" && v != "false"
+}
+
+// sandboxDir is per-user and stable across invocations so a sequence of
+// commands sees the same store. CLAUDE_SWITCH_SANDBOX_DIR gives each test
+// its own.
+func sandboxDir() string {
+	if dir := os.Getenv("CLAUDE_SWITCH_SANDBOX_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("claude-switch-sandbox-%d", os.Getuid()))
+}
+
+func sandboxToken(kind string) string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return fmt.Sprintf("sk-ant-%s01-sandbox-%s", kind, hex.EncodeToString(buf))
+}
+
+// sandboxUUID derives a stable, UUID-shaped identifier from a seed string.
+func sandboxUUID(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	h := hex.EncodeToString(sum[:16])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+func sandboxAccount(email, org string) json.RawMessage {
+	account, _ := json.Marshal(map[string]string{
+		"accountUuid":      sandboxUUID(email),
+		"emailAddress":     email,
+		"organizationUuid": sandboxUUID(org),
+		"organizationName": org,
+	})
+	return account
+}
+
+func sandboxCredentials(plan string, expiresAt uint64, refreshToken string) *OAuthCredentials {
+	if refreshToken == "" {
+		refreshToken = sandboxToken("ort")
+	}
+	return &OAuthCredentials{
+		AccessToken:      sandboxToken("oat"),
+		RefreshToken:     refreshToken,
+		ExpiresAt:        expiresAt,
+		Scopes:           strings.Fields(scopes),
+		SubscriptionType: &plan,
+	}
+}
+
+// seedSandbox creates the fixture store on first use: a logged-in "work"
+// account, an expired "personal" one that refreshes, a "revoked" one whose
+// refresh token is dead, and an API key profile.
+func seedSandbox() error {
+	if _, err := os.Stat(profilesDir()); err == nil {
+		return nil
+	}
+
+	hour := uint64(60 * 60 * 1000)
+	fixtures := map[string]*Profile{
+		"work": {
+			Type:        "oauth",
+			Credentials: sandboxCredentials("max", nowMs()+8*hour, ""),
+			Account:     sandboxAccount("work@example.com", "Example Corp"),
+		},
+		"personal": {
+			Type:        "oauth",
+			Credentials: sandboxCredentials("pro", nowMs()-hour, ""),
+			Account:     sandboxAccount("me@example.org", "Personal"),
+		},
+		"revoked": {
+			Type:        "oauth",
+			Credentials: sandboxCredentials("pro", nowMs()-24*hour, sandboxRevokedToken),
+			Account:     sandboxAccount("old@example.net", "Old Team"),
+		},
+		"ci": {
+			Type:   "api_key",
+			ApiKey: sandboxToken("api"),
+		},
+	}
+	for name, profile := range fixtures {
+		if err := saveProfile(name, profile); err != nil {
+			return err
+		}
+	}
+
+	work := fixtures["work"]
+	if err := writeCredentials(work.Credentials); err != nil {
+		return err
+	}
+	if err := writeOAuthAccount(work.Account); err != nil {
+		return err
+	}
+	active := "work"
+	return saveState(&State{ActiveProfile: &active})
+}
+
+// sandboxLogin stands in for `claude /login`, leaving a new fake account in
+// the sandbox's Claude config.
+// sandboxLoginFails is set by CLAUDE_SWITCH_SANDBOX_LOGIN=fail, so wrappers
+// can exercise what happens when a login is abandoned.
+func sandboxLoginFails() bool {
+	return os.Getenv("CLAUDE_SWITCH_SANDBOX_LOGIN") == "fail"
+}
+
+func sandboxLogin() error {
+	infof("[sandbox] simulating Claude login\n")
+	if sandboxLoginFails() {
+		return errors.New("login aborted")
+	}
+	id := sandboxToken("id")
+	email := fmt.Sprintf("user-%s@example.com", id[len(id)-6:])
+	creds := sandboxCredentials("max", nowMs()+8*60*60*1000, "")
+	if err := writeCredentials(creds); err != nil {
+		return err
+	}
+	return writeOAuthAccount(sandboxAccount(email, "Sandbox Org"))
+}
+
+// sandboxOAuthLogin stands in for the browser login, returning a new fake
+// account without touching the sandbox's Claude config.
+func sandboxOAuthLogin() (*Profile, error) {
+	infof("[sandbox] simulating browser login\n")
+	if sandboxLoginFails() {
+		return nil, errors.New("login aborted")
+	}
+	id := sandboxToken("id")
+	return &Profile{
+		Type:        "oauth",
+		Credentials: sandboxCredentials("max", nowMs()+8*60*60*1000, ""),
+		Account:     sandboxAccount(fmt.Sprintf("user-%s@example.com", id[len(id)-6:]), "Sandbox Org"),
+	}, nil
+}
+
+func sandboxRefresh(creds *OAuthCredentials) (*OAuthCredentials, error) {
+	if creds.RefreshToken == sandboxRevokedToken {
+		return nil, &RefreshError{Kind: refreshInvalidGrant, Message: "invalid_grant"}
+	}
+	refreshed := *creds
+	refreshed.AccessToken = sandboxToken("oat")
+	refreshed.RefreshToken = sandboxToken("ort")
+	refreshed.ExpiresAt = nowMs() + 8*60*60*1000
+	return &refreshed, nil
+}
+
+// sandboxUsage makes up usage that stays the same for a profile, so list
+// --usage shows a spread of values.
+func sandboxUsage(profile *Profile) *Usage {
+	sum := sha256.Sum256([]byte(claudeswitch.AccountField(profile.Account, "accountUuid")))
+	now := time.Now().UTC().Truncate(time.Minute)
+	fiveHour := now.Add(time.Duration(sum[1]%240+10) * time.Minute)
+	sevenDay := now.Add(time.Duration(sum[2]%150+2) * time.Hour)
+	return &Usage{
+		FiveHour: &UsageWindow{Utilization: float64(sum[0] % 101), ResetsAt: &fiveHour},
+		SevenDay: &UsageWindow{Utilization: float64(sum[3] % 101), ResetsAt: &sevenDay},
+	}
+}
+
+func sandboxIdentity(accessToken string) (*OAuthIdentity, error) {
+	if !strings.HasPrefix(accessToken, "sk-ant-oat01-sandbox-") {
+		return nil, &cliError{Code: errTokenRejected, Message: "access token was rejected (401)"}
+	}
+	plan := "max"
+	return &OAuthIdentity{
+		Account:          sandboxAccount("manual@example.com", "Sandbox Org"),
+		SubscriptionType: &plan,
+	}, nil
+}
+
+// sandboxOrganizations puts every fake account in all the fixture
+// organizations, so org use can move any of them around.
+func sandboxOrganizations(accessToken string) ([]Organization, error) {
+	if !strings.HasPrefix(accessToken, "sk-ant-oat01-sandbox-") {
+		return nil, &cliError{Code: errTokenRejected, Message: "access token was rejected (401)"}
+	}
+	var orgs []Organization
+	for _, o := range []struct{ name, plan, tier, role string }{
+		{"Example Corp", "max", "default_claude_max_20x", "admin"},
+		{"Personal", "pro", "", "admin"},
+		{"Old Team", "team", "default_claude_team", "developer"},
+		{"Sandbox Org", "max", "default_claude_max_5x", "user"},
+	} {
+		orgs = append(orgs, Organization{UUID: sandboxUUID(o.name), Name: o.name, Plan: o.plan, RateLimitTier: o.tier, Role: o.role})
+	}
+	return orgs, nil
+}
+
+// sandboxModelsAccess accepts the fixture API keys and long-lived tokens,
+// which all carry the sandbox marker.
+func sandboxModelsAccess(credential string) error {
+	if !strings.Contains(credential, "01-sandbox-") {
+		return &cliError{Code: errTokenRejected, Message: "credentials were rejected (401)"}
+	}
+	return nil
+}
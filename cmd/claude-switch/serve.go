@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// --- Local REST API (serve) ---
+
+// serve answers list, use, token and refresh over HTTP on a loopback
+// address, for editors, GUIs and scripts that would otherwise run the CLI
+// for every call. Each request needs the bearer token that the server
+// writes, along with its URL, to serve.json in the config directory, which
+// only the user can read. api is a client for trying it out.
+
+type serveInfo struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+	PID   int    `json:"pid"`
+}
+
+func serveInfoPath() string {
+	return filepath.Join(configDir(), "serve.json")
+}
+
+func cmdServe(args []string) error {
+	listen := "127.0.0.1:0"
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--listen" || a == "-l" || strings.HasPrefix(a, "--listen="):
+			value, ok := strings.CutPrefix(a, "--listen=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires an address (e.g. 127.0.0.1:8787)", a)
+				}
+				i++
+				value = args[i]
+			}
+			listen = value
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return usageError("invalid address '%s' (expected host:port, e.g. 127.0.0.1:0)", listen)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return usageError("serve only listens on loopback addresses, not '%s'", host)
+	}
+	if encryptionEnabled() {
+		if _, err := unlockProfiles(); err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	info := serveInfo{URL: "http://" + ln.Addr().String(), Token: randomURLToken(32), PID: os.Getpid()}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	if err := writeSecure(serveInfoPath(), data); err != nil {
+		ln.Close()
+		return err
+	}
+	defer removeServeInfo()
+
+	server := &http.Server{Handler: serveHandler(info.Token), ReadHeaderTimeout: 10 * time.Second}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	if jsonOutput() {
+		printJSON(map[string]any{"url": info.URL, "pid": info.PID, "token_file": serveInfoPath()})
+	} else {
+		fmt.Println(info.URL)
+		infof("Serving on %s; the token is in %s. Ctrl-C to stop.\n", info.URL, serveInfoPath())
+	}
+	daemonLog("serving on %s", info.URL)
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	daemonLog("stopped")
+	return nil
+}
+
+// removeServeInfo removes serve.json unless another server has replaced it.
+func removeServeInfo() {
+	if info, err := readServeInfo(); err == nil && info.PID == os.Getpid() {
+		os.Remove(serveInfoPath())
+	}
+}
+
+func readServeInfo() (*serveInfo, error) {
+	data, err := readFile(serveInfoPath())
+	if err != nil {
+		return nil, err
+	}
+	var info serveInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", serveInfoPath(), err)
+	}
+	return &info, nil
+}
+
+func serveHandler(token string) http.Handler {
+	// Requests are served one at a time, as in the agent: two concurrent
+	// refreshes of the same profile would race to rotate its refresh token.
+	var mu sync.Mutex
+	handle := func(fn func(r *http.Request) (any, error)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			auth, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+				daemonLog("%s %s: unauthorized", r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusUnauthorized)
+				writeErrorJSON(w, &cliError{Code: errUnauthorized, Message: "missing or wrong bearer token", Hint: "send the token from " + serveInfoPath()})
+				return
+			}
+			mu.Lock()
+			result, err := fn(r)
+			mu.Unlock()
+			if err != nil {
+				status := serveStatus(classifyError(err).Code)
+				daemonLog("%s %s: %d %v", r.Method, r.URL.Path, status, err)
+				w.WriteHeader(status)
+				writeErrorJSON(w, err)
+				return
+			}
+			daemonLog("%s %s: 200", r.Method, r.URL.Path)
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(result)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/profiles", handle(func(r *http.Request) (any, error) {
+		names, err := listProfiles()
+		if err != nil {
+			return nil, err
+		}
+		return listSummaries(names, nil, nil), nil
+	}))
+	mux.HandleFunc("GET /v1/profiles/{name}", handle(func(r *http.Request) (any, error) {
+		name, err := serveProfileName(r)
+		if err != nil {
+			return nil, err
+		}
+		index := loadIndex()
+		meta, err := loadProfileMeta(&index, name)
+		if err != nil {
+			return nil, err
+		}
+		return summarize(name, meta), nil
+	}))
+	mux.HandleFunc("POST /v1/profiles/{name}/use", handle(func(r *http.Request) (any, error) {
+		name, err := serveProfileName(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := serveSettleSessions(r.URL.Query().Get("sessions")); err != nil {
+			return nil, err
+		}
+		profile, err := activateProfile(name, false)
+		if err != nil {
+			return nil, err
+		}
+		return summarizeProfile(name, profile), nil
+	}))
+	mux.HandleFunc("GET /v1/profiles/{name}/token", handle(func(r *http.Request) (any, error) {
+		name, err := serveProfileName(r)
+		if err != nil {
+			return nil, err
+		}
+		vars, err := profileEnvVars(name, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(vars) == 0 {
+			return nil, &cliError{Code: errNoCredentials, Message: "no credential found", Profile: name}
+		}
+		return map[string]string{"profile": name, "variable": vars[0].Key, "token": vars[0].Value}, nil
+	}))
+	mux.HandleFunc("POST /v1/profiles/{name}/refresh", handle(func(r *http.Request) (any, error) {
+		name, err := serveProfileName(r)
+		if err != nil {
+			return nil, err
+		}
+		var within time.Duration
+		if value := r.URL.Query().Get("within"); value != "" {
+			if within, err = time.ParseDuration(value); err != nil || within <= 0 {
+				return nil, usageError("invalid duration '%s' (e.g. 24h)", value)
+			}
+		}
+		result := refreshOne(name, within)
+		if result.Error != "" {
+			return nil, &cliError{Code: result.Code, Message: result.Error, Profile: name}
+		}
+		return result, nil
+	}))
+	mux.HandleFunc("/", handle(func(r *http.Request) (any, error) {
+		return nil, &cliError{Code: errUsage, Message: fmt.Sprintf("no endpoint %s %s", r.Method, r.URL.Path)}
+	}))
+	return mux
+}
+
+// serveProfileName takes the profile from the path. Names must be given in
+// full: a prefix that happens to match would switch to the wrong account.
+func serveProfileName(r *http.Request) (string, error) {
+	return resolveProfileName(r.PathValue("name"), false)
+}
+
+// serveSettleSessions is settleSessions without the prompt: with running
+// Claude sessions, use needs sessions=force or sessions=kill. Waiting isn't
+// offered, since it would hold up every other request.
+func serveSettleSessions(mode string) error {
+	switch mode {
+	case sessionsForce, sessionsKill:
+		return settleSessions(mode)
+	case "":
+	default:
+		return usageError("invalid sessions '%s' (expected force or kill)", mode)
+	}
+	if sessions := claudeSessions(); len(sessions) > 0 {
+		return &cliError{
+			Code:    errClaudeRunning,
+			Message: fmt.Sprintf("%s using %s; switching now would leave Claude on the old account", describeSessions(sessions), claudeConfigDir()),
+			Hint:    "quit Claude first, or pass sessions=kill or sessions=force",
+		}
+	}
+	return nil
+}
+
+// serveStatus is the HTTP status of an error code.
+func serveStatus(code string) int {
+	switch code {
+	case errUsage, errInvalidName, errAmbiguousName:
+		return http.StatusBadRequest
+	case errUnauthorized:
+		return http.StatusUnauthorized
+	case errProfileNotFound:
+		return http.StatusNotFound
+	case errClaudeRunning, errLocked, errReauthRequired, errNoCredentials:
+		return http.StatusConflict
+	case errRateLimited:
+		return http.StatusTooManyRequests
+	case errNetwork, errRefreshFailed:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// --- api: a client for serve ---
+
+func cmdAPI(args []string) error {
+	method := "GET"
+	var path string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-X" || a == "--method" || strings.HasPrefix(a, "--method="):
+			value, ok := strings.CutPrefix(a, "--method=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a method (GET or POST)", a)
+				}
+				i++
+				value = args[i]
+			}
+			method = strings.ToUpper(value)
+		case strings.HasPrefix(a, "-"):
+			return usageError("unexpected argument: %s", a)
+		case path != "":
+			return usageError("api takes a single path")
+		default:
+			path = a
+		}
+	}
+	if path == "" {
+		return usageError("api requires a path, e.g. /v1/profiles")
+	}
+
+	info, err := readServeInfo()
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cliError{Code: errGeneric, Message: "no server is running", Hint: "start one with 'claude-switch serve'"}
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	req, err := http.NewRequest(method, info.URL+path, nil)
+	if err != nil {
+		return usageError("invalid request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+info.Token)
+	client := &http.Client{Transport: withDebug(nil), Timeout: 5 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &cliError{Code: errNetwork, Message: fmt.Sprintf("no server answered at %s", info.URL), Hint: "start one with 'claude-switch serve'", Err: err}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var envelope struct {
+			Error *struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+				Profile string `json:"profile"`
+				Hint    string `json:"hint"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &envelope) == nil && envelope.Error != nil {
+			e := envelope.Error
+			return &cliError{Code: e.Code, Message: e.Message, Profile: e.Profile, Hint: e.Hint}
+		}
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	_, err = os.Stdout.Write(body)
+	return err
+}
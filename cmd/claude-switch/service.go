@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- service: run `refresh --all` on a schedule (launchd / systemd) ---
+
+const (
+	defaultServiceInterval = 6 * time.Hour
+	minServiceInterval     = 15 * time.Minute
+	defaultServiceWithin   = 24 * time.Hour
+)
+
+// serviceEnvVars are passed on to the scheduled job when set, since launchd
+// and systemd start it without the login shell's environment. Secrets such
+// as CLAUDE_SWITCH_PASSPHRASE are never written into a unit file.
+var serviceEnvVars = []string{"XDG_CONFIG_HOME", "CLAUDE_SWITCH_CONFIG_DIR", "CLAUDE_CONFIG_DIR", "HTTPS_PROXY", "VAULT_ADDR", "CLAUDE_SWITCH_SANDBOX", "CLAUDE_SWITCH_SANDBOX_DIR"}
+
+// serviceSpec describes the scheduled job the platform files turn into a
+// launchd plist or systemd units.
+type serviceSpec struct {
+	Program  string
+	Args     []string
+	Env      []envVar
+	Interval time.Duration
+	LogPath  string
+}
+
+// ServiceStatus is the output of `service status`.
+type ServiceStatus struct {
+	Installed bool     `json:"installed"`
+	Loaded    bool     `json:"loaded"`
+	Files     []string `json:"files"`
+	Schedule  string   `json:"schedule,omitempty"`
+	LogPath   string   `json:"log"`
+}
+
+func serviceLogPath() string {
+	return filepath.Join(configDir(), "refresh.log")
+}
+
+// serviceDir is where the unit files go: a per-user location for the
+// platform's scheduler, or a directory inside the sandbox.
+func serviceDir(userDir ...string) string {
+	if sandboxed() {
+		return filepath.Join(sandboxDir(), "services")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(append([]string{home}, userDir...)...)
+}
+
+func cmdService(args []string) error {
+	if len(args) == 0 {
+		return usageError("service requires an action: install, uninstall or status")
+	}
+	action, args := args[0], args[1:]
+	switch action {
+	case "install":
+		return serviceInstall(args)
+	case "uninstall":
+		if len(args) > 0 {
+			return usageError("unexpected argument: %s", args[0])
+		}
+		removed, err := uninstallService()
+		if err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(map[string]any{"removed": removed})
+		}
+		if len(removed) == 0 {
+			infof("The refresh service is not installed.\n")
+			return nil
+		}
+		for _, path := range removed {
+			infof("Removed %s\n", path)
+		}
+		return nil
+	case "status":
+		if len(args) > 0 {
+			return usageError("unexpected argument: %s", args[0])
+		}
+		status, err := serviceStatus()
+		if err != nil {
+			return err
+		}
+		status.LogPath = serviceLogPath()
+		if jsonOutput() {
+			return printJSON(status)
+		}
+		switch {
+		case !status.Installed:
+			fmt.Println("not installed")
+		case status.Loaded:
+			fmt.Printf("installed and loaded (%s)\n", status.Schedule)
+		default:
+			fmt.Printf("installed but not loaded (%s)\n", status.Schedule)
+		}
+		for _, path := range status.Files {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Printf("  log: %s\n", status.LogPath)
+		return nil
+	default:
+		return usageError("unknown service action '%s' (expected install, uninstall or status)", action)
+	}
+}
+
+func serviceInstall(args []string) error {
+	interval, within := defaultServiceInterval, defaultServiceWithin
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		var target *time.Duration
+		var flag string
+		switch {
+		case a == "--interval" || strings.HasPrefix(a, "--interval="):
+			target, flag = &interval, "--interval"
+		case a == "--within" || strings.HasPrefix(a, "--within="):
+			target, flag = &within, "--within"
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+		value, ok := strings.CutPrefix(a, flag+"=")
+		if !ok {
+			if i+1 >= len(args) {
+				return usageError("%s requires a duration (e.g. 6h)", a)
+			}
+			i++
+			value = args[i]
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return usageError("invalid duration '%s' (e.g. 6h)", value)
+		}
+		*target = d
+	}
+	if interval < minServiceInterval {
+		return usageError("interval must be at least %s", compactDuration(minServiceInterval))
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	spec := serviceSpec{Program: exe, Interval: interval, LogPath: serviceLogPath()}
+	if backendOverride != "" {
+		spec.Args = append(spec.Args, "--backend", backendOverride)
+	}
+	spec.Args = append(spec.Args, "refresh", "--all", "--within", compactDuration(within))
+	for _, key := range serviceEnvVars {
+		if value := os.Getenv(key); value != "" {
+			spec.Env = append(spec.Env, envVar{key, value})
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(spec.LogPath), 0o755); err != nil {
+		return err
+	}
+
+	files, err := installService(spec)
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"files": files, "interval": compactDuration(interval), "within": compactDuration(within), "log": spec.LogPath})
+	}
+	for _, path := range files {
+		infof("Wrote %s\n", path)
+	}
+	infof("Refreshing tokens expiring within %s every %s; log: %s\n", compactDuration(within), compactDuration(interval), spec.LogPath)
+	if encryptionEnabled() {
+		fmt.Fprintln(os.Stderr, "Warning: profiles are encrypted and the service can't prompt for the passphrase, so its refreshes will fail.")
+	}
+	return nil
+}
+
+// compactDuration formats d like time.Duration.String without the trailing
+// zero units ("6h" rather than "6h0m0s"), which both Go and systemd parse.
+func compactDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+//go:build darwin
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const launchdLabel = "com.github.claude-switch.refresh"
+
+func launchdPlistPath() string {
+	return filepath.Join(serviceDir("Library", "LaunchAgents"), launchdLabel+".plist")
+}
+
+func plistString(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return "<string>" + b.String() + "</string>"
+}
+
+func installService(spec serviceSpec) ([]string, error) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t%s\n", plistString(launchdLabel))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, a := range append([]string{spec.Program}, spec.Args...) {
+		fmt.Fprintf(&b, "\t\t%s\n", plistString(a))
+	}
+	b.WriteString("\t</array>\n")
+	if len(spec.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, v := range spec.Env {
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t%s\n", v.Key, plistString(v.Value))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	// launchd runs a missed StartInterval job once the Mac wakes up.
+	fmt.Fprintf(&b, "\t<key>StartInterval</key>\n\t<integer>%d</integer>\n", int(spec.Interval.Seconds()))
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t%s\n", plistString(spec.LogPath))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t%s\n", plistString(spec.LogPath))
+	b.WriteString("</dict>\n</plist>\n")
+
+	path := launchdPlistPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	// Unload any previous version so a reinstall picks up the new plist.
+	launchctl("bootout", launchdDomain()+"/"+launchdLabel)
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return nil, err
+	}
+	if err := launchctl("bootstrap", launchdDomain(), path); err != nil {
+		return []string{path}, &cliError{
+			Code:    errGeneric,
+			Message: fmt.Sprintf("launchctl bootstrap failed: %v", err),
+			Hint:    fmt.Sprintf("the plist was written; load it with 'launchctl bootstrap %s %s'", launchdDomain(), path),
+			Err:     err,
+		}
+	}
+	return []string{path}, nil
+}
+
+func uninstallService() ([]string, error) {
+	path := launchdPlistPath()
+	launchctl("bootout", launchdDomain()+"/"+launchdLabel)
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []string{path}, nil
+}
+
+var startIntervalRe = regexp.MustCompile(`<key>StartInterval</key>\s*<integer>(\d+)</integer>`)
+
+func serviceStatus() (ServiceStatus, error) {
+	var status ServiceStatus
+	path := launchdPlistPath()
+	data, err := readFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	status.Installed = true
+	status.Files = []string{path}
+	if m := startIntervalRe.FindSubmatch(data); m != nil {
+		if d, err := time.ParseDuration(string(m[1]) + "s"); err == nil {
+			status.Schedule = "every " + compactDuration(d)
+		}
+	}
+	status.Loaded = sandboxed() || launchctl("print", launchdDomain()+"/"+launchdLabel) == nil
+	return status, nil
+}
+
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// launchctl runs launchctl, except in the sandbox where the plist is only
+// written.
+func launchctl(args ...string) error {
+	if sandboxed() {
+		return nil
+	}
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
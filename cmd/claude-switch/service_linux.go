@@ -0,0 +1,147 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// A oneshot service runs the refresh and a timer starts it every interval
+// after boot, so a machine that was off simply refreshes once it is up.
+const (
+	systemdServiceName = "claude-switch-refresh.service"
+	systemdTimerName   = "claude-switch-refresh.timer"
+)
+
+func systemdUnitDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" && !sandboxed() {
+		return filepath.Join(xdg, "systemd", "user")
+	}
+	return serviceDir(".config", "systemd", "user")
+}
+
+func systemdUnitPaths() []string {
+	dir := systemdUnitDir()
+	return []string{filepath.Join(dir, systemdServiceName), filepath.Join(dir, systemdTimerName)}
+}
+
+// systemdQuote quotes one ExecStart word. % starts a unit specifier and has
+// to be doubled even inside quotes.
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;$") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func installService(spec serviceSpec) ([]string, error) {
+	words := []string{systemdQuote(spec.Program)}
+	for _, a := range spec.Args {
+		words = append(words, systemdQuote(a))
+	}
+	var service strings.Builder
+	service.WriteString("[Unit]\nDescription=Refresh claude-switch OAuth tokens\n\n[Service]\nType=oneshot\n")
+	for _, v := range spec.Env {
+		fmt.Fprintf(&service, "Environment=%s\n", systemdQuote(v.Key+"="+v.Value))
+	}
+	fmt.Fprintf(&service, "ExecStart=%s\n", strings.Join(words, " "))
+	fmt.Fprintf(&service, "StandardOutput=append:%s\nStandardError=append:%s\n", spec.LogPath, spec.LogPath)
+
+	interval := compactDuration(spec.Interval)
+	timer := fmt.Sprintf("[Unit]\nDescription=Refresh claude-switch OAuth tokens every %s\n\n[Timer]\nOnBootSec=5min\nOnUnitActiveSec=%s\n\n[Install]\nWantedBy=timers.target\n", interval, interval)
+
+	paths := systemdUnitPaths()
+	if err := os.MkdirAll(filepath.Dir(paths[0]), 0o755); err != nil {
+		return nil, err
+	}
+	for i, content := range []string{service.String(), timer} {
+		if err := os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return paths, err
+	}
+	// restart rather than start so a reinstall picks up a new interval
+	if err := systemctl("enable", systemdTimerName); err != nil {
+		return paths, err
+	}
+	return paths, systemctl("restart", systemdTimerName)
+}
+
+func uninstallService() ([]string, error) {
+	paths := systemdUnitPaths()
+	if _, err := os.Stat(paths[1]); err == nil {
+		systemctl("disable", "--now", systemdTimerName)
+	}
+	var removed []string
+	for _, path := range paths {
+		err := os.Remove(path)
+		switch {
+		case err == nil:
+			removed = append(removed, path)
+		case !errors.Is(err, fs.ErrNotExist):
+			return removed, err
+		}
+	}
+	if len(removed) > 0 {
+		systemctl("daemon-reload")
+	}
+	return removed, nil
+}
+
+func serviceStatus() (ServiceStatus, error) {
+	var status ServiceStatus
+	paths := systemdUnitPaths()
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			status.Files = append(status.Files, path)
+		}
+	}
+	status.Installed = len(status.Files) == len(paths)
+	if !status.Installed {
+		return status, nil
+	}
+	if f, err := os.Open(paths[1]); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if value, ok := strings.CutPrefix(scanner.Text(), "OnUnitActiveSec="); ok {
+				status.Schedule = "every " + value
+			}
+		}
+		f.Close()
+	}
+	if sandboxed() {
+		status.Loaded = true
+		return status, nil
+	}
+	out, _ := exec.Command("systemctl", "--user", "is-active", systemdTimerName).Output()
+	status.Loaded = strings.TrimSpace(string(out)) == "active"
+	return status, nil
+}
+
+// systemctl runs `systemctl --user`, except in the sandbox where the unit
+// files are only written.
+func systemctl(args ...string) error {
+	if sandboxed() {
+		return nil
+	}
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return &cliError{
+			Code:    errGeneric,
+			Message: fmt.Sprintf("systemctl --user %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(out))),
+			Hint:    "the unit files were written; enable them by hand once the user session manager is available",
+			Err:     err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !darwin && !linux
+
+package main
+
+func serviceUnsupported() error {
+	return &cliError{
+		Code:    errGeneric,
+		Message: "service is only supported with launchd (macOS) and systemd (Linux)",
+		Hint:    "schedule 'claude-switch refresh --all --within 24h' with Task Scheduler or cron instead",
+	}
+}
+
+func installService(_ serviceSpec) ([]string, error) {
+	return nil, serviceUnsupported()
+}
+
+func uninstallService() ([]string, error) {
+	return nil, serviceUnsupported()
+}
+
+func serviceStatus() (ServiceStatus, error) {
+	return ServiceStatus{}, serviceUnsupported()
+}
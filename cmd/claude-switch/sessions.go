@@ -0,0 +1,196 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- Running sessions (sessions) ---
+
+// sessions lists the running Claude processes and the profile each is
+// signed in as. exec, run, shell and docker run note the pid of what they
+// start in launches.json, and all but docker run set CLAUDE_SWITCH_PROFILE
+// in its environment, so a Claude they started, or one started in a shell
+// they started, is traced back to its profile. Other sessions use the
+// login in Claude's config directory (the active profile's) or one given
+// them outside claude-switch.
+
+// launchRecord notes a process started for a profile.
+type launchRecord struct {
+	PID        int       `json:"pid"`
+	Profile    string    `json:"profile"`
+	Command    string    `json:"command"`
+	LaunchedAt time.Time `json:"launched_at"`
+}
+
+// launchWalkLimit is how many ancestors of a session are checked for a
+// launch record.
+const launchWalkLimit = 16
+
+func launchesPath() string {
+	return filepath.Join(configDir(), "launches.json")
+}
+
+func loadLaunches() []launchRecord {
+	var launches []launchRecord
+	if data, err := readFile(launchesPath()); err == nil {
+		json.Unmarshal(data, &launches)
+	}
+	return launches
+}
+
+// recordLaunch notes that pid runs args for the profile the command acts
+// on, dropping the records of processes that have exited. It's best
+// effort: a failure only leaves the session unattributed.
+func recordLaunch(pid int, args []string) {
+	if auditProfile == "" {
+		return
+	}
+	err := withLock(launchesLock, func() error {
+		launches := slices.DeleteFunc(loadLaunches(), func(l launchRecord) bool {
+			return l.PID == pid || !processAlive(l.PID)
+		})
+		launches = append(launches, launchRecord{PID: pid, Profile: auditProfile, Command: filepath.Base(args[0]), LaunchedAt: time.Now().UTC()})
+		data, err := json.MarshalIndent(launches, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeSecure(launchesPath(), data)
+	})
+	if err != nil {
+		debugf("sessions: couldn't record the launch of pid %d: %v", pid, err)
+	}
+}
+
+// How a session's profile was found.
+const (
+	viaLaunch   = "launch"   // started by exec, run, shell or docker run, or under one
+	viaEnv      = "env"      // CLAUDE_SWITCH_PROFILE in its environment
+	viaIsolated = "isolated" // its config directory is an isolated one
+	viaLogin    = "login"    // it uses the login in Claude's config directory
+	viaOther    = "other"    // its login comes from elsewhere
+)
+
+type session struct {
+	PID       int        `json:"pid"`
+	Profile   string     `json:"profile,omitempty"`
+	Via       string     `json:"via"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Dir       string     `json:"dir,omitempty"`
+}
+
+func listSessions() []session {
+	launches := loadLaunches()
+	active := ""
+	if state := loadState(); state.ActiveProfile != nil {
+		active = *state.ActiveProfile
+	}
+	var sessions []session
+	for _, p := range runningClaude() {
+		info := inspectProcess(p.PID)
+		s := session{PID: p.PID, Dir: info.Dir}
+		if !info.Started.IsZero() {
+			started := info.Started.UTC()
+			s.StartedAt = &started
+		}
+		if name, ok := launchedAs(p.PID, info, launches); ok {
+			s.Profile, s.Via = name, viaLaunch
+		} else if name := p.Env["CLAUDE_SWITCH_PROFILE"]; name != "" {
+			s.Profile, s.Via = name, viaEnv
+		} else if name, ok := isolatedProfile(p.configDir()); ok {
+			s.Profile, s.Via = name, viaIsolated
+		} else if p.sharesLogin(claudeConfigDir()) {
+			s.Profile, s.Via = active, viaLogin
+		} else {
+			s.Via = viaOther
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// launchedAs finds the launch record of pid or its nearest recorded
+// ancestor. A record older than the process it names is for an earlier
+// process that had the same pid.
+func launchedAs(pid int, info processInfo, launches []launchRecord) (string, bool) {
+	for range launchWalkLimit {
+		for _, l := range launches {
+			if l.PID == pid && (info.Started.IsZero() || !info.Started.After(l.LaunchedAt.Add(time.Second))) {
+				return l.Profile, true
+			}
+		}
+		if info.Parent <= 1 || info.Parent == pid {
+			break
+		}
+		pid = info.Parent
+		info = inspectProcess(pid)
+	}
+	return "", false
+}
+
+// isolatedProfile returns the profile whose isolated config directory dir
+// is, if it is one.
+func isolatedProfile(dir string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(isolatedRoot(), dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") || strings.ContainsRune(rel, filepath.Separator) {
+		return "", false
+	}
+	return rel, true
+}
+
+func cmdSessions(args []string) error {
+	var profile string
+	timeMode := timeRelative
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case parseTimeFlag(a, &timeMode):
+		case a == "--profile" || a == "-p":
+			if i+1 >= len(args) {
+				return usageError("%s requires a profile name", a)
+			}
+			i++
+			profile = args[i]
+		case strings.HasPrefix(a, "--profile="):
+			profile = strings.TrimPrefix(a, "--profile=")
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+
+	sessions := listSessions()
+	if profile != "" {
+		sessions = slices.DeleteFunc(sessions, func(s session) bool { return s.Profile != profile })
+	}
+	if jsonOutput() {
+		if sessions == nil {
+			sessions = []session{}
+		}
+		return printJSON(sessions)
+	}
+	if len(sessions) == 0 {
+		infof("No Claude sessions running.\n")
+		return nil
+	}
+
+	r := newRenderer(os.Stdout)
+	t := &table{shrink: []bool{false, false, false, false, true}}
+	t.add(r.header("PID"), r.header("PROFILE"), r.header("VIA"), r.header("STARTED"), r.header("DIR"))
+	for _, s := range sessions {
+		started := "-"
+		if s.StartedAt != nil {
+			started = formatLastUsed(uint64(s.StartedAt.UnixMilli()), timeMode)
+		}
+		t.add(fmt.Sprint(s.PID), cmp.Or(s.Profile, "-"), s.Via, started, cmp.Or(s.Dir, "-"))
+	}
+	return t.write(os.Stdout)
+}
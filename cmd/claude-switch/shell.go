@@ -0,0 +1,163 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// --- Subshell (shell <name>) ---
+
+// cmdShell starts the user's shell with a profile's variables set, like
+// exec without a command. The prompt is marked with the profile's name
+// where the shell allows it; CLAUDE_SWITCH_SHELL holds the name for
+// prompts that draw their own.
+func cmdShell(args []string) error {
+	var name string
+	isolated := false
+	for _, a := range args {
+		switch {
+		case a == "--isolated":
+			isolated = true
+		case strings.HasPrefix(a, "-"):
+			return usageError("unknown flag: %s", a)
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("shell requires a profile name")
+	}
+	if current := os.Getenv("CLAUDE_SWITCH_SHELL"); current != "" {
+		return &cliError{
+			Code:    errConfig,
+			Message: fmt.Sprintf("already in a claude-switch shell for '%s'", current),
+			Hint:    "exit it first",
+		}
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+	auditProfile = name
+	vars, err := credentialEnvVars(name, true)
+	if err != nil {
+		return err
+	}
+	if isolated {
+		dir, err := prepareIsolated(name)
+		if err != nil {
+			return err
+		}
+		vars = append(vars, dir)
+	}
+	// run inside the shell picks its profile too.
+	vars = append(vars, envVar{"CLAUDE_SWITCH_SHELL", name}, envVar{"CLAUDE_SWITCH_PROFILE", name})
+
+	shellArgs, promptVars, err := shellCommand(name)
+	if err != nil {
+		return err
+	}
+	vars = append(vars, promptVars...)
+	markUsed(name)
+	infof("Starting %s with profile '%s'; exit it to return.\n", filepath.Base(shellArgs[0]), name)
+	return execWithEnv(shellArgs, vars)
+}
+
+// userShell is the program to start: $SHELL, or the platform's default.
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell.exe"
+	}
+	return "/bin/sh"
+}
+
+// shellCommand returns the command line starting the user's shell with the
+// profile name in its prompt, and any variables that takes.
+func shellCommand(name string) ([]string, []envVar, error) {
+	shell := userShell()
+	marker := "(claude-switch:" + name + ") "
+	switch base := strings.TrimSuffix(strings.ToLower(filepath.Base(shell)), ".exe"); base {
+	case "bash":
+		rc, err := writeShellFile("bashrc", bashPromptRC)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []string{shell, "--rcfile", rc, "-i"}, nil, nil
+	case "zsh":
+		if _, err := writeShellFile(filepath.Join("zsh", ".zshenv"), zshPromptEnv); err != nil {
+			return nil, nil, err
+		}
+		rc, err := writeShellFile(filepath.Join("zsh", ".zshrc"), zshPromptRC)
+		if err != nil {
+			return nil, nil, err
+		}
+		vars := []envVar{{"ZDOTDIR", filepath.Dir(rc)}}
+		if dir := os.Getenv("ZDOTDIR"); dir != "" {
+			vars = append(vars, envVar{"CLAUDE_SWITCH_ZDOTDIR", dir})
+		}
+		return []string{shell}, vars, nil
+	case "fish":
+		return []string{shell, "-C", fishPromptInit}, nil, nil
+	case "pwsh", "powershell":
+		return []string{shell, "-NoExit", "-Command", powerShellPromptInit}, nil, nil
+	case "cmd":
+		return []string{shell}, []envVar{{"PROMPT", marker + cmp.Or(os.Getenv("PROMPT"), "$P$G")}}, nil
+	case "nu":
+		// nu builds its prompt in its own config; CLAUDE_SWITCH_SHELL is set.
+		return []string{shell}, nil, nil
+	}
+	// POSIX shells take PS1 from the environment.
+	return []string{shell}, []envVar{{"PS1", marker + cmp.Or(os.Getenv("PS1"), "$ ")}}, nil
+}
+
+// writeShellFile writes a startup file under the config directory's shell
+// directory and returns its path.
+func writeShellFile(file, content string) (string, error) {
+	path := filepath.Join(configDir(), "shell", file)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, writeSecure(path, []byte(content))
+}
+
+// The startup files run the user's own first, then prefix the prompt.
+const bashPromptRC = `# Written by claude-switch shell.
+[ -f ~/.bashrc ] && . ~/.bashrc
+PS1="(claude-switch:$CLAUDE_SWITCH_SHELL) $PS1"
+`
+
+// zsh reads its files from ZDOTDIR, which points here, so each one sources
+// the user's from their own ZDOTDIR (or home) in turn.
+const zshPromptEnv = `# Written by claude-switch shell.
+_claude_switch_zdotdir=$ZDOTDIR
+ZDOTDIR=${CLAUDE_SWITCH_ZDOTDIR:-$HOME}
+[[ -f $ZDOTDIR/.zshenv ]] && source $ZDOTDIR/.zshenv
+CLAUDE_SWITCH_ZDOTDIR=$ZDOTDIR
+ZDOTDIR=$_claude_switch_zdotdir
+unset _claude_switch_zdotdir
+`
+
+const zshPromptRC = `# Written by claude-switch shell.
+ZDOTDIR=${CLAUDE_SWITCH_ZDOTDIR:-$HOME}
+unset CLAUDE_SWITCH_ZDOTDIR
+[[ -f $ZDOTDIR/.zshrc ]] && source $ZDOTDIR/.zshrc
+PROMPT="(claude-switch:$CLAUDE_SWITCH_SHELL) $PROMPT"
+`
+
+const fishPromptInit = `functions -c fish_prompt __claude_switch_prompt
+function fish_prompt
+    printf '(claude-switch:%s) ' $CLAUDE_SWITCH_SHELL
+    __claude_switch_prompt
+end`
+
+const powerShellPromptInit = `$global:ClaudeSwitchPrompt = $function:prompt; ` +
+	`function global:prompt { "(claude-switch:$env:CLAUDE_SWITCH_SHELL) " + (& $global:ClaudeSwitchPrompt) }`
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// --- show <name> ---
+
+// ProfileDetails is the JSON shape of show: the summary plus everything
+// else worth inspecting. Secrets are masked unless --reveal is given.
+type ProfileDetails struct {
+	ProfileSummary
+	OrgUUID        string         `json:"org_uuid,omitempty"`
+	RateLimitTier  string         `json:"rate_limit_tier,omitempty"`
+	Scopes         []string       `json:"scopes,omitempty"`
+	AccessToken    string         `json:"access_token,omitempty"`
+	RefreshToken   string         `json:"refresh_token,omitempty"`
+	ApiKey         string         `json:"api_key,omitempty"`
+	OAuthToken     string         `json:"oauth_token,omitempty"`
+	FallbackApiKey string         `json:"fallback_api_key,omitempty"`
+	Proxy          *ProxySettings `json:"proxy,omitempty"`
+	EnvKeys        []string       `json:"env_keys,omitempty"`
+	SettingsFiles  []string       `json:"settings_files,omitempty"`
+	MCPServers     []string       `json:"mcp_servers,omitempty"`
+	Store          string         `json:"store,omitempty"`
+}
+
+func cmdShow(args []string) error {
+	var name string
+	reveal, withUsage := false, true
+	timeMode := timeUTC
+	for _, a := range args {
+		switch {
+		case parseTimeFlag(a, &timeMode):
+		case a == "--reveal":
+			reveal = true
+		case a == "--no-usage":
+			withUsage = false
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if name == "" {
+		return usageError("show requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+
+	// Before loading, since an expired token is refreshed for the lookup.
+	var usage usageResult
+	if withUsage {
+		usage.Usage, usage.Err = profileUsage(name)
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+
+	details := profileDetails(name, profile, reveal)
+	details.Usage = usage.Usage
+	if usage.Err != nil {
+		details.UsageError = usage.Err.Error()
+	}
+	if jsonOutput() {
+		return printJSON(details)
+	}
+	writeDetails(os.Stdout, profile, details, timeMode)
+
+	if !reveal && (details.AccessToken != "" || details.ApiKey != "" || details.OAuthToken != "") {
+		infof("Secrets are masked; pass --reveal to print them.\n")
+	}
+	return nil
+}
+
+func profileDetails(name string, profile *Profile, reveal bool) ProfileDetails {
+	secret := maskSecret
+	if reveal {
+		secret = func(s string) string { return s }
+	}
+	details := ProfileDetails{
+		ProfileSummary: summarizeProfile(name, profile),
+		ApiKey:         secret(profile.ApiKey),
+		OAuthToken:     secret(profile.OAuthToken),
+		FallbackApiKey: secret(profile.FallbackApiKey),
+		Store:          profile.Store,
+	}
+	if !profile.Proxy.Empty() {
+		details.Proxy = profile.Proxy
+	}
+	// Values may be secrets; `vars <name>` prints them.
+	for _, v := range extraEnvVars(profile) {
+		details.EnvKeys = append(details.EnvKeys, v.Key)
+	}
+	details.SettingsFiles = settingsFileNames(profile.Settings)
+	details.MCPServers = mcpServerNames(profile.MCPServers)
+	if profile.Type == "oauth" {
+		details.OrgUUID = profile.AccountInfo("organizationUuid")
+		if creds := profile.Credentials; creds != nil {
+			details.Scopes = creds.Scopes
+			details.AccessToken = secret(creds.AccessToken)
+			details.RefreshToken = secret(creds.RefreshToken)
+			if creds.RateLimitTier != nil {
+				details.RateLimitTier = *creds.RateLimitTier
+			}
+		}
+	}
+	return details
+}
+
+// writeDetails renders details as aligned "Key: value" rows, skipping
+// empty ones. Times are shown in timeMode's zone.
+func writeDetails(out io.Writer, profile *Profile, details ProfileDetails, timeMode string) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	row := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", key, value)
+		}
+	}
+	active := "no"
+	if details.Active {
+		active = "yes"
+	}
+	row("Name", details.Name)
+	row("Label", details.Label)
+	row("Active", active)
+	lastUsed := "never"
+	if ms := loadState().LastUsed[details.Name]; ms != 0 {
+		lastUsed = fmt.Sprintf("%s (%s)", formatLastUsed(ms, timeRelative), formatTimestamp(ms, timeMode))
+	}
+	row("Last used", lastUsed)
+	row("Type", profile.DisplayType())
+	if profile.Type == "oauth" {
+		row("Email", profile.DisplayEmail())
+		row("Org", profile.DisplayOrg())
+		row("Plan", profile.DisplaySub())
+		row("Rate limit tier", details.RateLimitTier)
+		row("Account UUID", details.AccountUUID)
+		row("Org UUID", details.OrgUUID)
+		row("Scopes", strings.Join(details.Scopes, " "))
+		if ts := profile.ExpiresAt(); ts != nil {
+			expiry := fmt.Sprintf("%s (%s)", formatExpiry(*ts, timeRelative), formatExpiry(*ts, timeMode))
+			if isExpired(profile.Credentials) {
+				expiry += ", refreshed on next use"
+			}
+			row("Expires", expiry)
+		}
+		if u := details.Usage; u != nil {
+			if u.FiveHour != nil {
+				row("5-hour usage", formatWindow(u.FiveHour))
+			}
+			if u.SevenDay != nil {
+				row("Weekly usage", formatWindow(u.SevenDay))
+			}
+			if u.SevenDayOpus != nil {
+				row("Weekly Opus usage", formatWindow(u.SevenDayOpus))
+			}
+		} else if details.UsageError != "" {
+			row("Usage", "unavailable: "+details.UsageError)
+		}
+		row("Access token", details.AccessToken)
+		row("Refresh token", details.RefreshToken)
+	}
+	row("Long-lived token", details.OAuthToken)
+	row("API key", details.ApiKey)
+	row("Fallback API key", details.FallbackApiKey)
+	if p := details.Proxy; p != nil {
+		row("HTTP proxy", p.HTTP)
+		row("HTTPS proxy", p.HTTPS)
+		row("No proxy", p.NoProxy)
+	}
+	row("Variables", strings.Join(details.EnvKeys, " "))
+	row("Claude settings", strings.Join(details.SettingsFiles, " "))
+	row("MCP servers", strings.Join(details.MCPServers, " "))
+	row("Stored in", details.Store)
+	// Notes can run over several lines; the rest line up under the first.
+	for i, line := range strings.Split(details.Notes, "\n") {
+		if i == 0 {
+			row("Notes", line)
+		} else {
+			fmt.Fprintf(w, "\t%s\n", line)
+		}
+	}
+	w.Flush()
+}
+
+// maskSecret keeps the key's type prefix (e.g. sk-ant-oat01-) and the last
+// four characters, which is enough to tell keys apart.
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	prefix := ""
+	if strings.HasPrefix(s, "sk-ant-") {
+		if i := strings.Index(s[len("sk-ant-"):], "-"); i >= 0 {
+			prefix = s[:len("sk-ant-")+i+1]
+		}
+	}
+	if len(s)-len(prefix) <= 8 {
+		return prefix + "****"
+	}
+	return prefix + "****" + s[len(s)-4:]
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- status: does Claude still hold the active profile's credentials? ---
+
+// Drift states reported by status. Scripts branch on these.
+const (
+	driftInSync        = "in_sync"
+	driftNoActive      = "no_active_profile"
+	driftAPIKey        = "api_key_profile"
+	driftLoggedOut     = "logged_out"
+	driftAccount       = "account_changed"
+	driftClaudeRotated = "claude_rotated"
+	driftStale         = "claude_stale"
+)
+
+// liveAuth is what Claude Code will actually use right now.
+type liveAuth struct {
+	Credentials *OAuthCredentials
+	// Source is the credentials file, or "keychain" when they only live there.
+	Source  string
+	Account json.RawMessage
+}
+
+// readLiveAuth reads Claude's credentials the way readOAuthCredentials does
+// (flat file first, then the system keychain) plus oauthAccount from
+// .claude.json.
+func readLiveAuth() liveAuth {
+	var live liveAuth
+	if data, err := readFile(credentialsPath()); err == nil {
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(data, &doc) == nil && doc["claudeAiOauth"] != nil {
+			if json.Unmarshal(doc["claudeAiOauth"], &live.Credentials) == nil {
+				live.Source = credentialsPath()
+			}
+		}
+	}
+	if live.Credentials == nil && defaults.keychainEnabled() {
+		if raw := readKeychainCredentials(); raw != nil && json.Unmarshal(raw, &live.Credentials) == nil {
+			live.Source = "keychain"
+		}
+	}
+	if data, err := readFile(claudeJSONPath()); err == nil {
+		var doc map[string]json.RawMessage
+		if json.Unmarshal(data, &doc) == nil {
+			live.Account = doc["oauthAccount"]
+		}
+	}
+	return live
+}
+
+// StatusReport is the JSON shape of status. Tokens appear only as
+// fingerprints, enough to tell whether two copies are the same.
+type StatusReport struct {
+	State               string `json:"state"`
+	InSync              bool   `json:"in_sync"`
+	Active              string `json:"active,omitempty"`
+	ActiveEmail         string `json:"active_email,omitempty"`
+	ActiveAccountUUID   string `json:"active_account_uuid,omitempty"`
+	LiveEmail           string `json:"live_email,omitempty"`
+	LiveAccountUUID     string `json:"live_account_uuid,omitempty"`
+	LiveProfile         string `json:"live_profile,omitempty"`
+	LiveSource          string `json:"live_source,omitempty"`
+	ProfileAccessToken  string `json:"profile_access_token,omitempty"`
+	ProfileRefreshToken string `json:"profile_refresh_token,omitempty"`
+	LiveAccessToken     string `json:"live_access_token,omitempty"`
+	LiveRefreshToken    string `json:"live_refresh_token,omitempty"`
+}
+
+// tokenFingerprint identifies a token without revealing it.
+func tokenFingerprint(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:6])
+}
+
+// activeDrift compares the active profile with Claude's live credentials.
+// The active profile is returned when it could be loaded.
+func activeDrift() (StatusReport, *Profile, liveAuth, error) {
+	live := readLiveAuth()
+	report := StatusReport{LiveSource: live.Source}
+	if live.Credentials != nil {
+		report.LiveAccessToken = tokenFingerprint(live.Credentials.AccessToken)
+		report.LiveRefreshToken = tokenFingerprint(live.Credentials.RefreshToken)
+	}
+	if live.Account != nil {
+		report.LiveEmail = claudeswitch.AccountField(live.Account, "emailAddress")
+		report.LiveAccountUUID = claudeswitch.AccountField(live.Account, "accountUuid")
+		report.LiveProfile = profileForAccount(report.LiveAccountUUID)
+	}
+
+	state := loadState()
+	if state.ActiveProfile == nil || !profileExists(*state.ActiveProfile) {
+		report.State = driftNoActive
+		return report, nil, live, nil
+	}
+	name := *state.ActiveProfile
+	report.Active = name
+	profile, err := loadProfile(name)
+	if err != nil {
+		return report, nil, live, err
+	}
+	if profile.Type != "oauth" {
+		report.State = driftAPIKey
+		report.InSync = true
+		return report, profile, live, nil
+	}
+	report.ActiveEmail = profile.AccountInfo("emailAddress")
+	report.ActiveAccountUUID = profile.AccountInfo("accountUuid")
+	if creds := profile.Credentials; creds != nil {
+		report.ProfileAccessToken = tokenFingerprint(creds.AccessToken)
+		report.ProfileRefreshToken = tokenFingerprint(creds.RefreshToken)
+	}
+
+	switch {
+	case live.Credentials == nil:
+		report.State = driftLoggedOut
+	case report.LiveAccountUUID != "" && report.ActiveAccountUUID != "" && report.LiveAccountUUID != report.ActiveAccountUUID:
+		report.State = driftAccount
+	case profile.Credentials == nil || live.Credentials.RefreshToken != profile.Credentials.RefreshToken:
+		// Same account, different tokens: whichever copy expires later was
+		// refreshed more recently. Claude's expiry is on the local clock.
+		report.State = driftClaudeRotated
+		if profile.Credentials != nil && profile.Credentials.ExpiresAt > fromLocalClock(live.Credentials.ExpiresAt) {
+			report.State = driftStale
+		}
+	default:
+		report.State = driftInSync
+		report.InSync = true
+	}
+	return report, profile, live, nil
+}
+
+// profileForAccount finds a saved profile for an account UUID via the index.
+func profileForAccount(uuid string) string {
+	if uuid == "" {
+		return ""
+	}
+	names, err := listProfiles()
+	if err != nil {
+		return ""
+	}
+	index := loadIndex()
+	for _, name := range names {
+		if meta, err := loadProfileMeta(&index, name); err == nil && meta.AccountUUID == uuid {
+			return name
+		}
+	}
+	return ""
+}
+
+// driftError explains a drifted report and how to fix it, or returns nil
+// when there's nothing to fix.
+func driftError(report StatusReport) error {
+	name := report.Active
+	var msg, hint string
+	switch report.State {
+	case driftLoggedOut:
+		msg = fmt.Sprintf("'%s' is active but Claude has no OAuth credentials (logged out?)", name)
+		hint = fmt.Sprintf("run 'claude-switch use %s' to log Claude back in", name)
+	case driftAccount:
+		who := report.LiveEmail
+		if who == "" {
+			who = report.LiveAccountUUID
+		}
+		msg = fmt.Sprintf("Claude is logged in as %s, not the active profile '%s' (claude /login run directly?)", who, name)
+		if report.LiveProfile != "" {
+			hint = fmt.Sprintf("that account is saved as '%s'; run 'claude-switch sync' to save its tokens and make it active, or 'claude-switch use %s' to switch back", report.LiveProfile, name)
+		} else {
+			hint = fmt.Sprintf("run 'claude-switch import <name>' to save that account, or 'claude-switch use %s' to switch back", name)
+		}
+	case driftClaudeRotated:
+		msg = fmt.Sprintf("Claude refreshed the tokens for '%s' since the profile was saved; the saved refresh token may no longer work", name)
+		hint = "run 'claude-switch sync' to save Claude's current tokens"
+	case driftStale:
+		msg = fmt.Sprintf("'%s' was refreshed by claude-switch but Claude still holds the old tokens", name)
+		hint = fmt.Sprintf("run 'claude-switch use %s' to hand Claude the new ones", name)
+	default:
+		return nil
+	}
+	return &cliError{Code: errDrift, Message: msg, Profile: name, Hint: hint}
+}
+
+func cmdStatus(args []string) error {
+	if len(args) > 0 {
+		return usageError("unexpected argument: %s", args[0])
+	}
+	report, _, _, err := activeDrift()
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+		return driftError(report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	row := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%s:\t%s\n", key, value)
+		}
+	}
+	active := report.Active
+	if active == "" {
+		active = "(none)"
+	} else if report.ActiveEmail != "" {
+		active += " (" + report.ActiveEmail + ")"
+	}
+	row("Active profile", active)
+	claude := "not logged in"
+	if report.LiveSource != "" {
+		claude = report.LiveEmail
+		if claude == "" {
+			claude = "(unknown account)"
+		}
+		if report.LiveProfile != "" {
+			claude += ", saved as '" + report.LiveProfile + "'"
+		}
+		claude += ", from " + report.LiveSource
+	}
+	row("Claude", claude)
+	if report.ProfileAccessToken != "" || report.LiveAccessToken != "" {
+		row("Access token", fmt.Sprintf("profile %s, Claude %s", orDash(report.ProfileAccessToken), orDash(report.LiveAccessToken)))
+		row("Refresh token", fmt.Sprintf("profile %s, Claude %s", orDash(report.ProfileRefreshToken), orDash(report.LiveRefreshToken)))
+	}
+	switch report.State {
+	case driftInSync:
+		row("Status", "in sync")
+	case driftAPIKey:
+		row("Status", "not an OAuth login; Claude reads the credential from the environment")
+	case driftNoActive:
+		row("Status", "no active profile")
+	default:
+		row("Status", "drifted ("+report.State+")")
+	}
+	w.Flush()
+	return driftError(report)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- Profile storage backends ---
+
+// StorageBackend keeps whole profiles by name. Names are validated before a
+// backend sees them, and the metadata index is maintained by the callers in
+// profile.go, so backends only move profiles in and out.
+type StorageBackend interface {
+	Load(name string) (*Profile, error)
+	Save(name string, profile *Profile) error
+	List() ([]string, error)
+	Remove(name string) error
+	Exists(name string) bool
+}
+
+// storageBackends maps the [storage] backend setting in config.toml to a
+// constructor.
+var storageBackends = map[string]func(cfg *Config) (StorageBackend, error){
+	"file":  func(*Config) (StorageBackend, error) { return fileStore{}, nil },
+	"vault": newVaultStore,
+}
+
+// backendOverride is set by the global --backend flag.
+var backendOverride string
+
+// activeStorage is resolved once per invocation.
+var activeStorage StorageBackend
+
+func storage() (StorageBackend, error) {
+	if activeStorage != nil {
+		return activeStorage, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.Storage.Backend
+	if backendOverride != "" {
+		name = backendOverride
+	}
+	if name == "" {
+		name = "file"
+	}
+	newBackend, ok := storageBackends[name]
+	if !ok {
+		var known []string
+		for k := range storageBackends {
+			known = append(known, k)
+		}
+		sort.Strings(known)
+		return nil, &cliError{
+			Code:    errConfig,
+			Message: fmt.Sprintf("unknown storage backend '%s'", name),
+			Hint:    fmt.Sprintf("set [storage] backend in %s to one of: %s", configPath(), strings.Join(known, ", ")),
+		}
+	}
+	backend, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	activeStorage = backend
+	return backend, nil
+}
+
+// usingFileStore reports whether profiles live in local files, which is
+// what encryption and the file-level maintenance commands operate on.
+func usingFileStore() bool {
+	store, err := storage()
+	if err != nil {
+		return false
+	}
+	_, ok := store.(fileStore)
+	return ok
+}
+
+// --- File store (default) ---
+
+// fileStore keeps one JSON file per profile under profilesDir, sealed when
+// encryption is enabled and otherwise protected with DPAPI on Windows. The
+// files are read and written by claudeswitch.ProfileStore; the callers hold
+// the profile locks and keep the index.
+type fileStore struct{}
+
+func (fileStore) files() *claudeswitch.ProfileStore {
+	return &claudeswitch.ProfileStore{
+		Dir: profilesDir(),
+		Open: func(name string, data []byte) ([]byte, error) {
+			if sealed := parseSealed(data); sealed != nil {
+				return openProfile(name, sealed)
+			}
+			if !encryptionEnabled() && defaults.dpapiEnabled() {
+				protectPlaintext(name, data)
+			}
+			return data, nil
+		},
+		Seal:     storedForm,
+		ReadFile: readFile,
+		WriteFile: func(path string, data []byte) error {
+			name := strings.TrimSuffix(filepath.Base(path), ".json")
+			return writeWithBackup(path, profileBackup(name), data)
+		},
+	}
+}
+
+func (f fileStore) Load(name string) (*Profile, error) {
+	profile, err := f.files().Load(name)
+	if errors.Is(err, claudeswitch.ErrProfileNotFound) {
+		return nil, notFoundError(name)
+	}
+	return profile, err
+}
+
+func (f fileStore) Save(name string, profile *Profile) error {
+	return f.files().Save(name, profile)
+}
+
+func (f fileStore) List() ([]string, error) {
+	return f.files().List()
+}
+
+func (f fileStore) Remove(name string) error {
+	err := f.files().Remove(name)
+	if errors.Is(err, claudeswitch.ErrProfileNotFound) {
+		return notFoundError(name)
+	}
+	return err
+}
+
+func (f fileStore) Exists(name string) bool {
+	return f.files().Exists(name)
+}
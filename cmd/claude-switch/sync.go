@@ -0,0 +1,101 @@
+package main
+
+import "fmt"
+
+// --- sync: save tokens Claude Code refreshed on its own ---
+
+// syncResult says what syncActiveProfile did.
+type syncResult struct {
+	Profile string `json:"profile,omitempty"`
+	Updated bool   `json:"updated"`
+	// Switched is set when Claude was logged into another saved profile's
+	// account, which is then marked active.
+	Switched bool   `json:"switched,omitempty"`
+	State    string `json:"state"`
+}
+
+// syncActiveProfile copies Claude's live credentials into the saved profile
+// for the same account: the active one, or another saved profile if Claude
+// has been logged into that account directly. Tokens are never overwritten
+// with older ones.
+func syncActiveProfile() (syncResult, error) {
+	report, profile, live, err := activeDrift()
+	if err != nil {
+		return syncResult{}, err
+	}
+	result := syncResult{Profile: report.Active, State: report.State}
+	name := report.Active
+
+	switch report.State {
+	case driftInSync, driftAPIKey:
+		return result, nil
+	case driftNoActive:
+		if report.LiveProfile == "" {
+			return result, &cliError{Code: errProfileNotFound, Message: "no active profile, and Claude's account isn't saved as a profile", Hint: "use 'claude-switch import <name>' to save it"}
+		}
+	case driftLoggedOut, driftStale:
+		return result, driftError(report)
+	case driftAccount:
+		if report.LiveProfile == "" {
+			return result, driftError(report)
+		}
+	}
+	if name == "" || report.State == driftAccount {
+		name = report.LiveProfile
+		if profile, err = loadProfile(name); err != nil {
+			return result, err
+		}
+		if profile.Credentials != nil && profile.Credentials.ExpiresAt > fromLocalClock(live.Credentials.ExpiresAt) {
+			return result, &cliError{Code: errDrift, Message: fmt.Sprintf("Claude holds older tokens for '%s' than the saved profile", name), Profile: name, Hint: fmt.Sprintf("run 'claude-switch use %s' to hand Claude the newer ones", name)}
+		}
+		result.Switched = true
+	}
+
+	if err := adoptLiveCredentials(name, profile, live); err != nil {
+		return result, err
+	}
+	if result.Switched {
+		if err := updateState(func(state *State) { state.ActiveProfile = &name }); err != nil {
+			return result, err
+		}
+	}
+	result.Profile = name
+	result.Updated = true
+	return result, nil
+}
+
+// adoptLiveCredentials saves Claude's live tokens, and its account if
+// known, to the profile name.
+func adoptLiveCredentials(name string, profile *Profile, live liveAuth) error {
+	creds := *live.Credentials
+	creds.ExpiresAt = fromLocalClock(creds.ExpiresAt)
+	profile.Credentials = &creds
+	if live.Account != nil {
+		profile.Account = live.Account
+	}
+	return saveProfile(name, profile)
+}
+
+func cmdSync(args []string) error {
+	if len(args) > 0 {
+		return usageError("unexpected argument: %s", args[0])
+	}
+	result, err := syncActiveProfile()
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(result)
+	}
+	switch {
+	case result.State == driftAPIKey:
+		infof("'%s' isn't an OAuth login; nothing to sync.\n", result.Profile)
+	case !result.Updated:
+		infof("'%s' is already in sync with Claude.\n", result.Profile)
+	case result.Switched:
+		infof("Claude is logged in as '%s'; saved its current tokens and marked it active.\n", result.Profile)
+	default:
+		infof("Saved Claude's current tokens to '%s'.\n", result.Profile)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"golang.org/x/term"
+)
+
+// --- Tables ---
+
+// table lays out rows in aligned columns. Unlike text/tabwriter it measures
+// cells by what the terminal shows, so escape codes from the renderer and
+// wide characters don't throw the alignment off.
+type table struct {
+	rows [][]string
+	// shrink marks the columns that may be cut short to fit the width.
+	shrink []bool
+}
+
+// tableGap separates the columns.
+const tableGap = "  "
+
+// minShrunkWidth is as narrow as fit makes a column.
+const minShrunkWidth = 10
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// widths returns each column's width, the widest cell in it.
+func (t *table) widths() []int {
+	var widths []int
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], ansi.StringWidth(cell))
+		}
+	}
+	return widths
+}
+
+// fit narrows the widest shrinkable columns until a row fits in width
+// columns, or none can give up any more.
+func fit(widths []int, shrink []bool, width int) []int {
+	total := len(tableGap) * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > width {
+		widest := -1
+		for i, w := range widths {
+			if i < len(shrink) && shrink[i] && w > minShrunkWidth && (widest < 0 || w > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+// write prints the table to w, fitted to the terminal if f is one.
+func (t *table) write(f *os.File) error {
+	widths := t.widths()
+	if cols, _, err := term.GetSize(int(f.Fd())); err == nil && cols > 0 {
+		widths = fit(widths, t.shrink, cols)
+	}
+	return t.render(f, widths)
+}
+
+func (t *table) render(w io.Writer, widths []int) error {
+	for _, row := range t.rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if ansi.StringWidth(cell) > widths[i] {
+				cell = ansi.Truncate(cell, widths[i], "…")
+			}
+			line.WriteString(cell)
+			// The last cell isn't padded, so lines have no trailing blanks.
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-ansi.StringWidth(cell)))
+				line.WriteString(tableGap)
+			}
+		}
+		if _, err := fmt.Fprintln(w, line.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
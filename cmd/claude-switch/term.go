@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// --- Terminal input ---
+
+// stdinReader is shared so prompts reading piped input consume it line by
+// line instead of each buffering ahead.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// readLine prompts on stderr and reads one line from stdin.
+func readLine(prompt string) (string, error) {
+	if stdinIsTerminal() {
+		fmt.Fprint(os.Stderr, prompt)
+	}
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// readSecret is readLine without echo when stdin is a terminal. Piped input
+// is read as a plain line so secrets can be scripted.
+func readSecret(prompt string) (string, error) {
+	if !stdinIsTerminal() {
+		return readLine(prompt)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// --- Trash: undoing remove ---
+
+// remove moves the profile into trash/<name>.<timestamp>.json in the config
+// directory along with its active marker, groups and last use, so
+// `remove --undo` can put it back. Entries are kept for trashMaxAge, after
+// which undo ignores them and gc deletes them.
+const trashMaxAge = 7 * 24 * time.Hour
+
+func trashDir() string {
+	return filepath.Join(configDir(), "trash")
+}
+
+// trashEntry is one removed profile. Profile holds it as it was stored, so
+// an encrypted profile stays sealed in the trash (until encrypt disable
+// decrypts it) and secrets kept in 1Password or the keychain stay there. A
+// keychain item is deleted along with the entry.
+type trashEntry struct {
+	Name      string          `json:"name"`
+	RemovedAt time.Time       `json:"removed_at"`
+	Active    bool            `json:"active,omitempty"`
+	Groups    []string        `json:"groups,omitempty"`
+	LastUsed  uint64          `json:"last_used,omitempty"`
+	Meta      *ProfileMeta    `json:"meta,omitempty"`
+	Profile   json.RawMessage `json:"profile"`
+
+	path string
+}
+
+func cmdRemove(args []string) error {
+	var name string
+	yes, undo, revoke := false, false, false
+	for _, a := range args {
+		switch {
+		case a == "--yes" || a == "-y":
+			yes = true
+		case a == "--undo":
+			undo = true
+		case a == "--revoke":
+			revoke = true
+		case name == "":
+			name = a
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+	if undo && revoke {
+		return usageError("--undo and --revoke can't be combined")
+	}
+	if undo {
+		if name == "" {
+			return listTrash()
+		}
+		return undoRemove(name)
+	}
+	if name == "" {
+		return usageError("remove requires a profile name")
+	}
+	name, err := resolveProfileName(name, false)
+	if err != nil {
+		return err
+	}
+	auditProfile = name
+
+	if !yes && stdinIsTerminal() && !jsonOutput() {
+		what := "'" + name + "'"
+		index := loadIndex()
+		if meta, err := loadProfileMeta(&index, name); err == nil && meta.Email != "" {
+			what += " (" + meta.Email + ")"
+		}
+		verb := "Remove"
+		if revoke {
+			verb = "Revoke the tokens of and remove"
+		}
+		answer, err := readLine(fmt.Sprintf("%s profile %s? [y/N] ", verb, what))
+		if err != nil {
+			return err
+		}
+		if a := strings.ToLower(answer); a != "y" && a != "yes" {
+			return &cliError{Code: errGeneric, Message: "remove cancelled"}
+		}
+	}
+
+	if revoke {
+		// Revoked tokens are useless, so there is nothing to keep in the
+		// trash.
+		profile, err := loadProfile(name)
+		if err != nil {
+			return err
+		}
+		state := loadState()
+		wasActive := state.ActiveProfile != nil && *state.ActiveProfile == name
+		if err := revokeProfile(name, profile); err != nil {
+			return err
+		}
+		if err := removeProfile(name); err != nil {
+			return err
+		}
+		if err := deleteKeychainStore(profile.Store); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: couldn't delete the keychain item of '%s': %v\n", name, err)
+		}
+		recordAudit(name, nil)
+		if jsonOutput() {
+			return printJSON(map[string]any{"name": name, "removed": true, "revoked": true})
+		}
+		infof("Revoked and removed profile '%s'\n", name)
+		if wasActive && profile.Type == "oauth" {
+			fmt.Fprintln(os.Stderr, "It was the active profile, so Claude's current login no longer works. Run 'claude-switch use <name>' to switch to another.")
+		}
+		return nil
+	}
+
+	if err := trashProfile(name); err != nil {
+		return err
+	}
+	recordAudit(name, nil)
+	if jsonOutput() {
+		return printJSON(map[string]any{"name": name, "removed": true})
+	}
+	infof("Removed profile '%s'. Run 'claude-switch remove --undo %s' within %d days to restore it.\n", name, name, int(trashMaxAge.Hours()/24))
+	return nil
+}
+
+// trashProfile removes a profile after saving it to the trash.
+func trashProfile(name string) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	store, err := storage()
+	if err != nil {
+		return err
+	}
+	entry := trashEntry{Name: name, RemovedAt: time.Now().UTC()}
+	// The stored form, read without decrypting so no passphrase is needed.
+	err = withLock(profileLock(name), func() error {
+		if _, ok := store.(fileStore); ok {
+			data, err := readFile(profilePath(name))
+			if err != nil {
+				return notFoundError(name)
+			}
+			entry.Profile = data
+			return nil
+		}
+		profile, err := store.Load(name)
+		if err != nil {
+			return err
+		}
+		entry.Profile, err = json.Marshal(profile)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if meta, ok := loadIndex().Profiles[name]; ok {
+		entry.Meta = &meta
+	}
+	state := loadState()
+	entry.Active = state.ActiveProfile != nil && *state.ActiveProfile == name
+	entry.Groups = profileGroups(state, name)
+	entry.LastUsed = state.LastUsed[name]
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(trashDir(), name+"."+entry.RemovedAt.Format(backupTimeFormat)+".json")
+	if err := writeSecure(path, data); err != nil {
+		return fmt.Errorf("failed to move '%s' to the trash: %w", name, err)
+	}
+	return removeProfile(name)
+}
+
+// loadTrash returns the trash entries that haven't expired, newest first.
+func loadTrash() ([]trashEntry, error) {
+	files, err := os.ReadDir(trashDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []trashEntry
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(trashDir(), f.Name())
+		data, err := readFile(path)
+		if err != nil {
+			continue
+		}
+		var entry trashEntry
+		if json.Unmarshal(data, &entry) != nil || time.Since(entry.RemovedAt) > trashMaxAge {
+			continue
+		}
+		entry.path = path
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b trashEntry) int { return b.RemovedAt.Compare(a.RemovedAt) })
+	return entries, nil
+}
+
+func listTrash() error {
+	entries, err := loadTrash()
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		type trashed struct {
+			Name      string    `json:"name"`
+			RemovedAt time.Time `json:"removed_at"`
+			Email     string    `json:"email,omitempty"`
+		}
+		out := []trashed{}
+		for _, e := range entries {
+			t := trashed{Name: e.Name, RemovedAt: e.RemovedAt}
+			if e.Meta != nil {
+				t.Email = e.Meta.Email
+			}
+			out = append(out, t)
+		}
+		return printJSON(out)
+	}
+	if len(entries) == 0 {
+		infof("No removed profiles to restore.\n")
+		return nil
+	}
+	t := &table{}
+	t.add("NAME", "EMAIL", "REMOVED")
+	for _, e := range entries {
+		email := "-"
+		if e.Meta != nil && e.Meta.Email != "" {
+			email = e.Meta.Email
+		}
+		t.add(e.Name, email, formatLastUsed(uint64(e.RemovedAt.UnixMilli()), timeRelative))
+	}
+	return t.write(os.Stdout)
+}
+
+// undoRemove restores the most recently removed profile called name.
+func undoRemove(name string) error {
+	entries, err := loadTrash()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(entries, func(e trashEntry) bool { return e.Name == name })
+	if i < 0 {
+		return &cliError{
+			Code:    errProfileNotFound,
+			Message: fmt.Sprintf("no removed profile '%s' in the trash", name),
+			Profile: name,
+			Hint:    "run 'claude-switch remove --undo' to list what can be restored",
+		}
+	}
+	entry := entries[i]
+	if profileExists(name) {
+		return existsError(name)
+	}
+
+	data := []byte(entry.Profile)
+	if sealed := parseSealed(data); sealed != nil {
+		if data, err = openProfile(name, sealed); err != nil {
+			return err
+		}
+	}
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return fmt.Errorf("failed to read '%s' from the trash: %w", name, err)
+	}
+	store, err := storage()
+	if err != nil {
+		return err
+	}
+	// Saved as it was stored: external secrets are still in 1Password or
+	// the keychain.
+	if err := withLock(profileLock(name), func() error { return store.Save(name, &profile) }); err != nil {
+		return err
+	}
+	if err := updateIndex(name, entry.Meta); err != nil {
+		return err
+	}
+	if err := updateState(func(state *State) {
+		if entry.Active && state.ActiveProfile == nil {
+			state.ActiveProfile = &name
+		}
+		for _, group := range entry.Groups {
+			joinGroup(state, group, name)
+		}
+		if entry.LastUsed != 0 {
+			if state.LastUsed == nil {
+				state.LastUsed = make(map[string]uint64)
+			}
+			state.LastUsed[name] = entry.LastUsed
+		}
+	}); err != nil {
+		return err
+	}
+	if err := os.Remove(entry.path); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", entry.path, err)
+	}
+
+	if jsonOutput() {
+		return printJSON(map[string]any{"name": name, "restored": true})
+	}
+	infof("Restored profile '%s'\n", name)
+	return nil
+}
+
+// decryptTrash writes the trashed profiles sealed with the passphrase back
+// as plaintext, or protected with DPAPI on Windows, for encrypt disable,
+// which deletes the parameters they would need to be opened. Expired
+// entries are included, since gc may not have run yet.
+func decryptTrash() error {
+	files, err := os.ReadDir(trashDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(trashDir(), f.Name())
+		data, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		var entry trashEntry
+		if json.Unmarshal(data, &entry) != nil {
+			continue
+		}
+		sealed := parseSealed(entry.Profile)
+		if sealed == nil || sealed.Encrypted == sealedDPAPI {
+			continue
+		}
+		plaintext, err := openProfile(entry.Name, sealed)
+		if err != nil {
+			return err
+		}
+		if defaults.dpapiEnabled() {
+			if plaintext, err = protectProfile(entry.Name, plaintext); err != nil {
+				return err
+			}
+		}
+		entry.Profile = plaintext
+		if data, err = json.MarshalIndent(entry, "", "  "); err != nil {
+			return err
+		}
+		if err := writeSecure(path, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gcTrash finds trash entries past trashMaxAge, and files in the trash
+// that can't be read as entries.
+func gcTrash() ([]gcItem, error) {
+	files, err := os.ReadDir(trashDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []gcItem
+	for _, f := range files {
+		path := filepath.Join(trashDir(), f.Name())
+		info, err := f.Info()
+		if err != nil || f.IsDir() {
+			continue
+		}
+		var entry trashEntry
+		data, err := readFile(path)
+		if err == nil && json.Unmarshal(data, &entry) == nil && time.Since(entry.RemovedAt) <= trashMaxAge {
+			continue
+		}
+		what := fmt.Sprintf("unreadable trash file %s", f.Name())
+		if entry.Name != "" {
+			what = fmt.Sprintf("trashed profile '%s' from %s", entry.Name, entry.RemovedAt.Format("2006-01-02"))
+		}
+		// Sealed profiles don't show their store; their items are left.
+		var stored Profile
+		json.Unmarshal(entry.Profile, &stored)
+		items = append(items, gcItem{
+			What:  what,
+			Bytes: info.Size(),
+			apply: func() error {
+				if err := deleteKeychainStore(stored.Store); err != nil {
+					return err
+				}
+				return os.Remove(path)
+			},
+		})
+	}
+	return items, nil
+}
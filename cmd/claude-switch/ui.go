@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+)
+
+// --- Full-screen TUI (claude-switch ui) ---
+
+type uiMode int
+
+const (
+	uiBrowse uiMode = iota
+	uiSearch
+	uiRename
+	uiConfirmRemove
+	uiDetails
+)
+
+type uiRow struct {
+	name string
+	meta *ProfileMeta
+	err  error
+}
+
+type uiModel struct {
+	rows    []uiRow
+	active  string
+	filter  string
+	cursor  int
+	mode    uiMode
+	input   string
+	details string
+	status  string
+	busy    bool
+}
+
+type (
+	uiTickMsg time.Time
+	// uiLogMsg carries a line a command wrote to stderr while the TUI owns
+	// the screen.
+	uiLogMsg  string
+	uiDoneMsg struct {
+		status string
+		err    error
+	}
+	uiDetailsMsg struct {
+		text string
+		err  error
+	}
+)
+
+var (
+	uiHeaderStyle   = lipgloss.NewStyle().Bold(true)
+	uiSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	uiActiveStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	uiExpiredStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	uiSoonStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	uiDimStyle      = lipgloss.NewStyle().Faint(true)
+)
+
+func cmdUI(args []string) error {
+	if len(args) > 0 {
+		return usageError("unexpected argument: %s", args[0])
+	}
+	if !stdinIsTerminal() || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return usageError("ui needs an interactive terminal")
+	}
+	if !useColor(os.Stdout) {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	m := &uiModel{}
+	if err := m.reload(); err != nil {
+		return err
+	}
+
+	// Commands report progress and warnings on stderr, which would tear up
+	// the screen; route those lines to the status bar instead.
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	origStderr := os.Stderr
+	os.Stderr = w
+	defer func() {
+		os.Stderr = origStderr
+		w.Close()
+	}()
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				p.Send(uiLogMsg(line))
+			}
+		}
+	}()
+	_, err = p.Run()
+	return err
+}
+
+func (m *uiModel) reload() error {
+	names, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	index := loadIndex()
+	m.rows = m.rows[:0]
+	for _, name := range names {
+		meta, err := loadProfileMeta(&index, name)
+		m.rows = append(m.rows, uiRow{name: name, meta: meta, err: err})
+	}
+	m.active = ""
+	if state := loadState(); state.ActiveProfile != nil {
+		m.active = *state.ActiveProfile
+	}
+	if visible := m.visible(); m.cursor >= len(visible) {
+		m.cursor = max(len(visible)-1, 0)
+	}
+	return nil
+}
+
+// visible returns the rows matching the search filter.
+func (m *uiModel) visible() []uiRow {
+	if m.filter == "" {
+		return m.rows
+	}
+	needle := strings.ToLower(m.filter)
+	var rows []uiRow
+	for _, row := range m.rows {
+		haystack := row.name
+		if row.meta != nil {
+			haystack += " " + row.meta.Email + " " + row.meta.Org + " " + row.meta.Plan
+		}
+		if strings.Contains(strings.ToLower(haystack), needle) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func (m *uiModel) selected() (string, bool) {
+	visible := m.visible()
+	if m.cursor < 0 || m.cursor >= len(visible) {
+		return "", false
+	}
+	return visible[m.cursor].name, true
+}
+
+func uiTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return uiTickMsg(t) })
+}
+
+func (m *uiModel) Init() tea.Cmd {
+	return uiTick()
+}
+
+func (m *uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case uiTickMsg:
+		return m, uiTick()
+	case uiLogMsg:
+		m.status = string(msg)
+	case uiDoneMsg:
+		m.busy = false
+		if err := m.reload(); err != nil && msg.err == nil {
+			msg.err = err
+		}
+		if msg.err != nil {
+			m.status = "error: " + msg.err.Error()
+			if ce := classifyError(msg.err); ce.Hint != "" {
+				m.status += " (" + ce.Hint + ")"
+			}
+		} else {
+			m.status = msg.status
+		}
+	case uiDetailsMsg:
+		m.busy = false
+		if msg.err != nil {
+			m.status = "error: " + msg.err.Error()
+		} else {
+			m.details = msg.text
+			m.mode = uiDetails
+		}
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m *uiModel) handleKey(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Type == tea.KeyCtrlC {
+		return m, tea.Quit
+	}
+	switch m.mode {
+	case uiSearch:
+		switch key.Type {
+		case tea.KeyEnter:
+			m.mode = uiBrowse
+		case tea.KeyEsc:
+			m.filter = ""
+			m.mode = uiBrowse
+		case tea.KeyBackspace:
+			if m.filter != "" {
+				m.filter = m.filter[:len(m.filter)-1]
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			m.filter += string(key.Runes)
+		}
+		m.cursor = 0
+		return m, nil
+	case uiRename:
+		switch key.Type {
+		case tea.KeyEnter:
+			from, ok := m.selected()
+			to := strings.TrimSpace(m.input)
+			m.mode = uiBrowse
+			if !ok || to == "" || to == from {
+				return m, nil
+			}
+			return m.run(func() (string, error) {
+				return fmt.Sprintf("Renamed '%s' to '%s'", from, to), renameProfile(from, to)
+			})
+		case tea.KeyEsc:
+			m.mode = uiBrowse
+		case tea.KeyBackspace:
+			if m.input != "" {
+				m.input = m.input[:len(m.input)-1]
+			}
+		case tea.KeyRunes:
+			m.input += string(key.Runes)
+		}
+		return m, nil
+	case uiConfirmRemove:
+		m.mode = uiBrowse
+		name, ok := m.selected()
+		if !ok || key.String() != "y" {
+			m.status = "Remove cancelled"
+			return m, nil
+		}
+		return m.run(func() (string, error) {
+			return fmt.Sprintf("Removed profile '%s'", name), trashProfile(name)
+		})
+	case uiDetails:
+		m.mode = uiBrowse
+		m.details = ""
+		if key.String() == "q" {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	visible := m.visible()
+	switch key.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(visible)-1 {
+			m.cursor++
+		}
+	case "/":
+		m.mode = uiSearch
+	case "esc":
+		m.filter = ""
+	}
+	name, ok := m.selected()
+	if !ok || m.busy {
+		return m, nil
+	}
+	switch key.String() {
+	case "enter", "s":
+		return m.run(func() (string, error) {
+			if _, err := activateProfile(name, false); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Switched to '%s'", name), nil
+		})
+	case "r":
+		return m.run(func() (string, error) {
+			profile, err := loadProfile(name)
+			if err != nil {
+				return "", err
+			}
+			if profile.Type != "oauth" {
+				return fmt.Sprintf("'%s' is a %s profile; nothing to refresh", name, profile.DescribeType()), nil
+			}
+			if _, err := refreshProfile(name, profile, false); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Refreshed '%s'", name), nil
+		})
+	case "d", "delete":
+		m.mode = uiConfirmRemove
+	case "n":
+		m.mode = uiRename
+		m.input = name
+	case "i", " ":
+		m.busy = true
+		return m, func() tea.Msg {
+			profile, err := loadProfile(name)
+			if err != nil {
+				return uiDetailsMsg{err: err}
+			}
+			var buf bytes.Buffer
+			writeDetails(&buf, profile, profileDetails(name, profile, false), timeUTC)
+			return uiDetailsMsg{text: buf.String()}
+		}
+	}
+	return m, nil
+}
+
+// run performs an action off the UI goroutine and reports its outcome.
+func (m *uiModel) run(action func() (string, error)) (tea.Model, tea.Cmd) {
+	m.busy = true
+	m.status = "Working..."
+	return m, func() tea.Msg {
+		status, err := action()
+		return uiDoneMsg{status: status, err: err}
+	}
+}
+
+func (m *uiModel) View() string {
+	var b strings.Builder
+	b.WriteString(uiHeaderStyle.Render("claude-switch") + uiDimStyle.Render(fmt.Sprintf("  %d profile(s)", len(m.rows))) + "\n\n")
+
+	if m.mode == uiDetails {
+		b.WriteString(m.details)
+		b.WriteString("\n" + uiDimStyle.Render("any key: back · q: quit") + "\n")
+		return b.String()
+	}
+
+	visible := m.visible()
+	nameWidth, typeWidth, emailWidth := len("NAME"), len("error"), len("EMAIL")
+	for _, row := range visible {
+		nameWidth = max(nameWidth, len(row.name))
+		if row.meta != nil {
+			typeWidth = max(typeWidth, len(row.meta.DisplayType()))
+			emailWidth = max(emailWidth, len(row.meta.DisplayEmail()))
+		}
+	}
+	layout := fmt.Sprintf("  %%-%ds  %%-%ds  %%-%ds  %%-6s  %%s", nameWidth, typeWidth, emailWidth)
+	b.WriteString(uiHeaderStyle.Render(fmt.Sprintf(layout, "NAME", "TYPE", "EMAIL", "PLAN", "EXPIRES")) + "\n")
+	if len(visible) == 0 {
+		b.WriteString(uiDimStyle.Render("  no matching profiles") + "\n")
+	}
+	for i, row := range visible {
+		var line string
+		if row.meta == nil {
+			line = fmt.Sprintf(layout, row.name, "error", "-", "-", "-")
+		} else {
+			line = fmt.Sprintf(layout, row.name, row.meta.DisplayType(), row.meta.DisplayEmail(), row.meta.DisplaySub(), "")
+		}
+		if row.name == m.active {
+			line = "* " + line[2:]
+		}
+		switch {
+		case i == m.cursor:
+			line = uiSelectedStyle.Render(line)
+		case row.name == m.active:
+			line = uiActiveStyle.Render(line)
+		}
+		b.WriteString(line + uiExpiry(row.meta) + "\n")
+	}
+
+	b.WriteString("\n")
+	switch m.mode {
+	case uiSearch:
+		b.WriteString("Search: " + m.filter + "█\n")
+	case uiRename:
+		b.WriteString("Rename to: " + m.input + "█  " + uiDimStyle.Render("(enter to confirm, esc to cancel)") + "\n")
+	case uiConfirmRemove:
+		name, _ := m.selected()
+		b.WriteString(fmt.Sprintf("Remove profile '%s'? (y/N)\n", name))
+	default:
+		if m.filter != "" {
+			b.WriteString(uiDimStyle.Render("filter: "+m.filter+" (esc to clear)") + "\n")
+		}
+		b.WriteString(uiDimStyle.Render("enter switch · r refresh · d remove · n rename · i details · / search · q quit") + "\n")
+	}
+	if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	return b.String()
+}
+
+// uiExpiry renders a live countdown to the access token's expiry.
+func uiExpiry(meta *ProfileMeta) string {
+	if meta == nil || meta.ExpiresAt == nil {
+		return "-"
+	}
+	remaining := time.Duration(int64(*meta.ExpiresAt)-int64(nowMs())) * time.Millisecond
+	switch {
+	case remaining <= 0:
+		return uiExpiredStyle.Render("expired " + humanDuration(-remaining) + " ago")
+	case remaining < time.Hour:
+		return uiSoonStyle.Render("in " + humanDuration(remaining))
+	}
+	return "in " + humanDuration(remaining)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// --- Subscription usage: the 5-hour and weekly windows ---
+
+// The same endpoint Claude Code's /usage reads. It only covers OAuth
+// (subscription) profiles; API keys have no such windows.
+const usageURL = "https://api.anthropic.com/api/oauth/usage"
+
+// UsageWindow is how much of one rate-limit window is used, in percent.
+type UsageWindow struct {
+	Utilization float64    `json:"utilization"`
+	ResetsAt    *time.Time `json:"resets_at,omitempty"`
+}
+
+type Usage struct {
+	FiveHour     *UsageWindow `json:"five_hour,omitempty"`
+	SevenDay     *UsageWindow `json:"seven_day,omitempty"`
+	SevenDayOpus *UsageWindow `json:"seven_day_opus,omitempty"`
+}
+
+// profileUsage fetches a profile's usage, refreshing an expired token the
+// way `refresh` does. Other profile types have none and return nil.
+func profileUsage(name string) (*Usage, error) {
+	profile, err := loadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Type != "oauth" {
+		return nil, nil
+	}
+	if profile.Credentials == nil {
+		return nil, &cliError{Code: errNoCredentials, Message: fmt.Sprintf("'%s' has no OAuth tokens", name), Profile: name}
+	}
+	if isExpired(profile.Credentials) {
+		if result := refreshOne(name, 0); result.Error != "" {
+			return nil, &cliError{Code: result.Code, Message: result.Error, Profile: name}
+		}
+		if profile, err = loadProfile(name); err != nil {
+			return nil, err
+		}
+	}
+	return fetchUsage(profile)
+}
+
+func fetchUsage(profile *Profile) (*Usage, error) {
+	if sandboxed() {
+		return sandboxUsage(profile), nil
+	}
+	req, err := http.NewRequest("GET", usageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+profile.Credentials.AccessToken)
+	req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+
+	client := &http.Client{Transport: proxyTransport(profile.Proxy), Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, &cliError{Code: errTokenRejected, Message: fmt.Sprintf("access token was rejected (%d)", resp.StatusCode)}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &cliError{Code: errRateLimited, Message: "usage lookup was rate limited; try again shortly"}
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("usage lookup failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var usage Usage
+	if err := json.Unmarshal(body, &usage); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return &usage, nil
+}
+
+type usageResult struct {
+	Usage *Usage
+	Err   error
+}
+
+// fetchUsages looks up the usage of several profiles in parallel.
+func fetchUsages(names []string) map[string]usageResult {
+	results := make(map[string]usageResult, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Go(func() {
+			usage, err := profileUsage(name)
+			mu.Lock()
+			results[name] = usageResult{usage, err}
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// formatWindow renders a window as "42% (resets in 2h10m)".
+func formatWindow(w *UsageWindow) string {
+	if w == nil {
+		return "-"
+	}
+	s := fmt.Sprintf("%.0f%%", w.Utilization)
+	if w.ResetsAt != nil {
+		s += " (resets " + formatReset(*w.ResetsAt) + ")"
+	}
+	return s
+}
+
+// formatReset renders a reset time relative to now, e.g. "in 2h10m".
+func formatReset(t time.Time) string {
+	d := time.Until(t)
+	if d <= 0 {
+		return "now"
+	}
+	if d < time.Minute {
+		return "in <1m"
+	}
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("in %dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+	return "in " + compactDuration(d.Truncate(time.Minute))
+}
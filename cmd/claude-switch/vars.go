@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// --- Per-profile environment variables ---
+
+// Extra variables, e.g. ANTHROPIC_BASE_URL for a gateway, that exec and env
+// inject along with the credential. They are stored in the profile, so
+// `encrypt enable` covers any secrets among them.
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnvKeys carry the credential and are set by the profile type.
+var reservedEnvKeys = []string{"CLAUDE_CODE_OAUTH_TOKEN", "ANTHROPIC_API_KEY"}
+
+// extraEnvVars returns the profile's variables in key order. Names that
+// vars wouldn't accept are left out, since they end up in shell code.
+func extraEnvVars(p *Profile) []envVar {
+	var vars []envVar
+	for _, key := range slices.Sorted(maps.Keys(p.Env)) {
+		if err := validateEnvKey(key); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring variable %q: %v\n", key, err)
+			continue
+		}
+		vars = append(vars, envVar{key, p.Env[key]})
+	}
+	return vars
+}
+
+func cmdVars(args []string) error {
+	var name string
+	set := map[string]string{}
+	var unset []string
+	clear := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--unset" || a == "-u":
+			if i+1 >= len(args) {
+				return usageError("%s requires a variable name", a)
+			}
+			i++
+			unset = append(unset, args[i])
+		case a == "--clear":
+			clear = true
+		case name == "":
+			name = a
+		case strings.Contains(a, "="):
+			key, value, _ := strings.Cut(a, "=")
+			if err := validateEnvKey(key); err != nil {
+				return err
+			}
+			set[key] = value
+		default:
+			return usageError("unexpected argument: %s (expected KEY=VALUE)", a)
+		}
+	}
+	if name == "" {
+		return usageError("vars requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+
+	if clear || len(set) > 0 || len(unset) > 0 {
+		unlock, err := lockProfileUpdate(name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	profile, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+
+	if !clear && len(set) == 0 && len(unset) == 0 {
+		if jsonOutput() {
+			return printJSON(varsJSON(profile.Env))
+		}
+		if len(profile.Env) == 0 {
+			infof("No variables set for '%s'\n", name)
+			return nil
+		}
+		for _, v := range extraEnvVars(profile) {
+			fmt.Printf("%s=%s\n", v.Key, v.Value)
+		}
+		return nil
+	}
+
+	if clear {
+		profile.Env = nil
+	}
+	for _, key := range unset {
+		delete(profile.Env, key)
+	}
+	if len(set) > 0 && profile.Env == nil {
+		profile.Env = make(map[string]string)
+	}
+	maps.Copy(profile.Env, set)
+	if len(profile.Env) == 0 {
+		profile.Env = nil
+	}
+
+	if err := saveProfile(name, profile); err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(varsJSON(profile.Env))
+	}
+	if profile.Env == nil {
+		infof("Cleared variables for '%s'\n", name)
+	} else {
+		infof("Updated variables for '%s'\n", name)
+	}
+	return nil
+}
+
+func validateEnvKey(key string) error {
+	if !envKeyPattern.MatchString(key) {
+		return usageError("invalid variable name '%s'", key)
+	}
+	for _, reserved := range reservedEnvKeys {
+		if strings.EqualFold(key, reserved) {
+			return usageError("%s is set from the profile's credential and can't be overridden", reserved)
+		}
+	}
+	return nil
+}
+
+// validateProfileEnv checks the variable names of a profile read from a
+// file, a backup or a remote, which vars never saw.
+func validateProfileEnv(p *Profile) error {
+	for _, key := range slices.Sorted(maps.Keys(p.Env)) {
+		if err := validateEnvKey(key); err != nil {
+			return &cliError{Code: errConfig, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+func varsJSON(env map[string]string) map[string]string {
+	if env == nil {
+		return map[string]string{}
+	}
+	return env
+}
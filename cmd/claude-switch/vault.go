@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- HashiCorp Vault storage backend ---
+
+// vaultStore keeps each profile as one secret in a KV engine, with the
+// profile's JSON fields as the secret's data. Refreshed tokens are written
+// back, so a profile rotated in Vault is picked up by every machine.
+type vaultStore struct {
+	address   string
+	namespace string
+	token     string
+	mount     string
+	path      string
+	kvVersion int
+	client    *http.Client
+}
+
+func newVaultStore(cfg *Config) (StorageBackend, error) {
+	if sandboxed() {
+		return nil, &cliError{Code: errConfig, Message: "the vault storage backend isn't available in sandbox mode"}
+	}
+	vc := cfg.Storage.Vault
+	store := &vaultStore{
+		address:   firstNonEmpty(vc.Address, os.Getenv("VAULT_AGENT_ADDR"), os.Getenv("VAULT_ADDR")),
+		namespace: firstNonEmpty(vc.Namespace, os.Getenv("VAULT_NAMESPACE")),
+		token:     vaultToken(),
+		mount:     strings.Trim(firstNonEmpty(vc.Mount, "secret"), "/"),
+		path:      strings.Trim(firstNonEmpty(vc.Path, "claude-switch"), "/"),
+		kvVersion: vc.KVVersion,
+		client:    &http.Client{Transport: withDebug(nil), Timeout: 15 * time.Second},
+	}
+	if store.address == "" {
+		return nil, &cliError{
+			Code:    errConfig,
+			Message: "no Vault address configured",
+			Hint:    fmt.Sprintf("set VAULT_ADDR or [storage.vault] address in %s", configPath()),
+		}
+	}
+	store.address = strings.TrimRight(store.address, "/")
+	switch store.kvVersion {
+	case 0:
+		store.kvVersion = 2
+	case 1, 2:
+	default:
+		return nil, &cliError{Code: errConfig, Message: fmt.Sprintf("unsupported kv_version %d (expected 1 or 2)", store.kvVersion)}
+	}
+	return store, nil
+}
+
+// vaultToken follows the Vault CLI: VAULT_TOKEN, then the token helper file
+// written by `vault login`. With neither, requests go out unauthenticated,
+// which is what a Vault Agent with use_auto_auth_token expects.
+func vaultToken() string {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	data, err := readFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// secretPath returns the API path for a profile secret (or the folder, for
+// an empty name) under the KV v2 data/ or metadata/ prefix.
+func (v *vaultStore) secretPath(kind, name string) string {
+	parts := []string{v.mount}
+	if v.kvVersion == 2 {
+		parts = append(parts, kind)
+	}
+	if v.path != "" {
+		parts = append(parts, v.path)
+	}
+	if name != "" {
+		parts = append(parts, url.PathEscape(name))
+	}
+	return "/v1/" + strings.Join(parts, "/")
+}
+
+// do sends one request and returns the response body. 404 is returned as a
+// nil body and nil error so callers can decide what missing means.
+func (v *vaultStore) do(method, path string, body any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, v.address+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode == http.StatusForbidden:
+		return nil, &cliError{
+			Code:    errConfig,
+			Message: fmt.Sprintf("vault denied %s %s", method, path),
+			Hint:    "check VAULT_TOKEN and that its policy covers the claude-switch path",
+		}
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("vault %s %s failed (%d): %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	return data, nil
+}
+
+func (v *vaultStore) Load(name string) (*Profile, error) {
+	data, err := v.do("GET", v.secretPath("data", name), nil)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, notFoundError(name)
+	}
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	secret := resp.Data
+	if v.kvVersion == 2 {
+		var inner struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(resp.Data, &inner); err != nil {
+			return nil, fmt.Errorf("failed to parse vault response: %w", err)
+		}
+		secret = inner.Data
+	}
+	// A KV v2 secret whose latest version was deleted reads back as null.
+	if len(secret) == 0 || string(secret) == "null" {
+		return nil, notFoundError(name)
+	}
+	var profile Profile
+	if err := json.Unmarshal(secret, &profile); err != nil {
+		return nil, fmt.Errorf("vault secret for '%s' is not a profile: %w", name, err)
+	}
+	return &profile, nil
+}
+
+func (v *vaultStore) Save(name string, profile *Profile) error {
+	var body any = profile
+	if v.kvVersion == 2 {
+		body = map[string]any{"data": profile}
+	}
+	_, err := v.do("POST", v.secretPath("data", name), body)
+	return err
+}
+
+func (v *vaultStore) List() ([]string, error) {
+	data, err := v.do("LIST", v.secretPath("metadata", ""), nil)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var resp struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	var names []string
+	for _, key := range resp.Data.Keys {
+		// Sub-folders end in "/" and aren't profiles
+		if !strings.HasSuffix(key, "/") {
+			names = append(names, key)
+		}
+	}
+	return names, nil
+}
+
+// Remove deletes every version of the secret, like removing a profile file.
+func (v *vaultStore) Remove(name string) error {
+	if !v.Exists(name) {
+		return notFoundError(name)
+	}
+	_, err := v.do("DELETE", v.secretPath("metadata", name), nil)
+	return err
+}
+
+func (v *vaultStore) Exists(name string) bool {
+	_, err := v.Load(name)
+	return err == nil
+}
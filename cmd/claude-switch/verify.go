@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- verify: check that credentials still work ---
+
+// An expiry date says nothing about whether a token has been revoked, so
+// verify asks the API. OAuth tokens are checked against the profile
+// endpoint, after refreshing an expired one, which also proves the refresh
+// token still works. API keys and long-lived tokens list one model, which
+// costs nothing.
+const modelsURL = "https://api.anthropic.com/v1/models?limit=1"
+
+// Verification outcomes, as reported in JSON.
+const (
+	verifyValid   = "valid"
+	verifyExpired = "expired" // expired and can't be renewed without a login
+	verifyRevoked = "revoked" // the refresh token was rejected
+	verifyInvalid = "invalid" // the token or key was rejected
+	verifyNetwork = "network" // the API couldn't be reached
+	verifyUnknown = "unknown" // anything else, e.g. rate limited
+)
+
+// VerifyResult is one profile's outcome in verify's JSON output.
+type VerifyResult struct {
+	Profile   string `json:"profile"`
+	Status    string `json:"status"`
+	Refreshed bool   `json:"refreshed,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+func cmdVerify(args []string) error {
+	var name string
+	refresh := true
+	for _, a := range args {
+		switch {
+		case a == "--no-refresh":
+			refresh = false
+		case strings.HasPrefix(a, "-") || name != "":
+			return usageError("unexpected argument: %s", a)
+		default:
+			name = a
+		}
+	}
+	if name == "" {
+		return usageError("verify requires a profile name")
+	}
+	name, err := resolveProfileName(name, true)
+	if err != nil {
+		return err
+	}
+
+	result := verifyProfile(name, refresh)
+	if jsonOutput() {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	} else if result.Refreshed {
+		infof("%s: valid (the expired token was refreshed)\n", name)
+	} else if result.Status == verifyValid {
+		infof("%s: valid\n", name)
+	}
+	if result.Status == verifyValid {
+		return nil
+	}
+	return &cliError{Code: result.Code, Message: result.Error, Profile: name, Hint: verifyHint(name, result.Status)}
+}
+
+func verifyHint(name, status string) string {
+	switch status {
+	case verifyExpired:
+		return fmt.Sprintf("run 'claude-switch verify %s' without --no-refresh, or 'claude-switch use %s' to log in again", name, name)
+	case verifyRevoked:
+		return fmt.Sprintf("run 'claude-switch use %s' to re-authenticate", name)
+	case verifyInvalid:
+		return "the credentials were revoked or deleted; add them again"
+	case verifyNetwork:
+		return "check your network connection and proxy settings"
+	}
+	return ""
+}
+
+// verifyProfile checks one profile's credentials against the API. With
+// refresh, an expired OAuth token is renewed first, as `use` would; without,
+// it is reported as expired.
+func verifyProfile(name string, refresh bool) VerifyResult {
+	result := VerifyResult{Profile: name}
+	fail := func(status string, err error) VerifyResult {
+		ce := classifyError(err)
+		result.Status, result.Error, result.Code = status, err.Error(), ce.Code
+		if status == verifyUnknown {
+			switch ce.Code {
+			case errReauthRequired:
+				result.Status = verifyRevoked
+			case errTokenRejected:
+				result.Status = verifyInvalid
+			case errNetwork:
+				result.Status = verifyNetwork
+			}
+		}
+		return result
+	}
+
+	profile, err := loadProfile(name)
+	if err != nil {
+		return fail(verifyUnknown, err)
+	}
+	switch profile.Type {
+	case "oauth":
+		creds := profile.Credentials
+		if creds == nil {
+			return fail(verifyInvalid, &cliError{Code: errNoCredentials, Message: fmt.Sprintf("'%s' has no OAuth tokens", name)})
+		}
+		if isExpired(creds) {
+			if !refresh || creds.RefreshToken == "" {
+				return fail(verifyExpired, &cliError{Code: errTokenExpired, Message: fmt.Sprintf("the access token of '%s' has expired", name)})
+			}
+			if r := refreshOne(name, 0); r.Error != "" {
+				return fail(verifyUnknown, &cliError{Code: r.Code, Message: r.Error})
+			}
+			if profile, err = loadProfile(name); err != nil {
+				return fail(verifyUnknown, err)
+			}
+			result.Refreshed = true
+		}
+		if _, err := fetchOAuthIdentity(profile.Credentials.AccessToken, profile.Proxy); err != nil {
+			return fail(verifyUnknown, err)
+		}
+	case "oauth_token":
+		if err := checkModelsAccess("Authorization", "Bearer "+profile.OAuthToken, profile.Proxy); err != nil {
+			return fail(verifyUnknown, err)
+		}
+	default:
+		if err := checkModelsAccess("x-api-key", profile.ApiKey, profile.Proxy); err != nil {
+			return fail(verifyUnknown, err)
+		}
+	}
+	result.Status = verifyValid
+	return result
+}
+
+// verifyWorkers bounds how many profiles verifyProfiles checks at once, so
+// a long list doesn't fire a burst of refreshes at the token endpoint.
+const verifyWorkers = 4
+
+// verifyProfiles verifies several profiles in parallel.
+func verifyProfiles(names []string, refresh bool) map[string]VerifyResult {
+	results := make(map[string]VerifyResult, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for range min(verifyWorkers, len(names)) {
+		wg.Go(func() {
+			for name := range queue {
+				result := verifyProfile(name, refresh)
+				mu.Lock()
+				results[name] = result
+				mu.Unlock()
+			}
+		})
+	}
+	for _, name := range names {
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// checkModelsAccess lists one model with the given credential header.
+func checkModelsAccess(header, value string, proxy *ProxySettings) error {
+	if sandboxed() {
+		return sandboxModelsAccess(value)
+	}
+	req, err := http.NewRequest("GET", modelsURL, nil)
+	if err != nil {
+		return fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.Header.Set(header, value)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	if header == "Authorization" {
+		req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+	}
+
+	client := &http.Client{Transport: proxyTransport(proxy), Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &cliError{Code: errTokenRejected, Message: fmt.Sprintf("credentials were rejected (%d)", resp.StatusCode)}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &cliError{Code: errRateLimited, Message: "the API is rate limiting requests; try again shortly"}
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("model lookup failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"claude-switch/pkg/claudeswitch"
+)
+
+// --- watch: keep the active profile in sync with Claude in the background ---
+
+// Claude's credentials are polled rather than watched: the keychain offers
+// no change notifications, and a read every few seconds is cheap.
+const (
+	defaultWatchInterval = 10 * time.Second
+	minWatchInterval     = time.Second
+)
+
+func watchPIDPath() string {
+	return filepath.Join(configDir(), "watch.pid")
+}
+
+func watchLogPath() string {
+	return filepath.Join(configDir(), "watch.log")
+}
+
+func cmdWatch(args []string) error {
+	interval := defaultWatchInterval
+	daemon, stop, passphraseStdin := false, false, false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--daemon" || a == "-d":
+			daemon = true
+		case a == "--stop":
+			stop = true
+		case a == passphraseStdinFlag:
+			passphraseStdin = true
+		case a == "--interval" || strings.HasPrefix(a, "--interval="):
+			value, ok := strings.CutPrefix(a, "--interval=")
+			if !ok {
+				if i+1 >= len(args) {
+					return usageError("%s requires a duration (e.g. 30s)", a)
+				}
+				i++
+				value = args[i]
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d < minWatchInterval {
+				return usageError("invalid interval '%s' (expected a duration of at least 1s, e.g. 30s)", value)
+			}
+			interval = d
+		default:
+			return usageError("unexpected argument: %s", a)
+		}
+	}
+
+	if stop {
+		return stopWatch()
+	}
+	if pid, ok := runningWatch(); ok {
+		return &cliError{Code: errGeneric, Message: fmt.Sprintf("watch is already running (pid %d)", pid), Hint: "stop it with 'claude-switch watch --stop'"}
+	}
+	// Unlock up front: a background process can't prompt, and a foreground
+	// one shouldn't prompt in the middle of its log.
+	var passphrase string
+	if encryptionEnabled() {
+		p, err := unlockDaemon(passphraseStdin)
+		if err != nil {
+			return err
+		}
+		passphrase = p
+	}
+	if daemon {
+		return startWatchDaemon(interval, passphrase)
+	}
+	return runWatch(interval)
+}
+
+// runningWatch returns the pid of a live watch process, if any.
+func runningWatch() (int, bool) {
+	data, err := readFile(watchPIDPath())
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid == os.Getpid() || !processAlive(pid) {
+		return 0, false
+	}
+	return pid, true
+}
+
+func stopWatch() error {
+	pid, ok := runningWatch()
+	if !ok {
+		os.Remove(watchPIDPath())
+		if !jsonOutput() {
+			infof("watch is not running.\n")
+		}
+		return nil
+	}
+	if err := stopProcess(pid); err != nil {
+		return fmt.Errorf("failed to stop watch (pid %d): %w", pid, err)
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"stopped": pid})
+	}
+	infof("Stopped watch (pid %d).\n", pid)
+	return nil
+}
+
+// startWatchDaemon re-runs watch detached from the terminal, logging to
+// watch.log in the config directory.
+func startWatchDaemon(interval time.Duration, passphrase string) error {
+	pid, err := spawnDaemon([]string{"watch", "--interval", interval.String()}, watchLogPath(), passphrase)
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(map[string]any{"pid": pid, "log": watchLogPath()})
+	}
+	infof("Watching in the background (pid %d); log: %s\n", pid, watchLogPath())
+	infof("Stop it with 'claude-switch watch --stop'.\n")
+	return nil
+}
+
+// passphraseStdinFlag tells a spawned agent or watch to read the passphrase
+// from stdin. It's internal, so it isn't in the usage text.
+const passphraseStdinFlag = "--passphrase-stdin"
+
+// spawnDaemon starts this binary with args, detached from the terminal and
+// appending its output to logPath. The storage backend override is passed
+// on; the environment is inherited. A passphrase is written to the daemon's
+// stdin, which is then closed.
+func spawnDaemon(args []string, logPath, passphrase string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return 0, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+
+	if backendOverride != "" {
+		args = append([]string{"--backend", backendOverride}, args...)
+	}
+	if passphrase != "" {
+		args = append(args, passphraseStdinFlag)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if passphrase != "" {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		// The passphrase fits in the pipe's buffer, so this doesn't block
+		// before the daemon starts reading.
+		_, err = w.WriteString(passphrase)
+		w.Close()
+		if err != nil {
+			return 0, err
+		}
+		cmd.Stdin = r
+	}
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+	return pid, nil
+}
+
+func runWatch(interval time.Duration) error {
+	if err := writeSecure(watchPIDPath(), []byte(strconv.Itoa(os.Getpid()))); err != nil {
+		return err
+	}
+	defer os.Remove(watchPIDPath())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	daemonLog("watching Claude's credentials every %s", interval)
+	var last, lastErr string
+	for {
+		if current := liveFingerprint(); current != last {
+			last = current
+			result, err := syncActiveProfile()
+			var ce *cliError
+			switch {
+			case err != nil:
+				if msg := err.Error(); msg != lastErr {
+					lastErr = msg
+					daemonLog("not synced: %s", msg)
+				}
+				// Drift that sync refuses to fix waits for Claude's
+				// credentials to change; anything else is retried.
+				if !errors.As(err, &ce) || ce.Code != errDrift {
+					last = ""
+				}
+			case result.Switched:
+				lastErr = ""
+				daemonLog("Claude is logged in as '%s'; saved its tokens and marked it active", result.Profile)
+			case result.Updated:
+				lastErr = ""
+				daemonLog("saved Claude's rotated tokens to '%s'", result.Profile)
+			default:
+				lastErr = ""
+			}
+		}
+
+		select {
+		case <-signals:
+			daemonLog("stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// liveFingerprint changes whenever Claude's tokens, its account or the
+// active profile do.
+func liveFingerprint() string {
+	live := readLiveAuth()
+	parts := []string{claudeswitch.AccountField(live.Account, "accountUuid")}
+	if live.Credentials != nil {
+		parts = append(parts, tokenFingerprint(live.Credentials.RefreshToken))
+	}
+	if state := loadState(); state.ActiveProfile != nil {
+		parts = append(parts, *state.ActiveProfile)
+	}
+	return strings.Join(parts, "/")
+}
+
+// daemonLog writes a timestamped line for the watch and agent logs.
+func daemonLog(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+// watchPIDStale reports a pid file left behind by a watch that didn't exit
+// cleanly, for gc.
+func watchPIDStale() bool {
+	if _, err := os.Stat(watchPIDPath()); errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	_, ok := runningWatch()
+	return !ok
+}
@@ -0,0 +1,34 @@
+// Package claudeswitch is the core of claude-switch as a library, for tools
+// that switch Claude Code accounts without running the CLI:
+//
+//   - ProfileStore reads and writes saved profiles in claude-switch's
+//     config directory, in the same format and under the same file locks as
+//     the CLI, so both can be used side by side.
+//   - Switcher puts a profile's OAuth login in place in Claude Code's
+//     config files, leaving every other key alone.
+//   - Refresher exchanges a profile's refresh token for fresh credentials.
+//
+// A typical switch loads a profile, refreshes it if it has expired, saves
+// the rotated tokens back and then switches:
+//
+//	store := claudeswitch.OpenStore(claudeswitch.DefaultConfigDir())
+//	p, err := store.Load("work")
+//	if err != nil {
+//		return err
+//	}
+//	if p.Expired() {
+//		if p.Credentials, err = claudeswitch.DefaultRefresher().Refresh(ctx, p.Credentials); err != nil {
+//			return err
+//		}
+//		if err := store.Save("work", p); err != nil {
+//			return err
+//		}
+//	}
+//	return claudeswitch.DefaultSwitcher().Switch(p)
+//
+// The CLI adds its own policy on top: encryption and keychain storage of
+// profiles, retries and cooldowns for refreshes, backups of every file it
+// replaces, and Claude's settings and MCP servers. Profiles it has
+// encrypted can't be read here. On macOS, where Claude reads its login from
+// the Keychain, set Switcher.Keychain to keep that in step too.
+package claudeswitch
@@ -0,0 +1,137 @@
+package claudeswitch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// --- File I/O with 0600 permissions ---
+
+// TempSuffix marks WriteFileAtomic's temporary files; the random part
+// follows.
+const TempSuffix = ".tmp-*"
+
+// WriteFileAtomic replaces path atomically: the data goes to a temporary
+// file in the same directory, is synced, and is then renamed over the
+// original, so a crash leaves either the old or the new file but never a
+// truncated one. New files are created 0600; an existing file keeps its
+// permissions. If path is a symlink, the file it points to is replaced and
+// the link is left in place.
+func WriteFileAtomic(path string, data []byte) error {
+	// A rename over the link would replace it with a regular file, cutting
+	// off e.g. a dotfiles checkout it points into.
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+TempSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// --- Cross-process file locks ---
+
+// Each file that is read, modified and written back gets its own advisory
+// lock (flock, or LockFileEx on Windows) in a locks directory, so
+// concurrent processes can't interleave their updates. Locks are never
+// nested on the same name: a second lock from the same process would wait
+// for itself.
+
+// LockTimeout is how long LockFile waits by default.
+const LockTimeout = 10 * time.Second
+
+// Lock names used by the CLI for the files shared with this package.
+const (
+	ClaudeCredentialsLock = "claude-credentials"
+	ClaudeJSONLock        = "claude-json"
+	IndexLock             = "index"
+)
+
+// ProfileLock is the name of the lock held while a profile is read or
+// written.
+func ProfileLock(name string) string {
+	return "profile-" + name
+}
+
+// LockTimeoutError is returned when a lock is still held by another process
+// after the timeout.
+type LockTimeoutError struct {
+	Path string
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for %s", e.Path)
+}
+
+// LockFile takes the exclusive lock called name in dir, waiting up to
+// timeout for other processes to release it. Call the returned function to
+// unlock.
+func LockFile(dir, name string, timeout time.Duration) (func(), error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, name+".lock")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			return func() {
+				unlock(f)
+				f.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, &LockTimeoutError{Path: path}
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+// withLock runs fn holding the lock called name in dir, or without a lock
+// if dir is empty.
+func withLock(dir, name string, fn func() error) error {
+	if dir == "" {
+		return fn()
+	}
+	unlock, err := LockFile(dir, name, LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
@@ -0,0 +1,256 @@
+package claudeswitch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// --- Claude Code's own credential structure ---
+
+// OAuthCredentials is the claudeAiOauth block of Claude's
+// .credentials.json. ExpiresAt is in Unix milliseconds.
+type OAuthCredentials struct {
+	AccessToken      string   `json:"accessToken"`
+	RefreshToken     string   `json:"refreshToken"`
+	ExpiresAt        uint64   `json:"expiresAt"`
+	Scopes           []string `json:"scopes"`
+	SubscriptionType *string  `json:"subscriptionType,omitempty"`
+	RateLimitTier    *string  `json:"rateLimitTier,omitempty"`
+}
+
+// --- Profile (tagged union via "type" field) ---
+
+// Profile types.
+const (
+	TypeOAuth      = "oauth"
+	TypeAPIKey     = "api_key"
+	TypeOAuthToken = "oauth_token"
+)
+
+// Profile is a saved account, as stored in the profiles directory.
+type Profile struct {
+	Type        string            `json:"type"`
+	Credentials *OAuthCredentials `json:"credentials,omitempty"`
+	Account     json.RawMessage   `json:"account,omitempty"`
+	ApiKey      string            `json:"api_key,omitempty"`
+	// Long-lived token from `claude setup-token` (type oauth_token); used
+	// as is and never refreshed.
+	OAuthToken string `json:"oauth_token,omitempty"`
+	// Short description shown in list, and free-form notes.
+	Label *string `json:"label,omitempty"`
+	Notes *string `json:"notes,omitempty"`
+	// API key used by exec/env when the OAuth credentials can't be refreshed.
+	FallbackApiKey string `json:"fallback_api_key,omitempty"`
+	// Proxy variables injected by exec/env and used for this profile's refreshes.
+	Proxy *ProxySettings `json:"proxy,omitempty"`
+	// Extra variables injected by exec/env.
+	Env map[string]string `json:"env,omitempty"`
+	// Claude's settings files put in place by use.
+	Settings *ClaudeSettings `json:"settings,omitempty"`
+	// mcpServers block put in Claude's config by use.
+	MCPServers json.RawMessage `json:"mcp_servers,omitempty"`
+	// Where the secrets above are kept when not in the profile file, e.g.
+	// "keychain:<item>" or an op:// reference.
+	Store string `json:"store,omitempty"`
+}
+
+// ReplaceCredentials swaps in the credentials of a freshly imported profile
+// while keeping everything else the user configured on this one.
+func (p *Profile) ReplaceCredentials(from *Profile) {
+	p.Type = from.Type
+	p.Credentials = from.Credentials
+	p.Account = from.Account
+	p.ApiKey = from.ApiKey
+	p.OAuthToken = from.OAuthToken
+}
+
+// WithoutSecrets returns a copy of p without its tokens and keys, as it is
+// written to storage when its secrets are kept elsewhere.
+func (p *Profile) WithoutSecrets() *Profile {
+	stripped := *p
+	stripped.Credentials = nil
+	stripped.ApiKey = ""
+	stripped.OAuthToken = ""
+	stripped.FallbackApiKey = ""
+	return &stripped
+}
+
+// RefreshBuffer is how long before it expires an access token is due for a
+// refresh.
+const RefreshBuffer = 5 * time.Minute
+
+// Expired reports whether p's OAuth access token has expired, or is within
+// RefreshBuffer of it.
+func (p *Profile) Expired() bool {
+	return p.Type == TypeOAuth && p.Credentials != nil &&
+		uint64(time.Now().Add(RefreshBuffer).UnixMilli()) >= p.Credentials.ExpiresAt
+}
+
+// AccountField returns a string field of an oauthAccount block.
+func AccountField(account json.RawMessage, key string) string {
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(account, &doc) != nil {
+		return ""
+	}
+	var s string
+	json.Unmarshal(doc[key], &s)
+	return s
+}
+
+// After a keychain-only login, .claude.json may have no oauthAccount block,
+// leaving a profile without email, org or account UUID. If the access token
+// is a JWT, its claims fill those in. Anthropic's sk-ant-oat tokens are
+// opaque, so for them nothing changes.
+
+// jwtClaimNames lists, for each oauthAccount field, the claims tried in
+// order.
+var jwtClaimNames = map[string][]string{
+	"emailAddress":     {"email"},
+	"accountUuid":      {"account_uuid", "sub"},
+	"organizationName": {"organization_name", "org_name"},
+	"organizationUuid": {"organization_uuid", "org_id"},
+}
+
+// AccountInfo returns an oauthAccount field, falling back to the access
+// token's claims.
+func (p *Profile) AccountInfo(key string) string {
+	if v := AccountField(p.Account, key); v != "" {
+		return v
+	}
+	if p.Credentials == nil {
+		return ""
+	}
+	claims := jwtClaims(p.Credentials.AccessToken)
+	for _, name := range jwtClaimNames[key] {
+		if s, ok := claims[name].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// jwtClaims decodes a JWT's payload without checking its signature, which
+// is fine for display but must never be used to trust anything. It returns
+// nil for a token that isn't a JWT.
+func jwtClaims(token string) map[string]any {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+	var claims map[string]any
+	if json.Unmarshal(payload, &claims) != nil {
+		return nil
+	}
+	return claims
+}
+
+func (p *Profile) DisplayEmail() string {
+	if p.Type == TypeOAuth {
+		if email := p.AccountInfo("emailAddress"); email != "" {
+			return email
+		}
+		return "(unknown)"
+	}
+	return "-"
+}
+
+// DescribeType names the kind of profile for messages.
+func (p *Profile) DescribeType() string {
+	switch p.Type {
+	case TypeOAuth:
+		return "OAuth"
+	case TypeOAuthToken:
+		return "long-lived token"
+	}
+	return "API key"
+}
+
+func (p *Profile) DisplayType() string {
+	if p.FallbackApiKey != "" {
+		return p.Type + "+key"
+	}
+	return p.Type
+}
+
+func (p *Profile) DisplayOrg() string {
+	if p.Type == TypeOAuth {
+		if org := p.AccountInfo("organizationName"); org != "" {
+			return org
+		}
+	}
+	return "-"
+}
+
+func (p *Profile) DisplaySub() string {
+	if p.Type == TypeOAuth && p.Credentials != nil && p.Credentials.SubscriptionType != nil {
+		return *p.Credentials.SubscriptionType
+	}
+	return "-"
+}
+
+func (p *Profile) ExpiresAt() *uint64 {
+	if p.Type == TypeOAuth && p.Credentials != nil {
+		return &p.Credentials.ExpiresAt
+	}
+	return nil
+}
+
+// --- Per-profile proxy settings ---
+
+type ProxySettings struct {
+	HTTP    string `json:"http_proxy,omitempty"`
+	HTTPS   string `json:"https_proxy,omitempty"`
+	NoProxy string `json:"no_proxy,omitempty"`
+}
+
+func (ps *ProxySettings) Empty() bool {
+	return ps == nil || (ps.HTTP == "" && ps.HTTPS == "" && ps.NoProxy == "")
+}
+
+// Proxy picks the proxy for a request, for http.Transport's Proxy field.
+func (ps *ProxySettings) Proxy(req *http.Request) (*url.URL, error) {
+	if ps.Bypass(req.URL.Hostname()) {
+		return nil, nil
+	}
+	raw := ps.HTTP
+	if req.URL.Scheme == "https" && ps.HTTPS != "" {
+		raw = ps.HTTPS
+	}
+	if raw == "" {
+		return nil, nil
+	}
+	return url.Parse(raw)
+}
+
+// Bypass reports whether host matches the NO_PROXY list: "*", exact hosts,
+// and domain suffixes with or without a leading dot.
+func (ps *ProxySettings) Bypass(host string) bool {
+	for _, entry := range strings.Split(ps.NoProxy, ",") {
+		entry = strings.TrimPrefix(strings.TrimSpace(entry), ".")
+		if entry == "" {
+			continue
+		}
+		if entry == "*" || host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Per-profile Claude settings ---
+
+// ClaudeSettings is a copy of Claude's settings files (settings.json and
+// CLAUDE.md). A file missing from Files is removed when the settings are
+// applied.
+type ClaudeSettings struct {
+	Files      map[string]string `json:"files,omitempty"`
+	CapturedAt time.Time         `json:"captured_at"`
+}
@@ -0,0 +1,57 @@
+package claudeswitch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAccountInfo(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"email":"jwt@example.com","sub":"uuid-from-sub"}`))
+	p := &Profile{
+		Type:        TypeOAuth,
+		Account:     json.RawMessage(`{"emailAddress":"me@example.com"}`),
+		Credentials: &OAuthCredentials{AccessToken: "header." + payload + ".sig"},
+	}
+	if got := p.AccountInfo("emailAddress"); got != "me@example.com" {
+		t.Errorf("emailAddress = %q, want the account's", got)
+	}
+	if got := p.AccountInfo("accountUuid"); got != "uuid-from-sub" {
+		t.Errorf("accountUuid = %q, want the sub claim", got)
+	}
+	p.Credentials.AccessToken = "sk-ant-oat01-opaque"
+	if got := p.AccountInfo("accountUuid"); got != "" {
+		t.Errorf("accountUuid from an opaque token = %q, want none", got)
+	}
+	if got := AccountField(json.RawMessage(`not json`), "emailAddress"); got != "" {
+		t.Errorf("AccountField of invalid JSON = %q", got)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	at := func(d time.Duration) *Profile {
+		return &Profile{Type: TypeOAuth, Credentials: &OAuthCredentials{ExpiresAt: uint64(time.Now().Add(d).UnixMilli())}}
+	}
+	if at(time.Hour).Expired() {
+		t.Error("a token valid for an hour is expired")
+	}
+	if !at(RefreshBuffer / 2).Expired() {
+		t.Error("a token within the refresh buffer isn't expired")
+	}
+	if (&Profile{Type: TypeAPIKey, ApiKey: "sk-ant-api"}).Expired() {
+		t.Error("an API key profile is expired")
+	}
+}
+
+func TestWithoutSecrets(t *testing.T) {
+	label := "work"
+	p := &Profile{Type: TypeOAuth, Credentials: &OAuthCredentials{AccessToken: "a"}, FallbackApiKey: "k", Label: &label}
+	stripped := p.WithoutSecrets()
+	if stripped.Credentials != nil || stripped.FallbackApiKey != "" {
+		t.Errorf("secrets left in %+v", stripped)
+	}
+	if stripped.Label != p.Label || p.Credentials == nil {
+		t.Error("WithoutSecrets changed the original or dropped other fields")
+	}
+}
@@ -0,0 +1,192 @@
+package claudeswitch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// --- OAuth token refresh ---
+
+// Claude Code's OAuth client.
+const (
+	DefaultClientID = "9d1c250a-e61b-44d9-88ed-5944d1962f5e"
+	DefaultTokenURL = "https://platform.claude.com/v1/oauth/token"
+	DefaultScopes   = "user:profile user:inference user:sessions:claude_code user:mcp_servers"
+)
+
+// DefaultRetryAfter is the wait assumed when a 429 has no usable
+// Retry-After header.
+const DefaultRetryAfter = 60 * time.Second
+
+type RefreshErrorKind int
+
+const (
+	// RefreshInvalidGrant means the refresh token was revoked or already
+	// used; only a new login helps.
+	RefreshInvalidGrant RefreshErrorKind = iota
+	// RefreshRateLimited means the token endpoint asked to wait RetryAfter.
+	RefreshRateLimited
+	RefreshOther
+)
+
+type RefreshError struct {
+	Kind       RefreshErrorKind
+	Message    string
+	RetryAfter time.Duration
+	Status     int // the token endpoint's HTTP status
+}
+
+func (e *RefreshError) Error() string {
+	return e.Message
+}
+
+// RateLimitedError is the error for a refresh that has to wait.
+func RateLimitedError(wait time.Duration) *RefreshError {
+	wait = wait.Round(time.Second)
+	return &RefreshError{
+		Kind:       RefreshRateLimited,
+		Message:    fmt.Sprintf("token endpoint is rate limiting refreshes; retry in %s (at %s)", wait, time.Now().Add(wait).Format("15:04:05")),
+		RetryAfter: wait,
+	}
+}
+
+// ParseRetryAfter accepts both forms allowed by RFC 9110: delay-seconds and
+// an HTTP date.
+func ParseRetryAfter(h string) time.Duration {
+	if h == "" {
+		return DefaultRetryAfter
+	}
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return DefaultRetryAfter
+}
+
+// Refresher exchanges refresh tokens for fresh credentials. Each call makes
+// a single request; retrying and honouring RetryAfter are up to the caller.
+type Refresher struct {
+	ClientID string
+	TokenURL string
+	Scopes   string
+	// Client sends the request; http.DefaultClient if nil.
+	Client *http.Client
+	// Now is the current time the new ExpiresAt is computed from; time.Now
+	// if nil. The CLI keeps expiry times on the token server's clock.
+	Now func() time.Time
+	// ServerDate, if set, is given each response's Date header with when the
+	// request was sent and the response received, for measuring clock skew.
+	// It's called before Now.
+	ServerDate func(date string, sent, received time.Time)
+}
+
+// DefaultRefresher refreshes against Claude's token endpoint, giving up
+// after 30 seconds.
+func DefaultRefresher() *Refresher {
+	return &Refresher{
+		ClientID: DefaultClientID,
+		TokenURL: DefaultTokenURL,
+		Scopes:   DefaultScopes,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Refresh returns creds with a new access token and, if the server rotated
+// it, a new refresh token. The old refresh token may stop working as soon
+// as the request reaches the server, so the result must be saved. Failures
+// reported by the server are *RefreshError.
+func (r *Refresher) Refresh(ctx context.Context, creds *OAuthCredentials) (*OAuthCredentials, error) {
+	reqBody, err := json.Marshal(map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": creds.RefreshToken,
+		"client_id":     r.ClientID,
+		"scope":         r.Scopes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.TokenURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request setup failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if r.ServerDate != nil {
+		r.ServerDate(resp.Header.Get("Date"), sent, time.Now())
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, RateLimitedError(ParseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if bytes.Contains(body, []byte("invalid_grant")) {
+			return nil, &RefreshError{Kind: RefreshInvalidGrant, Message: "invalid_grant"}
+		}
+		return nil, &RefreshError{
+			Kind:    RefreshOther,
+			Message: fmt.Sprintf("token refresh failed (%d): %s", resp.StatusCode, body),
+			Status:  resp.StatusCode,
+		}
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	accessToken, ok := result["access_token"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing access_token in refresh response")
+	}
+
+	newRefreshToken := creds.RefreshToken
+	if rt, ok := result["refresh_token"].(string); ok {
+		newRefreshToken = rt
+	}
+
+	expiresIn := uint64(3600)
+	if ei, ok := result["expires_in"].(float64); ok {
+		expiresIn = uint64(ei)
+	}
+	now := time.Now()
+	if r.Now != nil {
+		now = r.Now()
+	}
+
+	return &OAuthCredentials{
+		AccessToken:      accessToken,
+		RefreshToken:     newRefreshToken,
+		ExpiresAt:        uint64(now.UnixMilli()) + expiresIn*1000,
+		Scopes:           creds.Scopes,
+		SubscriptionType: creds.SubscriptionType,
+		RateLimitTier:    creds.RateLimitTier,
+	}, nil
+}
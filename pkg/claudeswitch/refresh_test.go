@@ -0,0 +1,95 @@
+package claudeswitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testRefresher(t *testing.T, handler http.HandlerFunc) *Refresher {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	r := DefaultRefresher()
+	r.TokenURL = server.URL
+	return r
+}
+
+func TestRefresh(t *testing.T) {
+	r := testRefresher(t, func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]string
+		json.NewDecoder(req.Body).Decode(&body)
+		if body["grant_type"] != "refresh_token" || body["refresh_token"] != "old-refresh" {
+			t.Errorf("request = %v", body)
+		}
+		w.Write([]byte(`{"access_token":"new-access","refresh_token":"new-refresh","expires_in":600}`))
+	})
+	now := time.UnixMilli(1_000_000)
+	r.Now = func() time.Time { return now }
+	plan := "max"
+	creds, err := r.Refresh(context.Background(), &OAuthCredentials{RefreshToken: "old-refresh", SubscriptionType: &plan})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessToken != "new-access" || creds.RefreshToken != "new-refresh" {
+		t.Errorf("tokens = %q, %q", creds.AccessToken, creds.RefreshToken)
+	}
+	if creds.ExpiresAt != 1_000_000+600_000 {
+		t.Errorf("ExpiresAt = %d", creds.ExpiresAt)
+	}
+	if creds.SubscriptionType != &plan {
+		t.Error("the subscription type wasn't carried over")
+	}
+}
+
+func TestRefreshErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header string
+		body   string
+		kind   RefreshErrorKind
+		wait   time.Duration
+	}{
+		{name: "invalid grant", status: 400, body: `{"error":"invalid_grant"}`, kind: RefreshInvalidGrant},
+		{name: "rate limited", status: 429, header: "120", kind: RefreshRateLimited, wait: 2 * time.Minute},
+		{name: "server error", status: 500, body: "oops", kind: RefreshOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testRefresher(t, func(w http.ResponseWriter, req *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			_, err := r.Refresh(context.Background(), &OAuthCredentials{RefreshToken: "r"})
+			var re *RefreshError
+			if !errors.As(err, &re) {
+				t.Fatalf("err = %v, want a *RefreshError", err)
+			}
+			if re.Kind != tt.kind || re.RetryAfter != tt.wait {
+				t.Errorf("err = %+v, want kind %d and wait %s", re, tt.kind, tt.wait)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := ParseRetryAfter("30"); got != 30*time.Second {
+		t.Errorf("ParseRetryAfter(30) = %s", got)
+	}
+	if got := ParseRetryAfter(""); got != DefaultRetryAfter {
+		t.Errorf("ParseRetryAfter(\"\") = %s", got)
+	}
+	if got := ParseRetryAfter("soon"); got != DefaultRetryAfter {
+		t.Errorf("ParseRetryAfter(soon) = %s", got)
+	}
+	if got := ParseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)); got != 0 {
+		t.Errorf("ParseRetryAfter of a past date = %s", got)
+	}
+}
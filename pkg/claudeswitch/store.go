@@ -0,0 +1,238 @@
+package claudeswitch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// --- Profile storage ---
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrInvalidName     = errors.New("invalid profile name")
+	// ErrEncrypted is returned for a profile the CLI has encrypted, by a
+	// store without an Open function.
+	ErrEncrypted = errors.New("profile is encrypted")
+)
+
+// ValidateName checks that name can be used as a profile name: a single
+// normal path component.
+func ValidateName(name string) error {
+	invalid := fmt.Errorf("%w: '%s'", ErrInvalidName, name)
+	if name == "" || strings.Contains(name, "/") || strings.Contains(name, "\\") ||
+		name == "." || name == ".." || strings.Contains(name, string(os.PathSeparator)) {
+		return invalid
+	}
+	cleaned := filepath.Clean(name)
+	if cleaned != name || filepath.Base(name) != name {
+		return invalid
+	}
+	return nil
+}
+
+// DefaultConfigDir is the directory the CLI keeps its profiles in:
+// $CLAUDE_SWITCH_CONFIG_DIR, else claude-switch in $XDG_CONFIG_HOME or
+// ~/.config.
+func DefaultConfigDir() string {
+	if dir := os.Getenv("CLAUDE_SWITCH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "claude-switch")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", "claude-switch")
+	}
+	return filepath.Join(home, ".config", "claude-switch")
+}
+
+// ProfileStore keeps one JSON file per profile in Dir.
+type ProfileStore struct {
+	Dir string
+	// LockDir, if set, makes each call hold the profile's lock there.
+	// Callers that lock profiles themselves leave it empty.
+	LockDir string
+	// IndexPath, if set, is the CLI's metadata index. A profile's entry is
+	// dropped from it when the profile is saved or removed, so the CLI
+	// rebuilds it from the file.
+	IndexPath string
+	// Open turns the content of a profile's file into its JSON, and Seal
+	// does the reverse. Without Open, encrypted profiles fail to load with
+	// ErrEncrypted; without Seal, profiles are written as plain JSON.
+	Open func(name string, data []byte) ([]byte, error)
+	Seal func(name string, data []byte) ([]byte, error)
+	// ReadFile and WriteFile read and replace files; os.ReadFile and
+	// WriteFileAtomic if nil.
+	ReadFile  func(path string) ([]byte, error)
+	WriteFile func(path string, data []byte) error
+}
+
+// OpenStore returns the store of the CLI's config directory dir, taking
+// the same locks as the CLI.
+func OpenStore(dir string) *ProfileStore {
+	return &ProfileStore{
+		Dir:       filepath.Join(dir, "profiles"),
+		LockDir:   filepath.Join(dir, "locks"),
+		IndexPath: filepath.Join(dir, "index.json"),
+	}
+}
+
+// Path returns the file profile name is kept in.
+func (s *ProfileStore) Path(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
+
+func (s *ProfileStore) read(path string) ([]byte, error) {
+	if s.ReadFile != nil {
+		return s.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
+
+func (s *ProfileStore) write(path string, data []byte) error {
+	if s.WriteFile != nil {
+		return s.WriteFile(path, data)
+	}
+	return WriteFileAtomic(path, data)
+}
+
+func (s *ProfileStore) Load(name string) (*Profile, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	var profile Profile
+	err := withLock(s.LockDir, ProfileLock(name), func() error {
+		data, err := s.read(s.Path(name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: '%s'", ErrProfileNotFound, name)
+		}
+		if err != nil {
+			return err
+		}
+		if s.Open != nil {
+			if data, err = s.Open(name, data); err != nil {
+				return err
+			}
+		} else if isSealed(data) {
+			return fmt.Errorf("%w: '%s'", ErrEncrypted, name)
+		}
+		return json.Unmarshal(data, &profile)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (s *ProfileStore) Save(name string, profile *Profile) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.Seal != nil {
+		if data, err = s.Seal(name, data); err != nil {
+			return err
+		}
+	}
+	if err := withLock(s.LockDir, ProfileLock(name), func() error {
+		return s.write(s.Path(name), data)
+	}); err != nil {
+		return err
+	}
+	return s.dropIndexEntry(name)
+}
+
+// List returns the names of the profiles, sorted.
+func (s *ProfileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if filepath.Ext(name) == ".json" {
+			names = append(names, strings.TrimSuffix(name, ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *ProfileStore) Remove(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if err := withLock(s.LockDir, ProfileLock(name), func() error {
+		err := os.Remove(s.Path(name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: '%s'", ErrProfileNotFound, name)
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	return s.dropIndexEntry(name)
+}
+
+func (s *ProfileStore) Exists(name string) bool {
+	_, err := os.Stat(s.Path(name))
+	return err == nil
+}
+
+// isSealed reports whether a profile file holds the CLI's encrypted form.
+func isSealed(data []byte) bool {
+	var sealed struct {
+		Encrypted int `json:"encrypted"`
+	}
+	return json.Unmarshal(data, &sealed) == nil && sealed.Encrypted != 0
+}
+
+// dropIndexEntry removes name from the CLI's index, if the store has one.
+func (s *ProfileStore) dropIndexEntry(name string) error {
+	if s.IndexPath == "" {
+		return nil
+	}
+	return withLock(s.LockDir, IndexLock, func() error {
+		data, err := s.read(s.IndexPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var index map[string]json.RawMessage
+		var profiles map[string]json.RawMessage
+		if json.Unmarshal(data, &index) != nil || json.Unmarshal(index["profiles"], &profiles) != nil {
+			return nil
+		}
+		if _, ok := profiles[name]; !ok {
+			return nil
+		}
+		delete(profiles, name)
+		if index["profiles"], err = json.Marshal(profiles); err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return err
+		}
+		return s.write(s.IndexPath, out)
+	})
+}
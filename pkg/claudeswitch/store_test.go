@@ -0,0 +1,90 @@
+package claudeswitch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"work", "me@example.com", "team-1"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`, "../x"} {
+		if err := ValidateName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("ValidateName(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestProfileStore(t *testing.T) {
+	dir := t.TempDir()
+	store := OpenStore(dir)
+	index := filepath.Join(dir, "index.json")
+	if err := os.WriteFile(index, []byte(`{"profiles":{"work":{},"personal":{}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Load("work"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("Load of a missing profile = %v, want ErrProfileNotFound", err)
+	}
+	p := &Profile{Type: TypeAPIKey, ApiKey: "sk-ant-api03-x"}
+	for _, name := range []string{"work", "personal"} {
+		if err := store.Save(name, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := store.Load("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("Load = %+v, want %+v", got, p)
+	}
+	names, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"personal", "work"}) {
+		t.Errorf("List = %v", names)
+	}
+	// Saving drops the profile's index entry so the CLI rebuilds it.
+	if data, _ := os.ReadFile(index); strings.Contains(string(data), `"work"`) {
+		t.Errorf("index still has the saved profile: %s", data)
+	}
+
+	if err := store.Remove("work"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Exists("work") || !store.Exists("personal") {
+		t.Error("Remove removed the wrong profile")
+	}
+	if err := store.Remove("work"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("second Remove = %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestProfileStoreEncrypted(t *testing.T) {
+	store := &ProfileStore{Dir: t.TempDir()}
+	if err := os.WriteFile(store.Path("work"), []byte(`{"encrypted":1,"ciphertext":"x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("work"); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Load without Open = %v, want ErrEncrypted", err)
+	}
+	store.Open = func(name string, data []byte) ([]byte, error) {
+		return []byte(`{"type":"api_key","api_key":"opened"}`), nil
+	}
+	p, err := store.Load("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ApiKey != "opened" {
+		t.Errorf("Load with Open = %+v", p)
+	}
+}
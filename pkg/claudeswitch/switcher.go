@@ -0,0 +1,196 @@
+package claudeswitch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// --- Claude's config files ---
+
+// DefaultClaudeConfigDir is Claude Code's config directory:
+// $CLAUDE_CONFIG_DIR, else ~/.claude.
+func DefaultClaudeConfigDir() string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", ".claude")
+	}
+	return filepath.Join(home, ".claude")
+}
+
+// DefaultClaudeJSONPath is Claude Code's .claude.json, which like Claude
+// is kept inside $CLAUDE_CONFIG_DIR if that is set, else in the home
+// directory.
+func DefaultClaudeJSONPath() string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, ".claude.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", ".claude.json")
+	}
+	return filepath.Join(home, ".claude.json")
+}
+
+// ErrNotOAuth is returned by Switch for API key and long-lived token
+// profiles, which Claude takes from its environment rather than its config.
+var ErrNotOAuth = errors.New("only OAuth profiles can be written to Claude's config")
+
+// Switcher edits the login in Claude Code's config files: claudeAiOauth in
+// .credentials.json, and oauthAccount and primaryApiKey in .claude.json.
+// Every other key is left as it is.
+type Switcher struct {
+	// ConfigDir is Claude's config directory, which holds .credentials.json.
+	ConfigDir string
+	// ClaudeJSON is the path of Claude's .claude.json.
+	ClaudeJSON string
+	// LockDir, if set, is where the locks on Claude's files are taken, the
+	// CLI's locks directory to work alongside it.
+	LockDir string
+	// Keychain, if set, is also given the credentials Switch writes, for
+	// platforms where Claude reads them from the system keychain, such as
+	// the macOS Keychain item "Claude Code-credentials".
+	Keychain func(*OAuthCredentials) error
+	// ReadFile and WriteFile read and replace files; os.ReadFile and
+	// WriteFileAtomic if nil.
+	ReadFile  func(path string) ([]byte, error)
+	WriteFile func(path string, data []byte) error
+}
+
+// DefaultSwitcher edits Claude's files where Claude looks for them, under
+// the CLI's locks.
+func DefaultSwitcher() *Switcher {
+	return &Switcher{
+		ConfigDir:  DefaultClaudeConfigDir(),
+		ClaudeJSON: DefaultClaudeJSONPath(),
+		LockDir:    filepath.Join(DefaultConfigDir(), "locks"),
+	}
+}
+
+// CredentialsPath is Claude's .credentials.json.
+func (s *Switcher) CredentialsPath() string {
+	return filepath.Join(s.ConfigDir, ".credentials.json")
+}
+
+// Switch makes the OAuth profile p Claude's login. Running Claude sessions
+// keep the login they started with until they restart.
+func (s *Switcher) Switch(p *Profile) error {
+	if p.Type != TypeOAuth {
+		return ErrNotOAuth
+	}
+	if p.Credentials == nil {
+		return errors.New("the profile has no OAuth credentials")
+	}
+	if err := s.WriteCredentials(p.Credentials); err != nil {
+		return err
+	}
+	if s.Keychain != nil {
+		if err := s.Keychain(p.Credentials); err != nil {
+			return fmt.Errorf("failed to update the keychain: %w", err)
+		}
+	}
+	return s.WriteAccount(p.Account)
+}
+
+// WriteCredentials sets the claudeAiOauth block of .credentials.json.
+func (s *Switcher) WriteCredentials(creds *OAuthCredentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return s.SetKeys(s.CredentialsPath(), map[string]json.RawMessage{"claudeAiOauth": data})
+}
+
+// WriteAccount sets the oauthAccount block of .claude.json, which holds
+// the email and organization Claude shows. A nil account is left out.
+func (s *Switcher) WriteAccount(account json.RawMessage) error {
+	if account == nil {
+		return nil
+	}
+	return s.SetKeys(s.ClaudeJSON, map[string]json.RawMessage{"oauthAccount": account})
+}
+
+// Credentials returns the claudeAiOauth block of .credentials.json, or nil
+// if there is none.
+func (s *Switcher) Credentials() (*OAuthCredentials, error) {
+	data, err := s.read(s.CredentialsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Credentials *OAuthCredentials `json:"claudeAiOauth"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.CredentialsPath(), err)
+	}
+	return doc.Credentials, nil
+}
+
+// Logout removes Claude's login from its config files.
+func (s *Switcher) Logout() error {
+	if err := s.SetKeys(s.CredentialsPath(), map[string]json.RawMessage{"claudeAiOauth": nil}); err != nil {
+		return err
+	}
+	return s.SetKeys(s.ClaudeJSON, map[string]json.RawMessage{"oauthAccount": nil, "primaryApiKey": nil})
+}
+
+// SetKeys sets top-level keys of .credentials.json or .claude.json under
+// its lock, deleting those whose value is nil. A missing file is only
+// created if there is something to set.
+func (s *Switcher) SetKeys(path string, values map[string]json.RawMessage) error {
+	lock := ClaudeJSONLock
+	if path == s.CredentialsPath() {
+		lock = ClaudeCredentialsLock
+	}
+	return withLock(s.LockDir, lock, func() error {
+		data, err := s.read(path)
+		missing := errors.Is(err, fs.ErrNotExist)
+		if err != nil && !missing {
+			return err
+		}
+		var doc map[string]json.RawMessage
+		if missing || json.Unmarshal(data, &doc) != nil {
+			doc = make(map[string]json.RawMessage)
+		}
+		changed := false
+		for key, value := range values {
+			if value == nil {
+				delete(doc, key)
+			} else {
+				doc[key] = value
+				changed = true
+			}
+		}
+		if missing && !changed {
+			return nil
+		}
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		return s.write(path, out)
+	})
+}
+
+func (s *Switcher) read(path string) ([]byte, error) {
+	if s.ReadFile != nil {
+		return s.ReadFile(path)
+	}
+	return os.ReadFile(path)
+}
+
+func (s *Switcher) write(path string, data []byte) error {
+	if s.WriteFile != nil {
+		return s.WriteFile(path, data)
+	}
+	return WriteFileAtomic(path, data)
+}
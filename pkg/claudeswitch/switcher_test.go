@@ -0,0 +1,94 @@
+package claudeswitch
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestSwitcher(t *testing.T) *Switcher {
+	dir := t.TempDir()
+	return &Switcher{
+		ConfigDir:  filepath.Join(dir, ".claude"),
+		ClaudeJSON: filepath.Join(dir, ".claude.json"),
+		LockDir:    filepath.Join(dir, "locks"),
+	}
+}
+
+func readDoc(t *testing.T, path string) map[string]json.RawMessage {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestSwitchKeepsOtherKeys(t *testing.T) {
+	s := newTestSwitcher(t)
+	if err := os.WriteFile(s.ClaudeJSON, []byte(`{"numStartups":3,"oauthAccount":{"emailAddress":"old@example.com"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var keychain *OAuthCredentials
+	s.Keychain = func(c *OAuthCredentials) error { keychain = c; return nil }
+
+	p := &Profile{
+		Type:        TypeOAuth,
+		Credentials: &OAuthCredentials{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: 1},
+		Account:     json.RawMessage(`{"emailAddress":"new@example.com"}`),
+	}
+	if err := s.Switch(p); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := s.Credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || creds.AccessToken != "access" || creds.RefreshToken != "refresh" {
+		t.Errorf("Credentials = %+v", creds)
+	}
+	if keychain != p.Credentials {
+		t.Error("the keychain wasn't given the credentials")
+	}
+	doc := readDoc(t, s.ClaudeJSON)
+	if string(doc["numStartups"]) != "3" {
+		t.Errorf("numStartups = %s, want it kept", doc["numStartups"])
+	}
+	if AccountField(doc["oauthAccount"], "emailAddress") != "new@example.com" {
+		t.Errorf("oauthAccount = %s", doc["oauthAccount"])
+	}
+
+	if err := s.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if creds, err := s.Credentials(); err != nil || creds != nil {
+		t.Errorf("Credentials after Logout = %+v, %v", creds, err)
+	}
+	doc = readDoc(t, s.ClaudeJSON)
+	if _, ok := doc["oauthAccount"]; ok || string(doc["numStartups"]) != "3" {
+		t.Errorf(".claude.json after Logout = %v", doc)
+	}
+}
+
+func TestSwitchRejectsAPIKeys(t *testing.T) {
+	s := newTestSwitcher(t)
+	if err := s.Switch(&Profile{Type: TypeAPIKey, ApiKey: "sk-ant-api"}); !errors.Is(err, ErrNotOAuth) {
+		t.Errorf("Switch of an API key profile = %v, want ErrNotOAuth", err)
+	}
+}
+
+func TestSetKeysLeavesMissingFile(t *testing.T) {
+	s := newTestSwitcher(t)
+	if err := s.SetKeys(s.ClaudeJSON, map[string]json.RawMessage{"oauthAccount": nil}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.ClaudeJSON); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("deleting a key created the file: %v", err)
+	}
+}